@@ -9,10 +9,91 @@ type GuestPayload struct {
 }
 
 // ReservationPayload is the payload used to create a Reservation
+// StartDate and EndDate are the check-in and check-out dates, RatePlanUUID and PromoCode are optional.
+// PaymentMethod is the payment provider's token for the guest's card
 type ReservationPayload struct {
+	GuestUUID     string `json:"guest_uuid"`
+	HotelUUID     string `json:"hotel_uuid"`
+	RoomTypeUUID  string `json:"roomtype_uuid"`
+	RatePlanUUID  string `json:"rateplan_uuid"`
+	StartDate     string `json:"start_date"`
+	EndDate       string `json:"end_date"`
+	PromoCode     string `json:"promo_code"`
+	PaymentMethod string `json:"payment_method"`
+	// JoinWaitlist puts the guest on the room type's waitlist when it is sold out for the stay
+	JoinWaitlist bool `json:"join_waitlist"`
+	// RedeemPoints spends the guest's loyalty points as a discount
+	RedeemPoints int `json:"redeem_points"`
+}
+
+// ConfirmReservationPayload is the payload used to pay for a held Reservation
+type ConfirmReservationPayload struct {
+	ReservationUUID string `json:"reservation_uuid"`
+	PaymentMethod   string `json:"payment_method"`
+}
+
+// ReservationStatusPayload is the payload used to check a guest in or out of a Reservation
+type ReservationStatusPayload struct {
+	ReservationUUID string `json:"reservation_uuid"`
+}
+
+// CapturePaymentPayload is the payload used to capture a Reservation's payment
+type CapturePaymentPayload struct {
+	ReservationUUID string `json:"reservation_uuid"`
+}
+
+// WaitlistPayload is the payload used to join the waitlist of a sold out room type
+type WaitlistPayload struct {
 	GuestUUID    string `json:"guest_uuid"`
 	HotelUUID    string `json:"hotel_uuid"`
 	RoomTypeUUID string `json:"roomtype_uuid"`
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+}
+
+// ReservationModificationPayload changes the stay of a reservation, fields that are left empty are kept.
+// Changing the room type drops the reservation's rate plan unless a new one is given. PaymentMethod pays
+// for a paid reservation whose price goes up
+type ReservationModificationPayload struct {
+	RoomTypeUUID  string `json:"roomtype_uuid"`
+	RatePlanUUID  string `json:"rateplan_uuid"`
+	StartDate     string `json:"start_date"`
+	EndDate       string `json:"end_date"`
+	PaymentMethod string `json:"payment_method"`
+}
+
+// BookingLinePayload is one room type of a multi-room booking
+type BookingLinePayload struct {
+	RoomTypeUUID string `json:"roomtype_uuid"`
+	RatePlanUUID string `json:"rateplan_uuid"`
+	Quantity     int64  `json:"quantity"`
+	Occupants    int    `json:"occupants"`
+}
+
+// BookingPayload is the payload used to book several rooms at once.
+// Setting ReleaseDate makes the booking a group block whose rooms are released on that date unless confirmed
+type BookingPayload struct {
+	GuestUUID   string               `json:"guest_uuid"`
+	HotelUUID   string               `json:"hotel_uuid"`
+	GroupName   string               `json:"group_name"`
+	StartDate   string               `json:"start_date"`
+	EndDate     string               `json:"end_date"`
+	ReleaseDate string               `json:"release_date"`
+	Lines       []BookingLinePayload `json:"lines"`
+	// PaymentMethod pays for a booking without a release date, group blocks are paid for when confirmed
+	PaymentMethod string `json:"payment_method"`
+}
+
+// BookingLineCancellationPayload is the payload used to cancel one line of a booking
+type BookingLineCancellationPayload struct {
+	BookingUUID string `json:"booking_uuid"`
+	LineUUID    string `json:"line_uuid"`
+}
+
+// ConfirmBookingPayload is the payload used to confirm a group block
+type ConfirmBookingPayload struct {
+	BookingUUID   string `json:"booking_uuid"`
+	PaymentMethod string `json:"payment_method"`
 }
 
 // CancelReservationPayload is the payload used to cancel a Reservation
@@ -20,3 +101,107 @@ type CancelReservationPayload struct {
 	GuestUUID    string `json:"guest_uuid"`
 	RoomTypeUUID string `json:"roomtype_uuid"`
 }
+
+// RatePeriodPayload sets the nightly rate of every date between StartDate and EndDate (both inclusive)
+// WeekdayRates is keyed by the lower case weekday name e.g "saturday", DefaultRate is used for the
+// weekdays that are not listed. Dates whose weekday has no rate are left untouched
+type RatePeriodPayload struct {
+	StartDate    string         `json:"start_date"`
+	EndDate      string         `json:"end_date"`
+	WeekdayRates map[string]int `json:"weekday_rates"`
+	DefaultRate  int            `json:"default_rate"`
+}
+
+// BulkRatePayload is the payload used to set the rate calendar of a room type
+type BulkRatePayload struct {
+	HotelUUID    string              `json:"hotel_uuid"`
+	RoomTypeUUID string              `json:"roomtype_uuid"`
+	Periods      []RatePeriodPayload `json:"periods"`
+}
+
+// RatePlanPayload is the payload used to create a RatePlan, plans are refundable unless stated otherwise
+type RatePlanPayload struct {
+	HotelUUID         string `json:"hotel_uuid"`
+	RoomTypeUUID      string `json:"roomtype_uuid"`
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	Refundable        *bool  `json:"refundable"`
+	BreakfastIncluded bool   `json:"breakfast_included"`
+	MinLengthOfStay   int    `json:"min_length_of_stay"`
+	MaxLengthOfStay   int    `json:"max_length_of_stay"`
+	MinAdvanceDays    int    `json:"min_advance_days"`
+	MaxAdvanceDays    int    `json:"max_advance_days"`
+}
+
+// RatePlanRatesPayload sets a rate plan's price and restrictions on every date between StartDate and
+// EndDate (both inclusive). When Weekdays is set e.g ["friday", "saturday"] only those weekdays are changed
+type RatePlanRatesPayload struct {
+	RatePlanUUID      string   `json:"rateplan_uuid"`
+	StartDate         string   `json:"start_date"`
+	EndDate           string   `json:"end_date"`
+	Weekdays          []string `json:"weekdays"`
+	Rate              int      `json:"rate"`
+	MinLengthOfStay   int      `json:"min_length_of_stay"`
+	ClosedToArrival   bool     `json:"closed_to_arrival"`
+	ClosedToDeparture bool     `json:"closed_to_departure"`
+}
+
+// OverbookingPayload overrides a room type's overbooking limit on every date between StartDate and
+// EndDate (both inclusive). Type is ABSOLUTE, a number of rooms, or PERCENTAGE of the physical inventory
+type OverbookingPayload struct {
+	HotelUUID    string `json:"hotel_uuid"`
+	RoomTypeUUID string `json:"roomtype_uuid"`
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+	Type         string `json:"type"`
+	Value        int    `json:"value"`
+}
+
+// PricingRulePayload is the payload used to create a PricingRule
+type PricingRulePayload struct {
+	HotelUUID    string  `json:"hotel_uuid"`
+	RoomTypeUUID string  `json:"roomtype_uuid"`
+	Name         string  `json:"name"`
+	Type         string  `json:"type"`
+	Multiplier   float64 `json:"multiplier"`
+	Priority     int     `json:"priority"`
+	MinOccupancy float64 `json:"min_occupancy"`
+	MaxOccupancy float64 `json:"max_occupancy"`
+	Weekday      int     `json:"weekday"`
+	MinLeadDays  int     `json:"min_lead_days"`
+	MaxLeadDays  int     `json:"max_lead_days"`
+}
+
+// PricingPreviewPayload previews the dynamic price calendar of a room type between StartDate and
+// EndDate (both inclusive). When Rules is set they are evaluated instead of the hotel's stored rules
+// so that changes can be tried out before saving them
+type PricingPreviewPayload struct {
+	HotelUUID    string               `json:"hotel_uuid"`
+	RoomTypeUUID string               `json:"roomtype_uuid"`
+	StartDate    string               `json:"start_date"`
+	EndDate      string               `json:"end_date"`
+	Rules        []PricingRulePayload `json:"rules"`
+}
+
+// PromotionPayload is the payload used to create a Promotion, ValidFrom and ValidUntil are RFC 3339 timestamps
+type PromotionPayload struct {
+	Code            string   `json:"code"`
+	Description     string   `json:"description"`
+	DiscountType    string   `json:"discount_type"`
+	Value           float64  `json:"value"`
+	ValidFrom       string   `json:"valid_from"`
+	ValidUntil      string   `json:"valid_until"`
+	HotelUUIDs      []string `json:"hotel_uuids"`
+	RoomTypeUUIDs   []string `json:"roomtype_uuids"`
+	MaxUses         int      `json:"max_uses"`
+	MaxUsesPerGuest int      `json:"max_uses_per_guest"`
+}
+
+// WebhookSubscriptionPayload is the payload used to subscribe to a hotel's reservation events
+// Secret signs every delivery, and an empty EventTypes subscribes to every event
+type WebhookSubscriptionPayload struct {
+	HotelUUID  string   `json:"hotel_uuid"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+}
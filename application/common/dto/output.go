@@ -0,0 +1,45 @@
+package dto
+
+import "github.com/MelvinKim/Hotel-Reservation-System/domain"
+
+// BulkRateSummary summarizes the outcome of a bulk rate calendar upload
+type BulkRateSummary struct {
+	Inserted int64 `json:"inserted"`
+	Updated  int64 `json:"updated"`
+	Total    int64 `json:"total"`
+}
+
+// RoomTypeAvailability describes how many rooms of a room type can be booked for a stay and at what price
+type RoomTypeAvailability struct {
+	RoomType  domain.RoomType `json:"room_type"`
+	Available int64           `json:"available"`
+	// BasePrice is the stay's dynamic price from the room type's rate calendar, zero when a night has no rate
+	BasePrice int             `json:"base_price"`
+	Offers    []RatePlanOffer `json:"offers"`
+}
+
+// RatePlanOffer is the outcome of evaluating a rate plan against a stay
+type RatePlanOffer struct {
+	RatePlan     domain.RatePlan `json:"rate_plan"`
+	Sellable     bool            `json:"sellable"`
+	Restrictions []string        `json:"restrictions"`
+	TotalPrice   int             `json:"total_price"`
+}
+
+// PricedNight is a single night of a room type's dynamic price calendar
+type PricedNight struct {
+	Date      string  `json:"date"`
+	HasRate   bool    `json:"has_rate"`
+	BaseRate  int     `json:"base_rate"`
+	Occupancy float64 `json:"occupancy"`
+	Price     int     `json:"price"`
+	// AppliedRules names the pricing rules whose multipliers make up the price
+	AppliedRules []string `json:"applied_rules"`
+}
+
+// LoyaltyBalance is how many loyalty points a guest has and how much they take off a reservation
+type LoyaltyBalance struct {
+	GuestUUID string `json:"guest_uuid"`
+	Points    int    `json:"points"`
+	Value     int    `json:"value"`
+}
@@ -0,0 +1,530 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is the YAML file read when CONFIG_FILE isn't set, it is optional
+const defaultConfigFile = "config.yaml"
+
+// sslModes are the sslmode values postgres accepts
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// logLevels are the levels logs can be filtered at, from the least to the most verbose
+var logLevels = []string{"panic", "fatal", "error", "warn", "warning", "info", "debug", "trace"}
+
+// Log formats
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// Rate limit stores
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+)
+
+// Tracing exporters
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// Config is the configuration of the service
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Mail      MailConfig      `yaml:"mail"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Log       LogConfig       `yaml:"log"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+}
+
+// ServerConfig configures the HTTP server
+type ServerConfig struct {
+	Port int `yaml:"port"`
+	// ShutdownTimeout is how long in-flight requests and background workers are waited for on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// DrainDelay is how long new requests are still accepted once the readiness probe fails on shutdown,
+	// so that the load balancer notices and stops sending them before the server stops listening
+	DrainDelay time.Duration `yaml:"drain_delay"`
+}
+
+// DatabaseConfig configures the connection to the postgres database
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+	// TimeZone is the session time zone timestamps are read in, an IANA name such as UTC or Africa/Nairobi
+	TimeZone string `yaml:"timezone"`
+	// MaxOpenConns and MaxIdleConns bound the connection pool, zero MaxOpenConns is unlimited
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// ConnectRetries is how many more times the database is pinged on start up when it can't be reached,
+	// the wait between attempts starts at ConnectBackoff and doubles
+	ConnectRetries int           `yaml:"connect_retries"`
+	ConnectBackoff time.Duration `yaml:"connect_backoff"`
+	// ReadTimeout and WriteTimeout bound each query reading and writing the database that the caller's
+	// context doesn't bound more tightly, zero leaves them unbounded
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+}
+
+// MailConfig configures how guest emails are sent: through SMTP when SMTP.Host is set, written to Dir
+// when that is set instead, and logged otherwise
+type MailConfig struct {
+	SMTP SMTPConfig `yaml:"smtp"`
+	Dir  string     `yaml:"dir"`
+}
+
+// SMTPConfig configures the SMTP server guest emails are sent through
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// TracingConfig configures where the spans of requests, use cases and queries are exported
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp
+	Exporter string `yaml:"exporter"`
+	// OTLPEndpoint is the host:port of the OTLP/HTTP collector the otlp exporter sends spans to
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+	OTLPInsecure bool   `yaml:"otlp_insecure"`
+	ServiceName  string `yaml:"service_name"`
+	// SampleRatio is the share of traces started by the service that are recorded, between 0 and 1
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// LogConfig configures the service's logs
+type LogConfig struct {
+	// Level is the least severe level logged: error, warn, info, debug or trace
+	Level string `yaml:"level"`
+	// Format is json, one object per line, or text for reading logs in a terminal
+	Format string `yaml:"format"`
+}
+
+// RateLimitConfig configures how many requests each client can make to the API
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Store is memory, each replica counting on its own, or redis, the replicas sharing their counts
+	Store string      `yaml:"store"`
+	Redis RedisConfig `yaml:"redis"`
+	// TrustedProxies are the IPs or CIDRs of the proxies whose X-Forwarded-For header gives the client's IP
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// Default limits the routes that have no limit of their own, they share it
+	Default RateLimit `yaml:"default"`
+	// Routes limits each route on its own, keyed by method and route template, e.g. "POST /api/v1/reservation"
+	Routes map[string]RateLimit `yaml:"routes"`
+}
+
+// RateLimit lets a client make PerMinute requests a minute on average, and up to Burst at once
+type RateLimit struct {
+	PerMinute float64 `yaml:"per_minute"`
+	Burst     int     `yaml:"burst"`
+}
+
+// RedisConfig configures the connection to redis
+type RedisConfig struct {
+	// Addr is the host:port of the redis server
+	Addr     string `yaml:"addr"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+// Lookup returns the value of an environment variable and whether it is set
+type Lookup func(key string) (string, bool)
+
+// Default returns the configuration used for the settings that aren't set anywhere else
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            8000,
+			ShutdownTimeout: 20 * time.Second,
+			DrainDelay:      5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			SSLMode:         "disable",
+			TimeZone:        "UTC",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectRetries:  5,
+			ConnectBackoff:  500 * time.Millisecond,
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+		},
+		Mail: MailConfig{
+			SMTP: SMTPConfig{Port: 587},
+		},
+		Tracing: TracingConfig{
+			Exporter:     TracingExporterNone,
+			OTLPEndpoint: "localhost:4318",
+			ServiceName:  "hotel-reservation-system",
+			SampleRatio:  1,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: LogFormatJSON,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   RateLimitStoreMemory,
+			Redis:   RedisConfig{Addr: "localhost:6379"},
+			Default: RateLimit{PerMinute: 300, Burst: 60},
+			// booking and looking up reservations are limited more tightly, they can hoard inventory and
+			// guess confirmation codes, and availability is what scrapers are after
+			Routes: map[string]RateLimit{
+				"POST /api/v1/reservation":        {PerMinute: 20, Burst: 5},
+				"POST /api/v1/reservation/hold":   {PerMinute: 20, Burst: 5},
+				"POST /api/v1/bookings":           {PerMinute: 10, Burst: 3},
+				"POST /api/v1/waitlist":           {PerMinute: 20, Burst: 5},
+				"GET /api/v1/reservations/lookup": {PerMinute: 10, Burst: 5},
+				"GET /api/v1/availability":        {PerMinute: 60, Burst: 20},
+			},
+		},
+	}
+}
+
+// Load builds the service's configuration. From the lowest to the highest precedence, settings come
+// from the defaults, the YAML file at CONFIG_FILE (config.yaml when it isn't set and the file exists),
+// a .env file in the working directory and the process' environment
+func Load() (*Config, error) {
+	dotenv, err := godotenv.Read(".env")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("can't read .env: %w", err)
+	}
+	return LoadFrom(func(key string) (string, bool) {
+		if value, ok := os.LookupEnv(key); ok {
+			return value, true
+		}
+		value, ok := dotenv[key]
+		return value, ok
+	})
+}
+
+// LoadFrom builds the service's configuration from the defaults, the YAML file at CONFIG_FILE and the
+// environment variables found by lookup, see Load
+func LoadFrom(lookup Lookup) (*Config, error) {
+	cfg := Default()
+
+	path, _ := lookup("CONFIG_FILE")
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("can't parse config file %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && !explicit:
+	default:
+		return nil, fmt.Errorf("can't read config file %s: %w", path, err)
+	}
+
+	if err := cfg.applyEnv(lookup); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides the settings whose environment variable is set
+func (c *Config) applyEnv(lookup Lookup) error {
+	var problems []string
+	setString := func(key string, target *string) {
+		if value, ok := lookup(key); ok {
+			*target = value
+		}
+	}
+	setInt := func(key string, target *int) {
+		value, ok := lookup(key)
+		if !ok || value == "" {
+			return
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s must be a number, got %q", key, value))
+			return
+		}
+		*target = parsed
+	}
+	setBool := func(key string, target *bool) {
+		value, ok := lookup(key)
+		if !ok || value == "" {
+			return
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s must be true or false, got %q", key, value))
+			return
+		}
+		*target = parsed
+	}
+	setFloat := func(key string, target *float64) {
+		value, ok := lookup(key)
+		if !ok || value == "" {
+			return
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s must be a number, got %q", key, value))
+			return
+		}
+		*target = parsed
+	}
+	setDuration := func(key string, target *time.Duration) {
+		value, ok := lookup(key)
+		if !ok || value == "" {
+			return
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s must be a duration such as 30s or 5m, got %q", key, value))
+			return
+		}
+		*target = parsed
+	}
+
+	setInt("PORT", &c.Server.Port)
+	setDuration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	setDuration("SHUTDOWN_DRAIN_DELAY", &c.Server.DrainDelay)
+	setString("DB_HOST", &c.Database.Host)
+	setInt("DB_PORT", &c.Database.Port)
+	setString("DB_USER", &c.Database.User)
+	setString("DB_PASSWORD", &c.Database.Password)
+	setString("DB_NAME", &c.Database.Name)
+	setString("DB_SSLMODE", &c.Database.SSLMode)
+	setString("DB_TIMEZONE", &c.Database.TimeZone)
+	setInt("DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns)
+	setInt("DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns)
+	setDuration("DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime)
+	setDuration("DB_CONN_MAX_IDLE_TIME", &c.Database.ConnMaxIdleTime)
+	setInt("DB_CONNECT_RETRIES", &c.Database.ConnectRetries)
+	setDuration("DB_CONNECT_BACKOFF", &c.Database.ConnectBackoff)
+	setDuration("DB_READ_TIMEOUT", &c.Database.ReadTimeout)
+	setDuration("DB_WRITE_TIMEOUT", &c.Database.WriteTimeout)
+	setString("SMTP_HOST", &c.Mail.SMTP.Host)
+	setInt("SMTP_PORT", &c.Mail.SMTP.Port)
+	setString("SMTP_USERNAME", &c.Mail.SMTP.Username)
+	setString("SMTP_PASSWORD", &c.Mail.SMTP.Password)
+	setString("SMTP_FROM", &c.Mail.SMTP.From)
+	setString("MAIL_DIR", &c.Mail.Dir)
+	setString("TRACING_EXPORTER", &c.Tracing.Exporter)
+	setString("OTEL_EXPORTER_OTLP_ENDPOINT", &c.Tracing.OTLPEndpoint)
+	setBool("OTEL_EXPORTER_OTLP_INSECURE", &c.Tracing.OTLPInsecure)
+	setString("OTEL_SERVICE_NAME", &c.Tracing.ServiceName)
+	setFloat("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+	setString("LOG_LEVEL", &c.Log.Level)
+	setString("LOG_FORMAT", &c.Log.Format)
+	setBool("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+	setString("RATE_LIMIT_STORE", &c.RateLimit.Store)
+	setFloat("RATE_LIMIT_PER_MINUTE", &c.RateLimit.Default.PerMinute)
+	setInt("RATE_LIMIT_BURST", &c.RateLimit.Default.Burst)
+	if value, ok := lookup("RATE_LIMIT_TRUSTED_PROXIES"); ok {
+		c.RateLimit.TrustedProxies = splitList(value)
+	}
+	setString("REDIS_ADDR", &c.RateLimit.Redis.Addr)
+	setString("REDIS_PASSWORD", &c.RateLimit.Redis.Password)
+	setInt("REDIS_DB", &c.RateLimit.Redis.DB)
+
+	return invalid(problems)
+}
+
+// Validate checks that the configuration can be used to start the service
+func (c *Config) Validate() error {
+	var problems []string
+	if !validPort(c.Server.Port) {
+		problems = append(problems, fmt.Sprintf("server port (PORT) must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		problems = append(problems, fmt.Sprintf("shutdown timeout (SHUTDOWN_TIMEOUT) must be positive, got %s", c.Server.ShutdownTimeout))
+	}
+	if c.Server.DrainDelay < 0 {
+		problems = append(problems, fmt.Sprintf("shutdown drain delay (SHUTDOWN_DRAIN_DELAY) can't be negative, got %s", c.Server.DrainDelay))
+	}
+	if c.Database.Host == "" {
+		problems = append(problems, "database host (DB_HOST) is required")
+	}
+	if !validPort(c.Database.Port) {
+		problems = append(problems, fmt.Sprintf("database port (DB_PORT) must be between 1 and 65535, got %d", c.Database.Port))
+	}
+	if c.Database.User == "" {
+		problems = append(problems, "database user (DB_USER) is required")
+	}
+	if c.Database.Name == "" {
+		problems = append(problems, "database name (DB_NAME) is required")
+	}
+	if !contains(sslModes, c.Database.SSLMode) {
+		problems = append(problems, fmt.Sprintf(
+			"database sslmode (DB_SSLMODE) must be one of %s, got %q",
+			strings.Join(sslModes, ", "), c.Database.SSLMode,
+		))
+	}
+	if _, err := time.LoadLocation(c.Database.TimeZone); err != nil || c.Database.TimeZone == "" {
+		problems = append(problems, fmt.Sprintf("database timezone (DB_TIMEZONE) %q isn't a known time zone", c.Database.TimeZone))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		problems = append(problems, "database pool sizes (DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS) can't be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problems = append(problems, fmt.Sprintf(
+			"database idle connections (DB_MAX_IDLE_CONNS) %d can't exceed the open connections (DB_MAX_OPEN_CONNS) %d",
+			c.Database.MaxIdleConns, c.Database.MaxOpenConns,
+		))
+	}
+	if c.Database.ConnMaxLifetime < 0 || c.Database.ConnMaxIdleTime < 0 {
+		problems = append(problems, "database connection lifetimes (DB_CONN_MAX_LIFETIME, DB_CONN_MAX_IDLE_TIME) can't be negative")
+	}
+	if c.Database.ConnectRetries < 0 || c.Database.ConnectBackoff <= 0 {
+		problems = append(problems, "database connect retries (DB_CONNECT_RETRIES) can't be negative and the backoff (DB_CONNECT_BACKOFF) must be positive")
+	}
+	if c.Database.ReadTimeout < 0 || c.Database.WriteTimeout < 0 {
+		problems = append(problems, "database query timeouts (DB_READ_TIMEOUT, DB_WRITE_TIMEOUT) can't be negative")
+	}
+	if c.Mail.SMTP.Host != "" {
+		if !validPort(c.Mail.SMTP.Port) {
+			problems = append(problems, fmt.Sprintf("SMTP port (SMTP_PORT) must be between 1 and 65535, got %d", c.Mail.SMTP.Port))
+		}
+		if c.Mail.SMTP.From == "" {
+			problems = append(problems, "SMTP sender (SMTP_FROM) is required when SMTP_HOST is set")
+		}
+	}
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOTLP:
+		if c.Tracing.OTLPEndpoint == "" {
+			problems = append(problems, "OTLP endpoint (OTEL_EXPORTER_OTLP_ENDPOINT) is required by the otlp exporter")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf(
+			"tracing exporter (TRACING_EXPORTER) must be one of %s, %s or %s, got %q",
+			TracingExporterNone, TracingExporterStdout, TracingExporterOTLP, c.Tracing.Exporter,
+		))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, fmt.Sprintf("tracing sample ratio (TRACING_SAMPLE_RATIO) must be between 0 and 1, got %v", c.Tracing.SampleRatio))
+	}
+	if !contains(logLevels, strings.ToLower(c.Log.Level)) {
+		problems = append(problems, fmt.Sprintf("log level (LOG_LEVEL) must be one of %s, got %q", strings.Join(logLevels, ", "), c.Log.Level))
+	}
+	if c.Log.Format != LogFormatJSON && c.Log.Format != LogFormatText {
+		problems = append(problems, fmt.Sprintf("log format (LOG_FORMAT) must be %s or %s, got %q", LogFormatJSON, LogFormatText, c.Log.Format))
+	}
+	if c.RateLimit.Enabled {
+		problems = append(problems, c.RateLimit.validate()...)
+	}
+	return invalid(problems)
+}
+
+// validate returns the problems of an enabled rate limit configuration
+func (c *RateLimitConfig) validate() []string {
+	var problems []string
+	switch c.Store {
+	case RateLimitStoreMemory:
+	case RateLimitStoreRedis:
+		if c.Redis.Addr == "" {
+			problems = append(problems, "redis address (REDIS_ADDR) is required by the redis rate limit store")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf(
+			"rate limit store (RATE_LIMIT_STORE) must be %s or %s, got %q",
+			RateLimitStoreMemory, RateLimitStoreRedis, c.Store,
+		))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			problems = append(problems, fmt.Sprintf("trusted proxy (RATE_LIMIT_TRUSTED_PROXIES) %q isn't an IP or a CIDR", proxy))
+		}
+	}
+	if !c.Default.valid() {
+		problems = append(problems, fmt.Sprintf(
+			"default rate limit (RATE_LIMIT_PER_MINUTE, RATE_LIMIT_BURST) must allow a positive number of requests a minute and a burst of at least 1, got %v a minute and a burst of %d",
+			c.Default.PerMinute, c.Default.Burst,
+		))
+	}
+	routes := make([]string, 0, len(c.Routes))
+	for route := range c.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		method, path, ok := strings.Cut(route, " ")
+		if !ok || method == "" || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			problems = append(problems, fmt.Sprintf("rate limited route %q must be a method and a route template, e.g. \"POST /api/v1/reservation\"", route))
+		}
+		if limit := c.Routes[route]; !limit.valid() {
+			problems = append(problems, fmt.Sprintf(
+				"rate limit of %s must allow a positive number of requests a minute and a burst of at least 1, got %v a minute and a burst of %d",
+				route, limit.PerMinute, limit.Burst,
+			))
+		}
+	}
+	return problems
+}
+
+func (l RateLimit) valid() bool {
+	return l.PerMinute > 0 && l.Burst >= 1
+}
+
+// splitList splits a comma separated list, ignoring blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// invalid reports every problem found in the configuration at once
+func invalid(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
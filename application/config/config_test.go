@@ -0,0 +1,178 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
+)
+
+// lookupIn looks environment variables up in env
+func lookupIn(env map[string]string) config.Lookup {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestLoadFrom(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+server:
+  port: 9100
+database:
+  host: db.internal
+  user: hotel
+  name: reservations
+  timezone: Africa/Nairobi
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("can't write config file: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantPort     int
+		wantHost     string
+		wantTimeZone string
+		wantErr      string
+	}{
+		{
+			name:         "file overrides the defaults",
+			env:          map[string]string{"CONFIG_FILE": path},
+			wantPort:     9100,
+			wantHost:     "db.internal",
+			wantTimeZone: "Africa/Nairobi",
+		},
+		{
+			name:         "environment overrides the file",
+			env:          map[string]string{"CONFIG_FILE": path, "PORT": "8000", "DB_HOST": "localhost"},
+			wantPort:     8000,
+			wantHost:     "localhost",
+			wantTimeZone: "Africa/Nairobi",
+		},
+		{
+			name:         "defaults without a file",
+			env:          map[string]string{"CONFIG_FILE": "", "DB_USER": "hotel", "DB_NAME": "reservations"},
+			wantPort:     8000,
+			wantHost:     "localhost",
+			wantTimeZone: "UTC",
+		},
+		{
+			name:    "missing explicit file",
+			env:     map[string]string{"CONFIG_FILE": filepath.Join(t.TempDir(), "missing.yaml")},
+			wantErr: "can't read config file",
+		},
+		{
+			name:    "port isn't a number",
+			env:     map[string]string{"CONFIG_FILE": path, "PORT": "eighty"},
+			wantErr: "PORT must be a number",
+		},
+		{
+			name:    "invalid settings are all reported",
+			env:     map[string]string{"CONFIG_FILE": path, "DB_TIMEZONE": "Mars/Olympus", "DB_SSLMODE": "maybe"},
+			wantErr: "DB_SSLMODE",
+		},
+		{
+			name:    "pool can't keep more idle connections than it opens",
+			env:     map[string]string{"CONFIG_FILE": path, "DB_MAX_OPEN_CONNS": "5", "DB_MAX_IDLE_CONNS": "10"},
+			wantErr: "DB_MAX_IDLE_CONNS",
+		},
+		{
+			name:    "lifetime isn't a duration",
+			env:     map[string]string{"CONFIG_FILE": path, "DB_CONN_MAX_LIFETIME": "forever"},
+			wantErr: "DB_CONN_MAX_LIFETIME must be a duration",
+		},
+		{
+			name:    "query timeouts can't be negative",
+			env:     map[string]string{"CONFIG_FILE": path, "DB_WRITE_TIMEOUT": "-1s"},
+			wantErr: "DB_WRITE_TIMEOUT",
+		},
+		{
+			name:    "drain delay can't be negative",
+			env:     map[string]string{"CONFIG_FILE": path, "SHUTDOWN_DRAIN_DELAY": "-5s"},
+			wantErr: "SHUTDOWN_DRAIN_DELAY",
+		},
+		{
+			name:    "unknown log level",
+			env:     map[string]string{"CONFIG_FILE": path, "LOG_LEVEL": "verbose"},
+			wantErr: "LOG_LEVEL",
+		},
+		{
+			name:    "trusted proxies must be IPs or CIDRs",
+			env:     map[string]string{"CONFIG_FILE": path, "RATE_LIMIT_TRUSTED_PROXIES": "172.28.0.10, proxy"},
+			wantErr: `RATE_LIMIT_TRUSTED_PROXIES) "proxy"`,
+		},
+		{
+			name:    "unknown rate limit store",
+			env:     map[string]string{"CONFIG_FILE": path, "RATE_LIMIT_STORE": "memcached"},
+			wantErr: "RATE_LIMIT_STORE",
+		},
+		{
+			name:    "SMTP needs a sender",
+			env:     map[string]string{"CONFIG_FILE": path, "SMTP_HOST": "smtp.example.com"},
+			wantErr: "SMTP_FROM",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.LoadFrom(lookupIn(tt.env))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadFrom() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFrom() unexpected error = %v", err)
+			}
+			if cfg.Server.Port != tt.wantPort {
+				t.Errorf("Server.Port = %d, want %d", cfg.Server.Port, tt.wantPort)
+			}
+			if cfg.Database.Host != tt.wantHost {
+				t.Errorf("Database.Host = %q, want %q", cfg.Database.Host, tt.wantHost)
+			}
+			if cfg.Database.TimeZone != tt.wantTimeZone {
+				t.Errorf("Database.TimeZone = %q, want %q", cfg.Database.TimeZone, tt.wantTimeZone)
+			}
+		})
+	}
+}
+
+func TestLoadFrom_rateLimitRoutes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+database:
+  user: hotel
+  name: reservations
+rate_limit:
+  routes:
+    POST /api/v1/reservation:
+      per_minute: 5
+      burst: 1
+    GET /api/v1/pricing-rules:
+      per_minute: 30
+      burst: 10
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("can't write config file: %v", err)
+	}
+
+	cfg, err := config.LoadFrom(lookupIn(map[string]string{"CONFIG_FILE": path}))
+	if err != nil {
+		t.Fatalf("LoadFrom() unexpected error = %v", err)
+	}
+	routes := cfg.RateLimit.Routes
+	if got := routes["POST /api/v1/reservation"]; got != (config.RateLimit{PerMinute: 5, Burst: 1}) {
+		t.Errorf("overridden route limit = %+v, want the file's", got)
+	}
+	if _, ok := routes["GET /api/v1/pricing-rules"]; !ok {
+		t.Errorf("expected the file to add a route limit")
+	}
+	if _, ok := routes["GET /api/v1/availability"]; !ok {
+		t.Errorf("expected the default route limits the file doesn't mention to be kept")
+	}
+}
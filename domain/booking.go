@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BookingStatus is the status of a booking and of each of its lines
+type BookingStatus string
+
+const (
+	// BOOKING_CONFIRMED rooms are booked
+	BOOKING_CONFIRMED BookingStatus = "CONFIRMED"
+	// BOOKING_BLOCKED rooms are held for a group until the booking's ReleaseDate
+	BOOKING_BLOCKED BookingStatus = "BLOCKED"
+	// BOOKING_RELEASED rooms were given back because the group block wasn't confirmed before its ReleaseDate
+	BOOKING_RELEASED BookingStatus = "RELEASED"
+	// BOOKING_CANCELLED rooms were given back by the guest
+	BOOKING_CANCELLED BookingStatus = "CANCELLED"
+)
+
+// Booking is a multi-room reservation for a family or a group, one line per room type.
+// Every line is booked or none is
+type Booking struct {
+	AbstractBase `gorm:"embedded"`
+	GuestUUID    string        `json:"guest_uuid"`
+	Guest        Guest         `json:"guest,omitempty" gorm:"foreignKey:GuestUUID"`
+	HotelUUID    string        `json:"hotel_uuid" gorm:"index"`
+	Hotel        Hotel         `json:"hotel,omitempty" gorm:"foreignKey:HotelUUID"`
+	GroupName    string        `json:"group_name,omitempty"`
+	StartDate    time.Time     `json:"start_date" gorm:"not null"`
+	EndDate      time.Time     `json:"end_date" gorm:"not null"`
+	Status       BookingStatus `json:"status"`
+	// ReleaseDate is when the rooms of an unconfirmed group block are given back
+	ReleaseDate *time.Time    `json:"release_date,omitempty" gorm:"index"`
+	TotalPrice  int           `json:"total_price"`
+	Lines       []BookingLine `json:"lines" gorm:"foreignKey:BookingUUID"`
+	// PaymentMethod is the payment provider's token used to pay for the booking, it isn't stored
+	PaymentMethod string `json:"payment_method,omitempty" gorm:"-"`
+}
+
+// BookingLine is a number of rooms of one room type within a booking
+type BookingLine struct {
+	AbstractBase `gorm:"embedded"`
+	BookingUUID  string   `json:"booking_uuid" gorm:"index;not null"`
+	RoomTypeUUID string   `json:"roomtype_uuid"`
+	RoomType     RoomType `json:"room_type,omitempty" gorm:"foreignKey:RoomTypeUUID"`
+	RatePlanUUID *string  `json:"rateplan_uuid,omitempty"`
+	Quantity     int64    `json:"quantity"`
+	// Occupants is how many guests stay in each room of the line
+	Occupants int `json:"occupants"`
+	// UnitPrice is the price of the stay in one room of the line
+	UnitPrice  int           `json:"unit_price"`
+	TotalPrice int           `json:"total_price"`
+	Status     BookingStatus `json:"status"`
+}
+
+// Validate checks a booking can be made
+func (b *Booking) Validate() error {
+	if b.GuestUUID == "" {
+		return errors.New("a guest is required")
+	}
+	if err := CheckStayLength(b.StartDate, b.EndDate); err != nil {
+		return err
+	}
+	if len(StayNights(b.StartDate, b.EndDate)) == 0 {
+		return errors.New("a booking must be at least one night long")
+	}
+	if len(b.Lines) == 0 {
+		return errors.New("a booking needs at least one room")
+	}
+	for i, line := range b.Lines {
+		if line.RoomTypeUUID == "" {
+			return fmt.Errorf("line %d: a room type is required", i)
+		}
+		if line.Quantity < 1 {
+			return fmt.Errorf("line %d: at least one room is required", i)
+		}
+		if line.Occupants < 0 {
+			return fmt.Errorf("line %d: occupants can't be negative", i)
+		}
+	}
+	if b.ReleaseDate != nil && b.ReleaseDate.After(b.StartDate) {
+		return errors.New("a group block must be released on or before its start date")
+	}
+	return nil
+}
+
+// IsOpen reports whether the line still holds its rooms
+func (l *BookingLine) IsOpen() bool {
+	return l.Status == BOOKING_CONFIRMED || l.Status == BOOKING_BLOCKED
+}
+
+// RecalculateTotal sets the booking's price to that of the lines that still hold their rooms
+func (b *Booking) RecalculateTotal() {
+	b.TotalPrice = 0
+	for _, line := range b.Lines {
+		if line.IsOpen() {
+			b.TotalPrice += line.TotalPrice
+		}
+	}
+}
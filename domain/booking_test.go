@@ -0,0 +1,73 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+func TestBooking_Validate(t *testing.T) {
+	start := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	line := domain.BookingLine{RoomTypeUUID: "room-type", Quantity: 2, Occupants: 2}
+	lateRelease := start.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name    string
+		booking domain.Booking
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			booking: domain.Booking{GuestUUID: "guest", StartDate: start, EndDate: start.AddDate(0, 0, 2), Lines: []domain.BookingLine{line}},
+		},
+		{
+			name:    "no guest",
+			booking: domain.Booking{StartDate: start, EndDate: start.AddDate(0, 0, 2), Lines: []domain.BookingLine{line}},
+			wantErr: true,
+		},
+		{
+			name:    "no lines",
+			booking: domain.Booking{GuestUUID: "guest", StartDate: start, EndDate: start.AddDate(0, 0, 2)},
+			wantErr: true,
+		},
+		{
+			name: "no rooms",
+			booking: domain.Booking{GuestUUID: "guest", StartDate: start, EndDate: start.AddDate(0, 0, 2), Lines: []domain.BookingLine{
+				{RoomTypeUUID: "room-type"},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "no nights",
+			booking: domain.Booking{GuestUUID: "guest", StartDate: start, EndDate: start, Lines: []domain.BookingLine{line}},
+			wantErr: true,
+		},
+		{
+			name: "released after arrival",
+			booking: domain.Booking{
+				GuestUUID: "guest", StartDate: start, EndDate: start.AddDate(0, 0, 2), ReleaseDate: &lateRelease, Lines: []domain.BookingLine{line},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.booking.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Booking.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBooking_RecalculateTotal(t *testing.T) {
+	booking := domain.Booking{Lines: []domain.BookingLine{
+		{TotalPrice: 400, Status: domain.BOOKING_CONFIRMED},
+		{TotalPrice: 200, Status: domain.BOOKING_CANCELLED},
+		{TotalPrice: 100, Status: domain.BOOKING_BLOCKED},
+	}}
+	booking.RecalculateTotal()
+	if booking.TotalPrice != 500 {
+		t.Errorf("Booking.RecalculateTotal() = %v, want %v", booking.TotalPrice, 500)
+	}
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ConfirmationCodeLength is the length of a confirmation code, check character included
+	ConfirmationCodeLength = 8
+	// crockfordAlphabet is Crockford's base32 alphabet, it leaves out I, L, O and U so that codes can be
+	// read out over the phone without being misheard
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// confirmationCodeAliases maps the characters a guest may type by mistake to the one they stand for
+var confirmationCodeAliases = strings.NewReplacer("-", "", " ", "", "I", "1", "L", "1", "O", "0")
+
+// NewConfirmationCode draws a random confirmation code of 7 Crockford base32 characters followed by a
+// check character. Codes are random rather than sequential so they can't be guessed from one another
+func NewConfirmationCode() (string, error) {
+	random := make([]byte, ConfirmationCodeLength-1)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("can't generate a confirmation code: %w", err)
+	}
+	code := make([]byte, 0, ConfirmationCodeLength)
+	for _, b := range random {
+		// 256 is a multiple of 32 so every character is equally likely
+		code = append(code, crockfordAlphabet[b%32])
+	}
+	return string(append(code, checkCharacter(code))), nil
+}
+
+// NormalizeConfirmationCode upper cases a code typed by a guest and undoes the usual typing mistakes
+func NormalizeConfirmationCode(code string) string {
+	return confirmationCodeAliases.Replace(strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// ValidConfirmationCode reports whether a normalized code is well formed and its check character matches
+func ValidConfirmationCode(code string) bool {
+	if len(code) != ConfirmationCodeLength {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if strings.IndexByte(crockfordAlphabet, code[i]) < 0 {
+			return false
+		}
+	}
+	return checkCharacter([]byte(code[:ConfirmationCodeLength-1])) == code[ConfirmationCodeLength-1]
+}
+
+// checkCharacter is the Luhn mod 32 check character of a code, it catches every single character typo
+// and most swaps of adjacent characters
+func checkCharacter(code []byte) byte {
+	factor, sum := 2, 0
+	for i := len(code) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(crockfordAlphabet, code[i])
+		sum += addend/32 + addend%32
+		factor = 3 - factor
+	}
+	return crockfordAlphabet[(32-sum%32)%32]
+}
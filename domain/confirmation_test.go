@@ -0,0 +1,63 @@
+package domain_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+func TestNewConfirmationCode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		code, err := domain.NewConfirmationCode()
+		if err != nil {
+			t.Fatalf("NewConfirmationCode() unexpected error = %v", err)
+		}
+		if !domain.ValidConfirmationCode(code) {
+			t.Fatalf("NewConfirmationCode() = %v, which isn't valid", code)
+		}
+		if seen[code] {
+			t.Fatalf("NewConfirmationCode() repeated %v", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestValidConfirmationCode(t *testing.T) {
+	code, err := domain.NewConfirmationCode()
+	if err != nil {
+		t.Fatalf("NewConfirmationCode() unexpected error = %v", err)
+	}
+	typo := []byte(code)
+	if typo[2] == '7' {
+		typo[2] = '8'
+	} else {
+		typo[2] = '7'
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{name: "valid", code: code, want: true},
+		{name: "typed in lower case with a dash", code: domain.NormalizeConfirmationCode(" " + strings.ToLower(code[:4]) + "-" + code[4:] + " "), want: true},
+		{name: "single character typo", code: string(typo), want: false},
+		{name: "too short", code: code[:7], want: false},
+		{name: "outside the alphabet", code: "UUUUUUUU", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := domain.ValidConfirmationCode(tt.code); got != tt.want {
+				t.Errorf("ValidConfirmationCode(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeConfirmationCode(t *testing.T) {
+	if got := domain.NormalizeConfirmationCode("ab1o-il2z"); got != "AB10112Z" {
+		t.Errorf("NormalizeConfirmationCode() = %v, want %v", got, "AB10112Z")
+	}
+}
@@ -0,0 +1,49 @@
+package domain
+
+import "time"
+
+// MaxStayNights bounds how long a stay can be, so that a request can't make the service list, price or
+// claim the inventory of millions of nights
+const MaxStayNights = 365
+
+// DateLayout is the layout used for calendar dates exchanged with clients e.g 2023-05-20
+const DateLayout = "2006-01-02"
+
+// ParseDate parses a calendar date in the DateLayout format
+func ParseDate(value string) (time.Time, error) {
+	return time.Parse(DateLayout, value)
+}
+
+// TruncateToDate drops the time of day, returning midnight UTC of the same calendar date
+func TruncateToDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// CountNights counts the nights between check-in and check-out without listing them, so that a span can be
+// bounded before StayNights materializes it. Spans too long for a time.Duration count as the longest one
+func CountNights(checkIn, checkOut time.Time) int {
+	span := TruncateToDate(checkOut).Sub(TruncateToDate(checkIn))
+	if span <= 0 {
+		return 0
+	}
+	return int(span / (24 * time.Hour))
+}
+
+// CheckStayLength returns ErrStayTooLong when a stay is longer than MaxStayNights, without listing its nights
+func CheckStayLength(checkIn, checkOut time.Time) error {
+	if CountNights(checkIn, checkOut) > MaxStayNights {
+		return ErrStayTooLong
+	}
+	return nil
+}
+
+// StayNights lists the nights of a stay i.e every calendar date from check-in up to, but excluding, check-out
+func StayNights(checkIn, checkOut time.Time) []time.Time {
+	nights := []time.Time{}
+	last := TruncateToDate(checkOut)
+	for night := TruncateToDate(checkIn); night.Before(last); night = night.AddDate(0, 0, 1) {
+		nights = append(nights, night)
+	}
+	return nights
+}
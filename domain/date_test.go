@@ -0,0 +1,41 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+func TestCountNights(t *testing.T) {
+	date := func(value string) time.Time {
+		parsed, err := domain.ParseDate(value)
+		if err != nil {
+			t.Fatalf("can't parse %s: %v", value, err)
+		}
+		return parsed
+	}
+	tests := []struct {
+		name     string
+		checkIn  time.Time
+		checkOut time.Time
+		want     int
+	}{
+		{name: "three nights", checkIn: date("2023-06-01"), checkOut: date("2023-06-04"), want: 3},
+		{name: "times of day are ignored", checkIn: date("2023-06-01").Add(22 * time.Hour), checkOut: date("2023-06-02").Add(time.Hour), want: 1},
+		{name: "check-out before check-in", checkIn: date("2023-06-04"), checkOut: date("2023-06-01"), want: 0},
+		{name: "span too long for a duration", checkIn: date("0001-01-01"), checkOut: date("9999-12-31"), want: 106751},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := domain.CountNights(tt.checkIn, tt.checkOut); got != tt.want {
+				t.Errorf("CountNights() = %d, want %d", got, tt.want)
+			}
+			if tt.want < 1000 {
+				if nights := domain.StayNights(tt.checkIn, tt.checkOut); len(nights) != tt.want {
+					t.Errorf("CountNights() = %d but StayNights() has %d nights", tt.want, len(nights))
+				}
+			}
+		})
+	}
+}
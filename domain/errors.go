@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrSoldOut is returned when a room type has no inventory left for at least one of the requested nights
+	ErrSoldOut = errors.New("the room type is sold out for the requested dates")
+	// ErrStayRestricted is returned when a stay breaks one of the restrictions of its rate plan
+	ErrStayRestricted = errors.New("the stay doesn't satisfy the rate plan's restrictions")
+	// ErrStayTooLong is returned when a stay is longer than MaxStayNights
+	ErrStayTooLong = fmt.Errorf("a stay can be at most %d nights long", MaxStayNights)
+	// ErrMissingRate is returned when a night within a stay has no price
+	ErrMissingRate = errors.New("no rate has been set for one of the requested nights")
+	// ErrPromotionNotApplicable is returned when a promo code doesn't exist or can't be used for a booking
+	ErrPromotionNotApplicable = errors.New("the promo code can't be applied to this reservation")
+	// ErrPromotionExhausted is returned when a promotion has reached one of its usage caps
+	ErrPromotionExhausted = errors.New("the promo code has reached its usage limit")
+	// ErrPaymentDeclined is returned when a reservation's payment isn't authorized
+	ErrPaymentDeclined = errors.New("the reservation's payment was declined")
+	// ErrHoldExpired is returned when confirming a hold that has lapsed or doesn't exist
+	ErrHoldExpired = errors.New("the reservation hold has expired")
+	// ErrWaitlistEntryTaken is returned when offering a room to a waitlist entry that is no longer waiting
+	ErrWaitlistEntryTaken = errors.New("the waitlist entry is no longer waiting")
+	// ErrReservationNotFound is returned when no reservation matches a confirmation code and guest name
+	ErrReservationNotFound = errors.New("no reservation matches the confirmation code and name")
+	// ErrInsufficientPoints is returned when a guest redeems more loyalty points than they have
+	ErrInsufficientPoints = errors.New("the guest doesn't have enough loyalty points")
+	// ErrBlockReleased is returned when confirming a group block whose rooms have been released
+	ErrBlockReleased = errors.New("the group block has been released")
+)
+
+// TimeoutError is returned when an operation is cut short because its deadline passed or its caller
+// went away. Err is context.DeadlineExceeded or context.Canceled
+type TimeoutError struct {
+	Operation string
+	Err       error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s didn't complete in time: %v", e.Operation, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
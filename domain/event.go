@@ -0,0 +1,369 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EventType names something that happened to a reservation or a multi-room booking
+type EventType string
+
+const (
+	RESERVATION_HELD           EventType = "reservation.held"
+	RESERVATION_CREATED        EventType = "reservation.created"
+	RESERVATION_PAYMENT_FAILED EventType = "reservation.payment_failed"
+	RESERVATION_HOLD_EXPIRED   EventType = "reservation.hold_expired"
+	RESERVATION_CANCELLED      EventType = "reservation.cancelled"
+	RESERVATION_CHECKED_IN     EventType = "reservation.checked_in"
+	RESERVATION_CHECKED_OUT    EventType = "reservation.checked_out"
+	// RESERVATION_ARRIVAL_DUE is recorded a few days before a RESERVED stay starts
+	RESERVATION_ARRIVAL_DUE EventType = "reservation.arrival_due"
+	// RESERVATION_WAITLIST_OFFERED is recorded when a waitlisted guest is offered a hold on a freed room
+	RESERVATION_WAITLIST_OFFERED EventType = "reservation.waitlist_offered"
+	// RESERVATION_MODIFIED is recorded when a reservation's dates or room type change
+	RESERVATION_MODIFIED EventType = "reservation.modified"
+	// BOOKING_CREATED is recorded when a multi-room booking or a group block is made
+	BOOKING_CREATED EventType = "booking.created"
+	// BOOKING_LINE_CANCELLED is recorded when the rooms of one line of a booking are given back
+	BOOKING_LINE_CANCELLED EventType = "booking.line_cancelled"
+	// BOOKING_BLOCK_CONFIRMED is recorded when the rooms of a group block are booked
+	BOOKING_BLOCK_CONFIRMED EventType = "booking.block_confirmed"
+	// BOOKING_BLOCK_RELEASED is recorded when a group block passes its release date unconfirmed
+	BOOKING_BLOCK_RELEASED EventType = "booking.block_released"
+)
+
+// ReservationEventVersion is bumped whenever a field of ReservationEvent changes meaning or is removed,
+// adding fields doesn't change the version
+const ReservationEventVersion = 1
+
+// ReservationEvent is the payload published to downstream systems when a reservation changes
+type ReservationEvent struct {
+	Version         int       `json:"version"`
+	Type            EventType `json:"type"`
+	OccurredAt      time.Time `json:"occurred_at"`
+	ReservationUUID string    `json:"reservation_uuid"`
+	GuestUUID       string    `json:"guest_uuid"`
+	HotelUUID       string    `json:"hotel_uuid"`
+	RoomTypeUUID    string    `json:"roomtype_uuid"`
+	RatePlanUUID    *string   `json:"rateplan_uuid,omitempty"`
+	StartDate       string    `json:"start_date"`
+	EndDate         string    `json:"end_date"`
+	Status          string    `json:"status"`
+	TotalPrice      int       `json:"total_price"`
+	Discount        int       `json:"discount"`
+	// ExpiresAt is when a HELD or PENDING reservation lapses
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// ConfirmationCode is empty for reservations made before confirmation codes existed
+	ConfirmationCode string `json:"confirmation_code,omitempty"`
+	// Released is the stay a RESERVATION_MODIFIED reservation gave back for its new one
+	Released *FreedStay `json:"released,omitempty"`
+}
+
+// BookingEventVersion is bumped whenever a field of BookingEvent changes meaning or is removed,
+// adding fields doesn't change the version
+const BookingEventVersion = 1
+
+// BookingEvent is the payload published to downstream systems when a multi-room booking changes
+type BookingEvent struct {
+	Version     int                `json:"version"`
+	Type        EventType          `json:"type"`
+	OccurredAt  time.Time          `json:"occurred_at"`
+	BookingUUID string             `json:"booking_uuid"`
+	GuestUUID   string             `json:"guest_uuid"`
+	HotelUUID   string             `json:"hotel_uuid"`
+	GroupName   string             `json:"group_name,omitempty"`
+	StartDate   string             `json:"start_date"`
+	EndDate     string             `json:"end_date"`
+	Status      BookingStatus      `json:"status"`
+	ReleaseDate *time.Time         `json:"release_date,omitempty"`
+	TotalPrice  int                `json:"total_price"`
+	Lines       []BookingLineEvent `json:"lines"`
+	// LineUUID is the line a BOOKING_LINE_CANCELLED event cancelled
+	LineUUID string `json:"line_uuid,omitempty"`
+}
+
+// BookingLineEvent is a line of the booking a BookingEvent describes
+type BookingLineEvent struct {
+	LineUUID     string        `json:"line_uuid"`
+	RoomTypeUUID string        `json:"roomtype_uuid"`
+	RatePlanUUID *string       `json:"rateplan_uuid,omitempty"`
+	Quantity     int64         `json:"quantity"`
+	Occupants    int           `json:"occupants"`
+	TotalPrice   int           `json:"total_price"`
+	Status       BookingStatus `json:"status"`
+}
+
+// FreedStay is a stay of a room type whose inventory was given back, waitlisted guests may book it
+type FreedStay struct {
+	RoomTypeUUID string `json:"roomtype_uuid"`
+	StartDate    string `json:"start_date"`
+	EndDate      string `json:"end_date"`
+}
+
+// OutboxEvent is an event waiting to be published, written in the same transaction as the change it
+// describes so that no change is committed without its event
+type OutboxEvent struct {
+	AbstractBase  `gorm:"embedded"`
+	AggregateUUID string          `json:"aggregate_uuid" gorm:"index;not null"`
+	HotelUUID     string          `json:"hotel_uuid" gorm:"index"`
+	Type          EventType       `json:"type" gorm:"not null"`
+	Payload       json.RawMessage `json:"payload" gorm:"type:jsonb;not null"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	PublishedAt   *time.Time      `json:"published_at" gorm:"index"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error,omitempty"`
+}
+
+// EventDelivery is an outbox event being handed to one of the consumers of the outbox. Every consumer
+// gets a delivery of its own, so that a failing consumer neither holds the others back nor makes them
+// consume an event again when it is retried
+type EventDelivery struct {
+	AbstractBase  `gorm:"embedded"`
+	Consumer      string         `json:"consumer" gorm:"uniqueIndex:idx_event_deliveries_consumer_event;not null"`
+	EventUUID     string         `json:"event_uuid" gorm:"uniqueIndex:idx_event_deliveries_consumer_event;not null"`
+	Event         *OutboxEvent   `json:"-" gorm:"foreignKey:EventUUID"`
+	OccurredAt    time.Time      `json:"occurred_at"`
+	Status        DeliveryStatus `json:"status" gorm:"index"`
+	Attempts      int            `json:"attempts"`
+	NextAttemptAt time.Time      `json:"next_attempt_at" gorm:"index"`
+	LastError     string         `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time     `json:"delivered_at,omitempty"`
+}
+
+const (
+	// MaxEventDeliveryAttempts is how many times an event is handed to a consumer before it is dead lettered
+	MaxEventDeliveryAttempts = 20
+	// EventDeliveryLease is how long a consumer has to consume the events it claimed before they are
+	// claimed again
+	EventDeliveryLease = 5 * time.Minute
+	// eventDeliveryBaseBackoff is the wait after the first failed attempt, it doubles after every failure
+	eventDeliveryBaseBackoff = 10 * time.Second
+	// eventDeliveryMaxBackoff caps the wait between two attempts
+	eventDeliveryMaxBackoff = time.Hour
+)
+
+// EventDeliveryBackoff is how long to wait before handing an event to a consumer again after it failed
+// attempts times
+func EventDeliveryBackoff(attempts int) time.Duration {
+	return exponentialBackoff(attempts, eventDeliveryBaseBackoff, eventDeliveryMaxBackoff)
+}
+
+// RecordAttempt updates the delivery with the outcome of an attempt made at the given time.
+// Failed deliveries are retried with an exponential backoff until MaxEventDeliveryAttempts is reached
+func (d *EventDelivery) RecordAttempt(at time.Time, err error) {
+	d.Attempts++
+	if err == nil {
+		d.Status = DELIVERED
+		d.LastError = ""
+		d.DeliveredAt = &at
+		return
+	}
+	d.LastError = err.Error()
+	if d.Attempts >= MaxEventDeliveryAttempts {
+		d.Status = DEAD_LETTERED
+		return
+	}
+	d.NextAttemptAt = at.Add(EventDeliveryBackoff(d.Attempts))
+}
+
+// NewReservationEvent builds the outbox event for a change to a reservation
+func NewReservationEvent(
+	eventType EventType,
+	reservation *Reservation,
+	occurredAt time.Time,
+) (*OutboxEvent, error) {
+	event := reservationEvent(eventType, reservation, occurredAt)
+	return newOutboxEvent(reservation.UUID, reservation.HotelUUID, eventType, event, occurredAt)
+}
+
+// NewReservationModifiedEvent builds the RESERVATION_MODIFIED event of a reservation that was moved from
+// the stay of previous, which it released
+func NewReservationModifiedEvent(
+	reservation *Reservation,
+	previous *Reservation,
+	occurredAt time.Time,
+) (*OutboxEvent, error) {
+	event := reservationEvent(RESERVATION_MODIFIED, reservation, occurredAt)
+	event.Released = &FreedStay{
+		RoomTypeUUID: previous.RoomTypeUUID,
+		StartDate:    previous.StartDate.Format(DateLayout),
+		EndDate:      previous.EndDate.Format(DateLayout),
+	}
+	return newOutboxEvent(reservation.UUID, reservation.HotelUUID, RESERVATION_MODIFIED, event, occurredAt)
+}
+
+// NewBookingEvent builds the outbox event for a change to a multi-room booking
+func NewBookingEvent(
+	eventType EventType,
+	booking *Booking,
+	occurredAt time.Time,
+) (*OutboxEvent, error) {
+	event := bookingEvent(eventType, booking, occurredAt)
+	return newOutboxEvent(booking.UUID, booking.HotelUUID, eventType, event, occurredAt)
+}
+
+// NewBookingLineCancelledEvent builds the BOOKING_LINE_CANCELLED event of a booking whose line was cancelled
+func NewBookingLineCancelledEvent(
+	booking *Booking,
+	line *BookingLine,
+	occurredAt time.Time,
+) (*OutboxEvent, error) {
+	event := bookingEvent(BOOKING_LINE_CANCELLED, booking, occurredAt)
+	event.LineUUID = line.UUID
+	return newOutboxEvent(booking.UUID, booking.HotelUUID, BOOKING_LINE_CANCELLED, event, occurredAt)
+}
+
+// bookingEvent is the payload of an event of a booking
+func bookingEvent(
+	eventType EventType,
+	booking *Booking,
+	occurredAt time.Time,
+) BookingEvent {
+	lines := make([]BookingLineEvent, 0, len(booking.Lines))
+	for _, line := range booking.Lines {
+		lines = append(lines, BookingLineEvent{
+			LineUUID:     line.UUID,
+			RoomTypeUUID: line.RoomTypeUUID,
+			RatePlanUUID: line.RatePlanUUID,
+			Quantity:     line.Quantity,
+			Occupants:    line.Occupants,
+			TotalPrice:   line.TotalPrice,
+			Status:       line.Status,
+		})
+	}
+	return BookingEvent{
+		Version:     BookingEventVersion,
+		Type:        eventType,
+		OccurredAt:  occurredAt,
+		BookingUUID: booking.UUID,
+		GuestUUID:   booking.GuestUUID,
+		HotelUUID:   booking.HotelUUID,
+		GroupName:   booking.GroupName,
+		StartDate:   booking.StartDate.Format(DateLayout),
+		EndDate:     booking.EndDate.Format(DateLayout),
+		Status:      booking.Status,
+		ReleaseDate: booking.ReleaseDate,
+		TotalPrice:  booking.TotalPrice,
+		Lines:       lines,
+	}
+}
+
+// reservationEvent is the payload of an event of a reservation
+func reservationEvent(
+	eventType EventType,
+	reservation *Reservation,
+	occurredAt time.Time,
+) ReservationEvent {
+	return ReservationEvent{
+		Version:          ReservationEventVersion,
+		Type:             eventType,
+		OccurredAt:       occurredAt,
+		ReservationUUID:  reservation.UUID,
+		GuestUUID:        reservation.GuestUUID,
+		HotelUUID:        reservation.HotelUUID,
+		RoomTypeUUID:     reservation.RoomTypeUUID,
+		RatePlanUUID:     reservation.RatePlanUUID,
+		StartDate:        reservation.StartDate.Format(DateLayout),
+		EndDate:          reservation.EndDate.Format(DateLayout),
+		Status:           reservation.Status,
+		TotalPrice:       reservation.TotalPrice,
+		Discount:         reservation.Discount,
+		ExpiresAt:        reservation.ExpiresAt,
+		ConfirmationCode: reservation.ConfirmationCode,
+	}
+}
+
+// newOutboxEvent encodes the payload of an event of an aggregate
+func newOutboxEvent(
+	aggregateUUID string,
+	hotelUUID string,
+	eventType EventType,
+	payload interface{},
+	occurredAt time.Time,
+) (*OutboxEvent, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode %s event: %w", eventType, err)
+	}
+	return &OutboxEvent{
+		AggregateUUID: aggregateUUID,
+		HotelUUID:     hotelUUID,
+		Type:          eventType,
+		Payload:       encoded,
+		OccurredAt:    occurredAt,
+	}, nil
+}
+
+// ReservationEvent decodes the reservation the event describes
+func (e *OutboxEvent) ReservationEvent() (*ReservationEvent, error) {
+	var event ReservationEvent
+	if err := json.Unmarshal(e.Payload, &event); err != nil {
+		return nil, fmt.Errorf("can't decode %s event %s: %w", e.Type, e.UUID, err)
+	}
+	return &event, nil
+}
+
+// BookingEvent decodes the booking the event describes
+func (e *OutboxEvent) BookingEvent() (*BookingEvent, error) {
+	var event BookingEvent
+	if err := json.Unmarshal(e.Payload, &event); err != nil {
+		return nil, fmt.Errorf("can't decode %s event %s: %w", e.Type, e.UUID, err)
+	}
+	return &event, nil
+}
+
+// FreedStays are the stays whose inventory the event gave back, one per room. Cancelled, lapsed and
+// failed reservations give back their stay, a modified reservation the stay it was moved from, a
+// cancelled booking line its rooms and a released group block the rooms of its lines
+func (e *OutboxEvent) FreedStays() ([]FreedStay, error) {
+	switch e.Type {
+	case RESERVATION_CANCELLED, RESERVATION_HOLD_EXPIRED, RESERVATION_PAYMENT_FAILED, RESERVATION_MODIFIED:
+	case BOOKING_LINE_CANCELLED, BOOKING_BLOCK_RELEASED:
+		return e.bookingFreedStays()
+	default:
+		return nil, nil
+	}
+	reservation, err := e.ReservationEvent()
+	if err != nil {
+		return nil, err
+	}
+	if e.Type == RESERVATION_MODIFIED {
+		if reservation.Released == nil {
+			// modified before the released stay was recorded
+			return nil, nil
+		}
+		return []FreedStay{*reservation.Released}, nil
+	}
+	return []FreedStay{{
+		RoomTypeUUID: reservation.RoomTypeUUID,
+		StartDate:    reservation.StartDate,
+		EndDate:      reservation.EndDate,
+	}}, nil
+}
+
+// bookingFreedStays are the stays of the rooms a booking event gave back: those of the cancelled line, or
+// of every line a released block gave back
+func (e *OutboxEvent) bookingFreedStays() ([]FreedStay, error) {
+	booking, err := e.BookingEvent()
+	if err != nil {
+		return nil, err
+	}
+	freed := []FreedStay{}
+	for _, line := range booking.Lines {
+		if e.Type == BOOKING_LINE_CANCELLED && line.LineUUID != booking.LineUUID {
+			continue
+		}
+		if e.Type == BOOKING_BLOCK_RELEASED && line.Status != BOOKING_RELEASED {
+			continue
+		}
+		for i := int64(0); i < line.Quantity; i++ {
+			freed = append(freed, FreedStay{
+				RoomTypeUUID: line.RoomTypeUUID,
+				StartDate:    booking.StartDate,
+				EndDate:      booking.EndDate,
+			})
+		}
+	}
+	return freed, nil
+}
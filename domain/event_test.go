@@ -0,0 +1,187 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+func TestNewReservationEvent(t *testing.T) {
+	ratePlanUUID := "rate-plan"
+	reservation := &domain.Reservation{
+		AbstractBase: domain.AbstractBase{UUID: "reservation"},
+		GuestUUID:    "guest",
+		HotelUUID:    "hotel",
+		RoomTypeUUID: "room-type",
+		RatePlanUUID: &ratePlanUUID,
+		StartDate:    date("2023-06-01"),
+		EndDate:      date("2023-06-04"),
+		Status:       string(domain.CANCELLED),
+		TotalPrice:   300,
+	}
+	occurredAt := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	event, err := domain.NewReservationEvent(domain.RESERVATION_CANCELLED, reservation, occurredAt)
+	if err != nil {
+		t.Fatalf("NewReservationEvent() unexpected error = %v", err)
+	}
+	if event.AggregateUUID != "reservation" || event.HotelUUID != "hotel" || event.Type != domain.RESERVATION_CANCELLED {
+		t.Errorf("unexpected event envelope %+v", event)
+	}
+
+	decoded, err := event.ReservationEvent()
+	if err != nil {
+		t.Fatalf("OutboxEvent.ReservationEvent() unexpected error = %v", err)
+	}
+	want := domain.ReservationEvent{
+		Version:         domain.ReservationEventVersion,
+		Type:            domain.RESERVATION_CANCELLED,
+		OccurredAt:      occurredAt,
+		ReservationUUID: "reservation",
+		GuestUUID:       "guest",
+		HotelUUID:       "hotel",
+		RoomTypeUUID:    "room-type",
+		RatePlanUUID:    &ratePlanUUID,
+		StartDate:       "2023-06-01",
+		EndDate:         "2023-06-04",
+		Status:          string(domain.CANCELLED),
+		TotalPrice:      300,
+	}
+	if decoded.RatePlanUUID == nil || *decoded.RatePlanUUID != ratePlanUUID {
+		t.Fatalf("expected rate plan %v but got %v", ratePlanUUID, decoded.RatePlanUUID)
+	}
+	decoded.RatePlanUUID = want.RatePlanUUID
+	if *decoded != want {
+		t.Errorf("expected %+v but got %+v", want, *decoded)
+	}
+}
+
+func TestEventDelivery_RecordAttempt(t *testing.T) {
+	at := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	failure := errors.New("smtp server unavailable")
+
+	tests := []struct {
+		name        string
+		attempts    int
+		err         error
+		wantStatus  domain.DeliveryStatus
+		wantNextRun time.Time
+	}{
+		{
+			name:       "consumed",
+			wantStatus: domain.DELIVERED,
+		},
+		{
+			name:        "first failure is retried",
+			err:         failure,
+			wantStatus:  domain.DELIVERY_PENDING,
+			wantNextRun: at.Add(10 * time.Second),
+		},
+		{
+			name:       "last failure is dead lettered",
+			attempts:   domain.MaxEventDeliveryAttempts - 1,
+			err:        failure,
+			wantStatus: domain.DEAD_LETTERED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := &domain.EventDelivery{Status: domain.DELIVERY_PENDING, Attempts: tt.attempts}
+			delivery.RecordAttempt(at, tt.err)
+			if delivery.Status != tt.wantStatus {
+				t.Errorf("expected status %v but got %v", tt.wantStatus, delivery.Status)
+			}
+			if !tt.wantNextRun.IsZero() && !delivery.NextAttemptAt.Equal(tt.wantNextRun) {
+				t.Errorf("expected the next attempt at %v but got %v", tt.wantNextRun, delivery.NextAttemptAt)
+			}
+		})
+	}
+}
+
+func TestOutboxEvent_FreedStays(t *testing.T) {
+	occurredAt := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	reservation := &domain.Reservation{
+		AbstractBase: domain.AbstractBase{UUID: "reservation"},
+		RoomTypeUUID: "room-type",
+		StartDate:    date("2023-06-01"),
+		EndDate:      date("2023-06-04"),
+	}
+	moved := *reservation
+	moved.StartDate = date("2023-07-01")
+	moved.EndDate = date("2023-07-03")
+	booking := &domain.Booking{
+		AbstractBase: domain.AbstractBase{UUID: "booking"},
+		StartDate:    date("2023-06-01"),
+		EndDate:      date("2023-06-03"),
+		Lines: []domain.BookingLine{
+			{AbstractBase: domain.AbstractBase{UUID: "doubles"}, RoomTypeUUID: "double", Quantity: 2, Status: domain.BOOKING_RELEASED},
+			{AbstractBase: domain.AbstractBase{UUID: "suite"}, RoomTypeUUID: "suite", Quantity: 1, Status: domain.BOOKING_CANCELLED},
+		},
+	}
+	stay := func(roomType, start, end string) domain.FreedStay {
+		return domain.FreedStay{RoomTypeUUID: roomType, StartDate: start, EndDate: end}
+	}
+
+	cancelled, _ := domain.NewReservationEvent(domain.RESERVATION_CANCELLED, reservation, occurredAt)
+	checkedIn, _ := domain.NewReservationEvent(domain.RESERVATION_CHECKED_IN, reservation, occurredAt)
+	modified, _ := domain.NewReservationModifiedEvent(&moved, reservation, occurredAt)
+	lineCancelled, _ := domain.NewBookingLineCancelledEvent(booking, &booking.Lines[1], occurredAt)
+	released, _ := domain.NewBookingEvent(domain.BOOKING_BLOCK_RELEASED, booking, occurredAt)
+	created, _ := domain.NewBookingEvent(domain.BOOKING_CREATED, booking, occurredAt)
+
+	tests := []struct {
+		name  string
+		event *domain.OutboxEvent
+		want  []domain.FreedStay
+	}{
+		{
+			name:  "cancelled reservation",
+			event: cancelled,
+			want:  []domain.FreedStay{stay("room-type", "2023-06-01", "2023-06-04")},
+		},
+		{
+			name:  "modified reservation gives back its previous stay",
+			event: modified,
+			want:  []domain.FreedStay{stay("room-type", "2023-06-01", "2023-06-04")},
+		},
+		{
+			name:  "cancelled booking line",
+			event: lineCancelled,
+			want:  []domain.FreedStay{stay("suite", "2023-06-01", "2023-06-03")},
+		},
+		{
+			name:  "released block gives back a stay per released room",
+			event: released,
+			want: []domain.FreedStay{
+				stay("double", "2023-06-01", "2023-06-03"),
+				stay("double", "2023-06-01", "2023-06-03"),
+			},
+		},
+		{
+			name:  "checked in reservation",
+			event: checkedIn,
+		},
+		{
+			name:  "created booking",
+			event: created,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.event.FreedStays()
+			if err != nil {
+				t.Fatalf("OutboxEvent.FreedStays() unexpected error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("OutboxEvent.FreedStays() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("OutboxEvent.FreedStays() = %+v, want %+v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -10,10 +10,20 @@ import (
 type ReservationStatus string
 
 const (
-	RESERVED  ReservationStatus = "RESERVED"
-	CANCELLED ReservationStatus = "CANCELLED"
+	PENDING        ReservationStatus = "PENDING"
+	RESERVED       ReservationStatus = "RESERVED"
+	CANCELLED      ReservationStatus = "CANCELLED"
+	PAYMENT_FAILED ReservationStatus = "PAYMENT_FAILED"
+	HELD           ReservationStatus = "HELD"
+	EXPIRED        ReservationStatus = "EXPIRED"
+	CHECKED_IN     ReservationStatus = "CHECKED_IN"
+	CHECKED_OUT    ReservationStatus = "CHECKED_OUT"
 )
 
+// PendingPaymentTimeout is how long a PENDING reservation keeps its inventory while its payment is
+// authorized. A reservation still PENDING after it, left by a crash or a timeout, fails its payment
+const PendingPaymentTimeout = 10 * time.Minute
+
 // AbstractBase is an abstract struct that can be embedded in other structs
 type AbstractBase struct {
 	UUID      string `gorm:"primaryKey"`
@@ -39,20 +49,42 @@ type Guest struct {
 }
 
 // Hotel
+// Email, LogoURL and BrandColor brand the emails sent to the hotel's guests
 type Hotel struct {
 	AbstractBase `gorm:"embedded"`
 	Name         string `json:"name" gorm:"unique, index"`
 	Address      string `json:"address" gorm:"unique, index"`
 	Location     string `json:"location" gorm:"index"`
+	Email        string `json:"email"`
+	LogoURL      string `json:"logo_url"`
+	BrandColor   string `json:"brand_color"`
 }
 
 // RoomType
+// OverbookingType and OverbookingValue are the overbooking limit of nights that don't override it
 type RoomType struct {
-	AbstractBase `gorm:"embedded"`
-	HotelUUID    string `json:"hotel_uuid"`
-	Hotel        Hotel  `json:"hotel,omitempty" gorm:"foreignKey:HotelUUID"`
-	Inventory    int64  `json:"inventory"`
-	Reserved     int64  `json:"reserved"`
+	AbstractBase     `gorm:"embedded"`
+	HotelUUID        string          `json:"hotel_uuid"`
+	Hotel            Hotel           `json:"hotel,omitempty" gorm:"foreignKey:HotelUUID"`
+	Inventory        int64           `json:"inventory"`
+	Reserved         int64           `json:"reserved"`
+	OverbookingType  OverbookingType `json:"overbooking_type"`
+	OverbookingValue int             `json:"overbooking_value"`
+}
+
+// RoomTypeInventory tracks how many rooms of a room type are available and reserved on a given night
+// Rows are created lazily, seeded from the room type's inventory, the first time a night is booked
+type RoomTypeInventory struct {
+	AbstractBase   `gorm:"embedded"`
+	HotelUUID      string    `json:"hotel_uuid" gorm:"index"`
+	Hotel          Hotel     `json:"hotel,omitempty" gorm:"foreignKey:HotelUUID"`
+	RoomTypeUUID   string    `json:"roomtype_uuid" gorm:"uniqueIndex:idx_room_type_inventories_room_type_date"`
+	RoomType       RoomType  `json:"room_type,omitempty" gorm:"foreignKey:RoomTypeUUID"`
+	Date           time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_room_type_inventories_room_type_date"`
+	TotalInventory int64     `json:"total_inventory"`
+	TotalReserved  int64     `json:"total_reserved"`
+	// OverbookingLimit is how many rooms can be sold beyond TotalInventory on the night
+	OverbookingLimit int64 `json:"overbooking_limit"`
 }
 
 // Room
@@ -66,32 +98,57 @@ type Room struct {
 }
 
 // Rate represents the amount of money we we will charge for a particular room during a given data
+// There is at most one rate per hotel, room type and night
 type Rate struct {
 	AbstractBase `gorm:"embedded"`
-	HotelUUID    string    `json:"hotel_uuid"`
+	HotelUUID    string    `json:"hotel_uuid" gorm:"uniqueIndex:idx_rates_hotel_room_type_date"`
 	Hotel        Hotel     `json:"hotel,omitempty" gorm:"foreignKey:HotelUUID"`
-	RoomTypeUUID string    `json:"roomtype_uuid"`
+	RoomTypeUUID string    `json:"roomtype_uuid" gorm:"uniqueIndex:idx_rates_hotel_room_type_date"`
 	RoomType     RoomType  `json:"room_type,omitempty" gorm:"foreignKey:RoomTypeUUID"`
 	Rate         int       `json:"rate"`
-	Date         time.Time `json:"date"`
+	Date         time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_rates_hotel_room_type_date"`
 }
 
 // Reservation
 type Reservation struct {
-	AbstractBase `gorm:"embedded"`
-	GuestUUID    string    `json:"guest_uuid"`
-	Guest        Guest     `json:"guest,omitempty" gorm:"foreignKey:GuestUUID"`
-	HotelUUID    string    `json:"hotel_uuid"`
-	Hotel        Hotel     `json:"hotel,omitempty" gorm:"foreignKey:HotelUUID"`
-	RoomTypeUUID string    `json:"roomtype_uuid"`
-	RoomType     RoomType  `json:"room_type,omitempty" gorm:"foreignKey:RoomTypeUUID"`
-	StartDate    time.Time `json:"start_date" gorm:"not null"`
-	EndDate      time.Time `json:"end_date" gorm:"not null"`
-	Status       string    `json:"status"`
+	AbstractBase  `gorm:"embedded"`
+	GuestUUID     string     `json:"guest_uuid"`
+	Guest         Guest      `json:"guest,omitempty" gorm:"foreignKey:GuestUUID"`
+	HotelUUID     string     `json:"hotel_uuid"`
+	Hotel         Hotel      `json:"hotel,omitempty" gorm:"foreignKey:HotelUUID"`
+	RoomTypeUUID  string     `json:"roomtype_uuid"`
+	RoomType      RoomType   `json:"room_type,omitempty" gorm:"foreignKey:RoomTypeUUID"`
+	RatePlanUUID  *string    `json:"rateplan_uuid,omitempty"`
+	RatePlan      *RatePlan  `json:"rate_plan,omitempty" gorm:"foreignKey:RatePlanUUID"`
+	StartDate     time.Time  `json:"start_date" gorm:"not null"`
+	EndDate       time.Time  `json:"end_date" gorm:"not null"`
+	PromoCode     string     `json:"promo_code,omitempty"`
+	PromotionUUID *string    `json:"promotion_uuid,omitempty"`
+	Promotion     *Promotion `json:"promotion,omitempty" gorm:"foreignKey:PromotionUUID"`
+	Discount      int        `json:"discount"`
+	TotalPrice    int        `json:"total_price"`
+	Status        string     `json:"status"`
+	// ConfirmationCode is the short code guests quote to find their reservation, see NewConfirmationCode
+	ConfirmationCode string `json:"confirmation_code" gorm:"index:idx_reservations_confirmation_code,unique,where:confirmation_code <> ''"`
+	// ExpiresAt is when a HELD or PENDING reservation lapses and its inventory is released
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	// ArrivalReminderAt is when the guest was reminded of their upcoming stay
+	ArrivalReminderAt *time.Time `json:"arrival_reminder_at,omitempty"`
+	// RedeemedPoints are the loyalty points spent on the reservation, their value is taken off TotalPrice
+	RedeemedPoints int `json:"redeemed_points,omitempty"`
+	// PaymentMethod is the payment provider's token used to pay for the reservation, it isn't stored
+	PaymentMethod string `json:"payment_method,omitempty" gorm:"-"`
 }
 
 /*
 Reservation status:
-1. RESERVED
-2. CANCELLED
+1. PENDING -- inventory is claimed until ExpiresAt while the payment is being authorized
+2. RESERVED
+3. CANCELLED
+4. PAYMENT_FAILED -- the payment wasn't authorized in time and the inventory was released
+5. HELD -- inventory is claimed until ExpiresAt while the guest pays, confirming it once the payment is
+authorized moves it to PENDING, a declined payment keeps it HELD
+6. EXPIRED -- the hold lapsed before it was confirmed and the inventory was released
+7. CHECKED_IN -- the guest has arrived at a RESERVED stay
+8. CHECKED_OUT -- the guest has left
 */
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// LoyaltyEntryType is why a guest's loyalty balance changed
+type LoyaltyEntryType string
+
+const (
+	// ACCRUAL entries award points for a stay the guest checked out of
+	ACCRUAL LoyaltyEntryType = "ACCRUAL"
+	// REDEMPTION entries spend points as a discount on a reservation
+	REDEMPTION LoyaltyEntryType = "REDEMPTION"
+	// REVERSAL entries undo every other entry of a reservation that was cancelled or never paid for
+	REVERSAL LoyaltyEntryType = "REVERSAL"
+	// ADJUSTMENT entries give back redeemed points a modified reservation's lower price no longer needs
+	ADJUSTMENT LoyaltyEntryType = "ADJUSTMENT"
+)
+
+const (
+	// LoyaltyEarnRate is how much a guest spends to earn one point
+	LoyaltyEarnRate = 100
+	// LoyaltyPointValue is how much one point takes off the price of a reservation
+	LoyaltyPointValue = 1
+)
+
+// errLoyaltyEntryImmutable is returned when changing a ledger entry, corrections are new entries
+var errLoyaltyEntryImmutable = errors.New("loyalty ledger entries can't be changed")
+
+// LoyaltyEntry is an immutable line of a guest's loyalty ledger, the guest's balance is the sum of the
+// points of their entries. A reservation has at most one entry of each type but ADJUSTMENT, one is added
+// each time a modification gives points back
+type LoyaltyEntry struct {
+	AbstractBase    `gorm:"embedded"`
+	GuestUUID       string           `json:"guest_uuid" gorm:"index;not null"`
+	ReservationUUID string           `json:"reservation_uuid" gorm:"uniqueIndex:idx_loyalty_entries_reservation_type;not null"`
+	Type            LoyaltyEntryType `json:"type" gorm:"uniqueIndex:idx_loyalty_entries_reservation_type;not null"`
+	// Points is positive when points are earned and negative when they are spent
+	Points int `json:"points"`
+}
+
+// BeforeUpdate keeps ledger entries from being changed
+func (e *LoyaltyEntry) BeforeUpdate(tx *gorm.DB) error {
+	return errLoyaltyEntryImmutable
+}
+
+// BeforeDelete keeps ledger entries from being deleted
+func (e *LoyaltyEntry) BeforeDelete(tx *gorm.DB) error {
+	return errLoyaltyEntryImmutable
+}
+
+// AccruedPoints is how many points a stay earns for its price
+func AccruedPoints(price int) int {
+	if price <= 0 {
+		return 0
+	}
+	return price / LoyaltyEarnRate
+}
+
+// PointsValue is how much a number of points takes off a price
+func PointsValue(points int) int {
+	return points * LoyaltyPointValue
+}
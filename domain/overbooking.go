@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// OverbookingType is how a room type's overbooking limit is expressed
+type OverbookingType string
+
+const (
+	// ABSOLUTE limits allow a fixed number of rooms beyond the physical inventory
+	ABSOLUTE OverbookingType = "ABSOLUTE"
+	// OVERBOOKING_PERCENTAGE limits allow a share of the physical inventory on top of it, rounded down
+	OVERBOOKING_PERCENTAGE OverbookingType = "PERCENTAGE"
+)
+
+// maxOverbookingPercentage keeps a mistyped percentage from doubling a hotel's sellable rooms
+const maxOverbookingPercentage = 50
+
+// ValidateOverbooking checks an overbooking limit can be applied
+func ValidateOverbooking(kind OverbookingType, value int) error {
+	switch kind {
+	case ABSOLUTE, "":
+	case OVERBOOKING_PERCENTAGE:
+		if value > maxOverbookingPercentage {
+			return fmt.Errorf("can't overbook by more than %d%%", maxOverbookingPercentage)
+		}
+	default:
+		return fmt.Errorf("unknown overbooking type %q", kind)
+	}
+	if value < 0 {
+		return fmt.Errorf("an overbooking limit can't be negative")
+	}
+	return nil
+}
+
+// OverbookingAllowance is how many rooms can be sold beyond the physical inventory under a limit.
+// An empty type is treated as ABSOLUTE
+func OverbookingAllowance(physical int64, kind OverbookingType, value int) int64 {
+	if kind == OVERBOOKING_PERCENTAGE {
+		return physical * int64(value) / 100
+	}
+	return int64(value)
+}
+
+// SellableRooms is how many rooms of the room type can be sold on a night that has never been booked
+func (rt *RoomType) SellableRooms() int64 {
+	return rt.Inventory + OverbookingAllowance(rt.Inventory, rt.OverbookingType, rt.OverbookingValue)
+}
+
+// Available is how many more rooms can be sold on the night, including the overbooking allowance
+func (i *RoomTypeInventory) Available() int64 {
+	return i.TotalInventory + i.OverbookingLimit - i.TotalReserved
+}
+
+// OversoldNight is a night on which a room type has more confirmed rooms, booked by reservations or by
+// multi-room bookings, than physical rooms. WalkOuts guests will have to be accommodated elsewhere if
+// everyone shows up
+type OversoldNight struct {
+	RoomTypeUUID  string    `json:"roomtype_uuid"`
+	Date          time.Time `json:"date"`
+	PhysicalRooms int64     `json:"physical_rooms"`
+	Confirmed     int64     `json:"confirmed"`
+	WalkOuts      int64     `json:"walk_outs"`
+}
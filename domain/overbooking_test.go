@@ -0,0 +1,51 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+func TestOverbookingAllowance(t *testing.T) {
+	tests := []struct {
+		name     string
+		physical int64
+		kind     domain.OverbookingType
+		value    int
+		want     int64
+	}{
+		{name: "absolute", physical: 20, kind: domain.ABSOLUTE, value: 3, want: 3},
+		{name: "no type is absolute", physical: 20, kind: "", value: 2, want: 2},
+		{name: "percentage", physical: 20, kind: domain.OVERBOOKING_PERCENTAGE, value: 10, want: 2},
+		{name: "percentage is rounded down", physical: 15, kind: domain.OVERBOOKING_PERCENTAGE, value: 10, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := domain.OverbookingAllowance(tt.physical, tt.kind, tt.value); got != tt.want {
+				t.Errorf("OverbookingAllowance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOverbooking(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    domain.OverbookingType
+		value   int
+		wantErr bool
+	}{
+		{name: "absolute", kind: domain.ABSOLUTE, value: 5},
+		{name: "percentage", kind: domain.OVERBOOKING_PERCENTAGE, value: 10},
+		{name: "negative", kind: domain.ABSOLUTE, value: -1, wantErr: true},
+		{name: "percentage too large", kind: domain.OVERBOOKING_PERCENTAGE, value: 80, wantErr: true},
+		{name: "unknown type", kind: "ROOMS", value: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := domain.ValidateOverbooking(tt.kind, tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOverbooking() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
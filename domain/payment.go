@@ -0,0 +1,211 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PaymentStatus is where a payment is in its authorize, capture and refund lifecycle
+type PaymentStatus string
+
+const (
+	AUTHORIZED         PaymentStatus = "AUTHORIZED"
+	CAPTURED           PaymentStatus = "CAPTURED"
+	PARTIALLY_REFUNDED PaymentStatus = "PARTIALLY_REFUNDED"
+	REFUNDED           PaymentStatus = "REFUNDED"
+	VOIDED             PaymentStatus = "VOIDED"
+	FAILED             PaymentStatus = "FAILED"
+)
+
+const (
+	// DefaultCurrency is the currency reservations are charged in
+	DefaultCurrency = "KES"
+	// PaymentSettlementLease is how long a settler has to settle a payment it claimed before another
+	// settler may retry it
+	PaymentSettlementLease = 2 * time.Minute
+	// settlementBaseBackoff is the wait after the first failed settlement, it doubles after every failure
+	settlementBaseBackoff = time.Minute
+	// settlementMaxBackoff caps the wait between two settlements
+	settlementMaxBackoff = 6 * time.Hour
+)
+
+// AuthorizeRequest describes the funds to hold on a guest's payment method
+type AuthorizeRequest struct {
+	Amount   int
+	Currency string
+	// PaymentMethod is the provider's token for the guest's card or wallet
+	PaymentMethod string
+	// IdempotencyKey makes retried authorizations safe, the reservation UUID is used
+	IdempotencyKey string
+}
+
+// PaymentGateway is the contract a payment provider integration must satisfy
+// Amounts are in the smallest unit of the currency
+type PaymentGateway interface {
+	// Authorize holds funds and returns the provider's reference for the authorization, a refused
+	// payment method is an ErrPaymentDeclined
+	Authorize(ctx context.Context, request AuthorizeRequest) (string, error)
+	// Capture collects up to the authorized amount
+	Capture(ctx context.Context, reference string, amount int) error
+	// Refund gives back up to the captured amount
+	Refund(ctx context.Context, reference string, amount int) error
+	// Void releases an authorization that hasn't been captured
+	Void(ctx context.Context, reference string) error
+}
+
+// Payment is the charge made for a reservation or a multi-room booking through the payment gateway,
+// exactly one of ReservationUUID and BookingUUID is set
+type Payment struct {
+	AbstractBase     `gorm:"embedded"`
+	ReservationUUID  *string       `json:"reservation_uuid,omitempty" gorm:"uniqueIndex"`
+	Reservation      Reservation   `json:"reservation,omitempty" gorm:"foreignKey:ReservationUUID"`
+	BookingUUID      *string       `json:"booking_uuid,omitempty" gorm:"uniqueIndex"`
+	Amount           int           `json:"amount"`
+	Currency         string        `json:"currency"`
+	Status           PaymentStatus `json:"status"`
+	GatewayReference string        `json:"gateway_reference"`
+	CapturedAmount   int           `json:"captured_amount"`
+	RefundedAmount   int           `json:"refunded_amount"`
+	FailureReason    string        `json:"failure_reason,omitempty"`
+	// RefundDue is what is still owed to the guest of a cancelled reservation or booking line. An authorization owing
+	// its whole amount is voided, otherwise it is captured before the refund is made
+	RefundDue int `json:"refund_due"`
+	// SettleAt is when the payment is next settled with the gateway, it is nil once nothing is owed
+	SettleAt           *time.Time `json:"settle_at,omitempty" gorm:"index"`
+	SettlementAttempts int        `json:"settlement_attempts"`
+	SettlementError    string     `json:"settlement_error,omitempty"`
+}
+
+// RefundAmount is how much of a reservation's payment is given back when it is cancelled at the given
+// time. Stays booked on a non refundable rate plan, and stays cancelled on or after the arrival
+// date, aren't refunded, everything else is refunded in full
+func RefundAmount(
+	reservation *Reservation,
+	ratePlan *RatePlan,
+	payment *Payment,
+	cancelledAt time.Time,
+) int {
+	if !isRefundable(ratePlan, reservation.StartDate, cancelledAt) {
+		return 0
+	}
+	return payment.Amount
+}
+
+// BookingLineRefund is how much of a booking's payment is given back when one of its lines is cancelled
+// at the given time. The same rules as RefundAmount apply, a refunded line gives back its whole price
+func BookingLineRefund(
+	booking *Booking,
+	line *BookingLine,
+	ratePlan *RatePlan,
+	cancelledAt time.Time,
+) int {
+	if !isRefundable(ratePlan, booking.StartDate, cancelledAt) {
+		return 0
+	}
+	return line.TotalPrice
+}
+
+// isRefundable reports whether a stay starting on startDate booked on ratePlan is refunded when it is
+// cancelled at the given time
+func isRefundable(ratePlan *RatePlan, startDate time.Time, cancelledAt time.Time) bool {
+	if ratePlan != nil && !ratePlan.Refundable {
+		return false
+	}
+	return TruncateToDate(cancelledAt).Before(TruncateToDate(startDate))
+}
+
+// OweRefund records that refund is owed, on top of any refund not settled yet, on the payment of a
+// reservation or booking line cancelled at the given time, capped at what can still be given back. The
+// payment is due to be settled at once, claimed for the PaymentSettlementLease by the cancellation. It
+// reports whether anything has to be settled: a captured payment owing nothing is left alone
+func (p *Payment) OweRefund(refund int, at time.Time) bool {
+	refund += p.RefundDue
+	switch p.Status {
+	case AUTHORIZED:
+		if refund > p.Amount {
+			refund = p.Amount
+		}
+	case CAPTURED, PARTIALLY_REFUNDED:
+		if remaining := p.CapturedAmount - p.RefundedAmount; refund > remaining {
+			refund = remaining
+		}
+		if refund <= 0 {
+			return false
+		}
+	default:
+		return false
+	}
+	settleAt := at.Add(PaymentSettlementLease)
+	p.RefundDue = refund
+	p.SettleAt = &settleAt
+	p.SettlementAttempts = 0
+	p.SettlementError = ""
+	return true
+}
+
+// Reprice adjusts the payment of a reservation whose price was changed to amount at the given time. A
+// lower price is left uncaptured on an authorization, and refunded from a captured payment by the
+// payment settler. A higher price is authorized anew by the authorization given by reference, which
+// replaces the payment's: the replaced authorization's reference is returned so that it can be voided.
+// The price of a payment that has been captured can't be raised
+func (p *Payment) Reprice(amount int, reference string, at time.Time) (string, error) {
+	if amount == p.Amount {
+		return "", nil
+	}
+	if amount < p.Amount {
+		refund := p.Amount - amount
+		switch p.Status {
+		case AUTHORIZED:
+			p.Amount = amount
+			if amount == 0 {
+				// an authorization left with nothing to capture is voided
+				p.OweRefund(0, at)
+			}
+		case CAPTURED, PARTIALLY_REFUNDED:
+			p.Amount = amount
+			p.OweRefund(refund, at)
+		default:
+			return "", fmt.Errorf("a %s payment can't be repriced", p.Status)
+		}
+		return "", nil
+	}
+
+	replaced := ""
+	switch {
+	case p.Status == AUTHORIZED:
+		replaced = p.GatewayReference
+	case p.Status == CAPTURED && p.CapturedAmount == 0:
+		// a stay that cost nothing had nothing to authorize
+	default:
+		return "", fmt.Errorf("the price of a %s payment can't be raised", p.Status)
+	}
+	if reference == "" {
+		return "", errors.New("the new price has to be authorized before it is raised")
+	}
+	p.Amount = amount
+	p.Status = AUTHORIZED
+	p.GatewayReference = reference
+	p.CapturedAmount = 0
+	return replaced, nil
+}
+
+// SettlementBackoff is how long to wait before settling a payment that has failed attempts times
+func SettlementBackoff(attempts int) time.Duration {
+	return exponentialBackoff(attempts, settlementBaseBackoff, settlementMaxBackoff)
+}
+
+// RecordSettlement updates the payment with the outcome of a settlement made at the given time.
+// A failed settlement is retried with an exponential backoff until it succeeds, the guest is owed it
+func (p *Payment) RecordSettlement(at time.Time, err error) {
+	p.SettlementAttempts++
+	if err == nil {
+		p.SettleAt = nil
+		p.SettlementError = ""
+		return
+	}
+	settleAt := at.Add(SettlementBackoff(p.SettlementAttempts))
+	p.SettleAt = &settleAt
+	p.SettlementError = err.Error()
+}
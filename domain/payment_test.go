@@ -0,0 +1,182 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+func TestPayment_OweRefund(t *testing.T) {
+	at := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		payment       domain.Payment
+		refund        int
+		want          bool
+		wantRefundDue int
+	}{
+		{
+			name:          "authorization refunded in full",
+			payment:       domain.Payment{Amount: 300, Status: domain.AUTHORIZED},
+			refund:        300,
+			want:          true,
+			wantRefundDue: 300,
+		},
+		{
+			name:    "authorization owing nothing is still captured",
+			payment: domain.Payment{Amount: 300, Status: domain.AUTHORIZED},
+			refund:  0,
+			want:    true,
+		},
+		{
+			name:          "refund capped at what is left of the capture",
+			payment:       domain.Payment{Amount: 300, Status: domain.PARTIALLY_REFUNDED, CapturedAmount: 300, RefundedAmount: 200},
+			refund:        300,
+			want:          true,
+			wantRefundDue: 100,
+		},
+		{
+			name:          "refunds owed add up",
+			payment:       domain.Payment{Amount: 300, Status: domain.CAPTURED, CapturedAmount: 300, RefundDue: 100},
+			refund:        100,
+			want:          true,
+			wantRefundDue: 200,
+		},
+		{
+			name:    "capture owing nothing is left alone",
+			payment: domain.Payment{Amount: 300, Status: domain.CAPTURED, CapturedAmount: 300},
+			refund:  0,
+			want:    false,
+		},
+		{
+			name:    "voided payment is left alone",
+			payment: domain.Payment{Amount: 300, Status: domain.VOIDED},
+			refund:  300,
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := tt.payment
+			if got := payment.OweRefund(tt.refund, at); got != tt.want {
+				t.Fatalf("Payment.OweRefund() = %v, want %v", got, tt.want)
+			}
+			if !tt.want {
+				if payment.SettleAt != nil {
+					t.Errorf("expected no settlement but it is due at %v", payment.SettleAt)
+				}
+				return
+			}
+			if payment.RefundDue != tt.wantRefundDue {
+				t.Errorf("expected %v due but got %v", tt.wantRefundDue, payment.RefundDue)
+			}
+			if payment.SettleAt == nil || !payment.SettleAt.Equal(at.Add(domain.PaymentSettlementLease)) {
+				t.Errorf("expected the settlement to be claimed until %v but got %v", at.Add(domain.PaymentSettlementLease), payment.SettleAt)
+			}
+		})
+	}
+}
+
+func TestPayment_Reprice(t *testing.T) {
+	at := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		payment       domain.Payment
+		amount        int
+		reference     string
+		wantPayment   domain.Payment
+		wantReplaced  string
+		wantRefundDue int
+		wantSettle    bool
+		wantErr       bool
+	}{
+		{
+			name:        "lower price is left uncaptured",
+			payment:     domain.Payment{Amount: 300, Status: domain.AUTHORIZED, GatewayReference: "old"},
+			amount:      200,
+			wantPayment: domain.Payment{Amount: 200, Status: domain.AUTHORIZED, GatewayReference: "old"},
+		},
+		{
+			name:        "authorization left with nothing to capture is voided",
+			payment:     domain.Payment{Amount: 300, Status: domain.AUTHORIZED, GatewayReference: "old"},
+			amount:      0,
+			wantPayment: domain.Payment{Amount: 0, Status: domain.AUTHORIZED, GatewayReference: "old"},
+			wantSettle:  true,
+		},
+		{
+			name:          "lower price of a captured payment is refunded",
+			payment:       domain.Payment{Amount: 300, Status: domain.CAPTURED, CapturedAmount: 300, GatewayReference: "old"},
+			amount:        200,
+			wantPayment:   domain.Payment{Amount: 200, Status: domain.CAPTURED, CapturedAmount: 300, GatewayReference: "old"},
+			wantRefundDue: 100,
+			wantSettle:    true,
+		},
+		{
+			name:         "higher price replaces the authorization",
+			payment:      domain.Payment{Amount: 300, Status: domain.AUTHORIZED, GatewayReference: "old"},
+			amount:       400,
+			reference:    "new",
+			wantPayment:  domain.Payment{Amount: 400, Status: domain.AUTHORIZED, GatewayReference: "new"},
+			wantReplaced: "old",
+		},
+		{
+			name:        "stay that cost nothing is authorized",
+			payment:     domain.Payment{Amount: 0, Status: domain.CAPTURED},
+			amount:      400,
+			reference:   "new",
+			wantPayment: domain.Payment{Amount: 400, Status: domain.AUTHORIZED, GatewayReference: "new"},
+		},
+		{
+			name:    "higher price needs an authorization",
+			payment: domain.Payment{Amount: 300, Status: domain.AUTHORIZED, GatewayReference: "old"},
+			amount:  400,
+			wantErr: true,
+		},
+		{
+			name:      "price of a captured payment can't be raised",
+			payment:   domain.Payment{Amount: 300, Status: domain.CAPTURED, CapturedAmount: 300, GatewayReference: "old"},
+			amount:    400,
+			reference: "new",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := tt.payment
+			replaced, err := payment.Reprice(tt.amount, tt.reference, at)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Payment.Reprice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if replaced != tt.wantReplaced {
+				t.Errorf("expected %q to be replaced but got %q", tt.wantReplaced, replaced)
+			}
+			if payment.Amount != tt.wantPayment.Amount || payment.Status != tt.wantPayment.Status ||
+				payment.GatewayReference != tt.wantPayment.GatewayReference || payment.CapturedAmount != tt.wantPayment.CapturedAmount {
+				t.Errorf("expected %+v but got %+v", tt.wantPayment, payment)
+			}
+			if payment.RefundDue != tt.wantRefundDue || (payment.SettleAt != nil) != tt.wantSettle {
+				t.Errorf("expected %v due and a settlement %v but got %v due at %v", tt.wantRefundDue, tt.wantSettle, payment.RefundDue, payment.SettleAt)
+			}
+		})
+	}
+}
+
+func TestPayment_RecordSettlement(t *testing.T) {
+	at := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	payment := &domain.Payment{Status: domain.AUTHORIZED, RefundDue: 300}
+	payment.RecordSettlement(at, errors.New("payment gateway unavailable"))
+	payment.RecordSettlement(at, errors.New("payment gateway unavailable"))
+	if payment.SettleAt == nil || !payment.SettleAt.Equal(at.Add(2*time.Minute)) {
+		t.Fatalf("expected the settlement to be retried at %v but got %v", at.Add(2*time.Minute), payment.SettleAt)
+	}
+	payment.RecordSettlement(at, nil)
+	if payment.SettleAt != nil || payment.SettlementError != "" || payment.SettlementAttempts != 3 {
+		t.Errorf("expected the payment to be settled after 3 attempts but got %+v", payment)
+	}
+}
@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// PricingRuleType is the kind of demand signal a pricing rule reacts to
+type PricingRuleType string
+
+const (
+	// OCCUPANCY rules apply when the share of a night's rooms already reserved falls within a tier
+	OCCUPANCY PricingRuleType = "OCCUPANCY"
+	// DAY_OF_WEEK rules apply to nights falling on a given weekday
+	DAY_OF_WEEK PricingRuleType = "DAY_OF_WEEK"
+	// LEAD_TIME rules apply when a night is booked within a window of days ahead of arrival
+	LEAD_TIME PricingRuleType = "LEAD_TIME"
+)
+
+// PricingRule adjusts a night's base rate by a multiplier when its condition holds
+// An empty RoomTypeUUID applies the rule to every room type of the hotel
+type PricingRule struct {
+	AbstractBase `gorm:"embedded"`
+	HotelUUID    string          `json:"hotel_uuid" gorm:"index"`
+	Hotel        Hotel           `json:"hotel,omitempty" gorm:"foreignKey:HotelUUID"`
+	RoomTypeUUID string          `json:"roomtype_uuid" gorm:"index"`
+	Name         string          `json:"name"`
+	Type         PricingRuleType `json:"type" gorm:"not null"`
+	Multiplier   float64         `json:"multiplier" gorm:"not null"`
+	// Priority breaks ties between rules of the same type that match the same night, the highest wins
+	Priority int `json:"priority"`
+	// MinOccupancy and MaxOccupancy bound an OCCUPANCY tier in percent, the upper bound is exclusive
+	// except for 100
+	MinOccupancy float64 `json:"min_occupancy"`
+	MaxOccupancy float64 `json:"max_occupancy"`
+	// Weekday is the day a DAY_OF_WEEK rule applies to, 0 is Sunday
+	Weekday int `json:"weekday"`
+	// MinLeadDays and MaxLeadDays bound a LEAD_TIME window, a zero MaxLeadDays has no upper bound
+	MinLeadDays int `json:"min_lead_days"`
+	MaxLeadDays int `json:"max_lead_days"`
+}
+
+// NightDemand holds the demand signals of a single night
+type NightDemand struct {
+	Date time.Time
+	// Occupancy is the percentage of the night's rooms that are reserved
+	Occupancy float64
+	BookedOn  time.Time
+}
+
+// Validate asserts the rule is well formed
+func (r *PricingRule) Validate() error {
+	if r.Multiplier <= 0 {
+		return fmt.Errorf("the multiplier must be greater than zero")
+	}
+	switch r.Type {
+	case OCCUPANCY:
+		if r.MinOccupancy < 0 || r.MaxOccupancy > 100 || r.MinOccupancy >= r.MaxOccupancy {
+			return fmt.Errorf("an occupancy tier must satisfy 0 <= min_occupancy < max_occupancy <= 100")
+		}
+	case DAY_OF_WEEK:
+		if r.Weekday < int(time.Sunday) || r.Weekday > int(time.Saturday) {
+			return fmt.Errorf("the weekday must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	case LEAD_TIME:
+		if r.MinLeadDays < 0 || r.MaxLeadDays < 0 {
+			return fmt.Errorf("lead days can't be negative")
+		}
+		if r.MaxLeadDays > 0 && r.MaxLeadDays < r.MinLeadDays {
+			return fmt.Errorf("max_lead_days can't be less than min_lead_days")
+		}
+	default:
+		return fmt.Errorf("unknown pricing rule type %q", r.Type)
+	}
+	return nil
+}
+
+// Matches reports whether the rule's condition holds for a night
+func (r *PricingRule) Matches(night NightDemand) bool {
+	switch r.Type {
+	case OCCUPANCY:
+		if night.Occupancy < r.MinOccupancy {
+			return false
+		}
+		return night.Occupancy < r.MaxOccupancy || (r.MaxOccupancy >= 100 && night.Occupancy >= 100)
+	case DAY_OF_WEEK:
+		return int(night.Date.Weekday()) == r.Weekday
+	case LEAD_TIME:
+		leadDays := int(TruncateToDate(night.Date).Sub(TruncateToDate(night.BookedOn)).Hours() / 24)
+		if leadDays < r.MinLeadDays {
+			return false
+		}
+		return r.MaxLeadDays == 0 || leadDays <= r.MaxLeadDays
+	default:
+		return false
+	}
+}
+
+// PriceNight applies pricing rules to a night's base rate. For every rule type the matching rule with the
+// highest priority applies, ties going to the oldest rule, and the multipliers of the different types
+// compound. The price is rounded to the nearest whole amount and the applied rules are returned
+// in a stable order
+func PriceNight(baseRate int, night NightDemand, rules []PricingRule) (int, []PricingRule) {
+	winners := map[PricingRuleType]PricingRule{}
+	for _, rule := range rules {
+		if !rule.Matches(night) {
+			continue
+		}
+		current, ok := winners[rule.Type]
+		if !ok || outranks(rule, current) {
+			winners[rule.Type] = rule
+		}
+	}
+
+	applied := make([]PricingRule, 0, len(winners))
+	for _, rule := range winners {
+		applied = append(applied, rule)
+	}
+	sort.Slice(applied, func(i, j int) bool { return applied[i].Type < applied[j].Type })
+
+	price := float64(baseRate)
+	for _, rule := range applied {
+		price *= rule.Multiplier
+	}
+	return int(math.Round(price)), applied
+}
+
+// outranks reports whether rule a takes precedence over rule b
+func outranks(a, b PricingRule) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if a.CreatedAt != nil && b.CreatedAt != nil && !a.CreatedAt.Equal(*b.CreatedAt) {
+		return a.CreatedAt.Before(*b.CreatedAt)
+	}
+	return a.UUID < b.UUID
+}
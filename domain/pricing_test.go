@@ -0,0 +1,159 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+func TestPriceNight(t *testing.T) {
+	rules := []domain.PricingRule{
+		{Type: domain.OCCUPANCY, MinOccupancy: 0, MaxOccupancy: 50, Multiplier: 0.9},
+		{Type: domain.OCCUPANCY, MinOccupancy: 50, MaxOccupancy: 80, Multiplier: 1},
+		{Type: domain.OCCUPANCY, MinOccupancy: 80, MaxOccupancy: 100, Multiplier: 1.25},
+		{Type: domain.DAY_OF_WEEK, Weekday: 6, Multiplier: 1.2},
+		{Type: domain.LEAD_TIME, MinLeadDays: 0, MaxLeadDays: 2, Multiplier: 1.1},
+		{Type: domain.LEAD_TIME, MinLeadDays: 0, MaxLeadDays: 7, Multiplier: 1.05, Priority: 1},
+		{Type: domain.LEAD_TIME, MinLeadDays: 90, Multiplier: 0.85},
+	}
+
+	type args struct {
+		baseRate int
+		night    domain.NightDemand
+		rules    []domain.PricingRule
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantPrice   int
+		wantApplied int
+	}{
+		{
+			name: "no rules leave the base rate untouched",
+			args: args{
+				baseRate: 100,
+				night:    domain.NightDemand{Date: date("2023-06-01"), BookedOn: date("2023-05-01"), Occupancy: 95},
+			},
+			wantPrice:   100,
+			wantApplied: 0,
+		},
+		{
+			name: "low occupancy weekday booked a month ahead",
+			args: args{
+				baseRate: 100,
+				// 2023-06-01 is a Thursday
+				night: domain.NightDemand{Date: date("2023-06-01"), BookedOn: date("2023-05-01"), Occupancy: 10},
+				rules: rules,
+			},
+			wantPrice:   90,
+			wantApplied: 1,
+		},
+		{
+			name: "high occupancy saturday",
+			args: args{
+				baseRate: 100,
+				night:    domain.NightDemand{Date: date("2023-06-03"), BookedOn: date("2023-05-01"), Occupancy: 85},
+				rules:    rules,
+			},
+			wantPrice:   150,
+			wantApplied: 2,
+		},
+		{
+			name: "fully booked night matches the top tier",
+			args: args{
+				baseRate: 100,
+				night:    domain.NightDemand{Date: date("2023-06-01"), BookedOn: date("2023-05-01"), Occupancy: 100},
+				rules:    rules,
+			},
+			wantPrice:   125,
+			wantApplied: 1,
+		},
+		{
+			name: "the highest priority lead time rule wins",
+			args: args{
+				baseRate: 100,
+				night:    domain.NightDemand{Date: date("2023-06-01"), BookedOn: date("2023-05-31"), Occupancy: 60},
+				rules:    rules,
+			},
+			wantPrice:   105,
+			wantApplied: 2,
+		},
+		{
+			name: "early bird discount compounds with the occupancy tier",
+			args: args{
+				baseRate: 200,
+				night:    domain.NightDemand{Date: date("2023-09-01"), BookedOn: date("2023-05-01"), Occupancy: 0},
+				rules:    rules,
+			},
+			wantPrice:   153,
+			wantApplied: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, applied := domain.PriceNight(tt.args.baseRate, tt.args.night, tt.args.rules)
+			if price != tt.wantPrice {
+				t.Errorf("PriceNight() price = %v, want %v", price, tt.wantPrice)
+			}
+			if len(applied) != tt.wantApplied {
+				t.Errorf("PriceNight() applied %v rules, want %v", len(applied), tt.wantApplied)
+			}
+		})
+	}
+}
+
+func TestPriceNight_IsDeterministic(t *testing.T) {
+	rules := []domain.PricingRule{
+		{AbstractBase: domain.AbstractBase{UUID: "b"}, Type: domain.DAY_OF_WEEK, Weekday: 4, Multiplier: 1.5},
+		{AbstractBase: domain.AbstractBase{UUID: "a"}, Type: domain.DAY_OF_WEEK, Weekday: 4, Multiplier: 2},
+	}
+	reversed := []domain.PricingRule{rules[1], rules[0]}
+	night := domain.NightDemand{Date: date("2023-06-01"), BookedOn: date("2023-05-01")}
+
+	price, _ := domain.PriceNight(100, night, rules)
+	reversedPrice, _ := domain.PriceNight(100, night, reversed)
+	if price != reversedPrice || price != 200 {
+		t.Fatalf("expected the rule order not to matter, got %v and %v", price, reversedPrice)
+	}
+}
+
+func TestPricingRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    domain.PricingRule
+		wantErr bool
+	}{
+		{
+			name:    "valid occupancy tier",
+			rule:    domain.PricingRule{Type: domain.OCCUPANCY, MinOccupancy: 80, MaxOccupancy: 100, Multiplier: 1.2},
+			wantErr: false,
+		},
+		{
+			name:    "inverted occupancy tier",
+			rule:    domain.PricingRule{Type: domain.OCCUPANCY, MinOccupancy: 80, MaxOccupancy: 50, Multiplier: 1.2},
+			wantErr: true,
+		},
+		{
+			name:    "invalid weekday",
+			rule:    domain.PricingRule{Type: domain.DAY_OF_WEEK, Weekday: 7, Multiplier: 1.2},
+			wantErr: true,
+		},
+		{
+			name:    "zero multiplier",
+			rule:    domain.PricingRule{Type: domain.LEAD_TIME, MinLeadDays: 1, Multiplier: 0},
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			rule:    domain.PricingRule{Type: "SEASON", Multiplier: 1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("PricingRule.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
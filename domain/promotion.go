@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// DiscountType is how a promotion's value is applied to a price
+type DiscountType string
+
+const (
+	// PERCENTAGE discounts take a share of the price off e.g 10 is 10% off
+	PERCENTAGE DiscountType = "PERCENTAGE"
+	// FIXED discounts take a fixed amount off the price
+	FIXED DiscountType = "FIXED"
+)
+
+// Promotion is a promo code guests can apply to a reservation
+// Empty HotelUUIDs or RoomTypeUUIDs make the promotion apply to every hotel or room type, and a zero
+// MaxUses or MaxUsesPerGuest means the promotion can be used without limit
+type Promotion struct {
+	AbstractBase    `gorm:"embedded"`
+	Code            string       `json:"code" gorm:"uniqueIndex;not null"`
+	Description     string       `json:"description"`
+	DiscountType    DiscountType `json:"discount_type" gorm:"not null"`
+	Value           float64      `json:"value"`
+	ValidFrom       time.Time    `json:"valid_from"`
+	ValidUntil      time.Time    `json:"valid_until"`
+	HotelUUIDs      []string     `json:"hotel_uuids" gorm:"serializer:json"`
+	RoomTypeUUIDs   []string     `json:"roomtype_uuids" gorm:"serializer:json"`
+	MaxUses         int          `json:"max_uses"`
+	MaxUsesPerGuest int          `json:"max_uses_per_guest"`
+	UsedCount       int          `json:"used_count"`
+}
+
+// PromotionRedemption records a promotion being used on a reservation
+type PromotionRedemption struct {
+	AbstractBase    `gorm:"embedded"`
+	PromotionUUID   string    `json:"promotion_uuid" gorm:"index"`
+	Promotion       Promotion `json:"promotion,omitempty" gorm:"foreignKey:PromotionUUID"`
+	GuestUUID       string    `json:"guest_uuid" gorm:"index"`
+	Guest           Guest     `json:"guest,omitempty" gorm:"foreignKey:GuestUUID"`
+	ReservationUUID string    `json:"reservation_uuid" gorm:"uniqueIndex"`
+	Discount        int       `json:"discount"`
+}
+
+// NormalizePromoCode makes promo codes case insensitive
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate asserts the promotion is well formed
+func (p *Promotion) Validate() error {
+	if p.Code == "" {
+		return fmt.Errorf("a promotion must have a code")
+	}
+	switch p.DiscountType {
+	case PERCENTAGE:
+		if p.Value <= 0 || p.Value > 100 {
+			return fmt.Errorf("a percentage discount must be greater than 0 and at most 100")
+		}
+	case FIXED:
+		if p.Value <= 0 {
+			return fmt.Errorf("a fixed discount must be greater than 0")
+		}
+	default:
+		return fmt.Errorf("unknown discount type %q", p.DiscountType)
+	}
+	if !p.ValidUntil.IsZero() && p.ValidUntil.Before(p.ValidFrom) {
+		return fmt.Errorf("the promotion can't end before it starts")
+	}
+	if p.MaxUses < 0 || p.MaxUsesPerGuest < 0 {
+		return fmt.Errorf("usage caps can't be negative")
+	}
+	return nil
+}
+
+// CheckApplicable returns ErrPromotionNotApplicable when the promotion can't be used for a booking of
+// a room type made at the given time
+func (p *Promotion) CheckApplicable(HotelUUID string, RoomTypeUUID string, at time.Time) error {
+	if !p.Active {
+		return fmt.Errorf("%w: the promotion is no longer active", ErrPromotionNotApplicable)
+	}
+	if at.Before(p.ValidFrom) || (!p.ValidUntil.IsZero() && at.After(p.ValidUntil)) {
+		return fmt.Errorf("%w: the promotion isn't valid at this time", ErrPromotionNotApplicable)
+	}
+	if len(p.HotelUUIDs) > 0 && !contains(p.HotelUUIDs, HotelUUID) {
+		return fmt.Errorf("%w: the promotion doesn't apply to this hotel", ErrPromotionNotApplicable)
+	}
+	if len(p.RoomTypeUUIDs) > 0 && !contains(p.RoomTypeUUIDs, RoomTypeUUID) {
+		return fmt.Errorf("%w: the promotion doesn't apply to this room type", ErrPromotionNotApplicable)
+	}
+	if p.MaxUses > 0 && p.UsedCount >= p.MaxUses {
+		return ErrPromotionExhausted
+	}
+	return nil
+}
+
+// Covers reports whether the promotion can discount stays at the hotel in the room type
+func (p *Promotion) Covers(HotelUUID string, RoomTypeUUID string) bool {
+	return (len(p.HotelUUIDs) == 0 || contains(p.HotelUUIDs, HotelUUID)) &&
+		(len(p.RoomTypeUUIDs) == 0 || contains(p.RoomTypeUUIDs, RoomTypeUUID))
+}
+
+// Discount is the amount the promotion takes off a price, it never exceeds the price
+func (p *Promotion) Discount(price int) int {
+	var discount int
+	switch p.DiscountType {
+	case PERCENTAGE:
+		discount = int(math.Round(float64(price) * p.Value / 100))
+	case FIXED:
+		discount = int(math.Round(p.Value))
+	}
+	if discount > price {
+		return price
+	}
+	return discount
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
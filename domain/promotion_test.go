@@ -0,0 +1,88 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+func TestPromotion_Discount(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion domain.Promotion
+		price     int
+		want      int
+	}{
+		{
+			name:      "percentage",
+			promotion: domain.Promotion{DiscountType: domain.PERCENTAGE, Value: 10},
+			price:     255,
+			want:      26,
+		},
+		{
+			name:      "fixed",
+			promotion: domain.Promotion{DiscountType: domain.FIXED, Value: 50},
+			price:     255,
+			want:      50,
+		},
+		{
+			name:      "fixed discount larger than the price",
+			promotion: domain.Promotion{DiscountType: domain.FIXED, Value: 500},
+			price:     255,
+			want:      255,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.promotion.Discount(tt.price); got != tt.want {
+				t.Errorf("Promotion.Discount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromotion_CheckApplicable(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	valid := domain.Promotion{
+		AbstractBase:  domain.AbstractBase{Active: true},
+		DiscountType:  domain.PERCENTAGE,
+		Value:         10,
+		ValidFrom:     now.AddDate(0, -1, 0),
+		ValidUntil:    now.AddDate(0, 1, 0),
+		HotelUUIDs:    []string{"hotel"},
+		RoomTypeUUIDs: []string{"room-type"},
+		MaxUses:       10,
+		UsedCount:     9,
+	}
+	expired := valid
+	expired.ValidUntil = now.AddDate(0, 0, -1)
+	exhausted := valid
+	exhausted.UsedCount = 10
+
+	tests := []struct {
+		name      string
+		promotion domain.Promotion
+		hotel     string
+		roomType  string
+		wantErr   error
+	}{
+		{name: "valid", promotion: valid, hotel: "hotel", roomType: "room-type"},
+		{name: "expired", promotion: expired, hotel: "hotel", roomType: "room-type", wantErr: domain.ErrPromotionNotApplicable},
+		{name: "other hotel", promotion: valid, hotel: "other", roomType: "room-type", wantErr: domain.ErrPromotionNotApplicable},
+		{name: "other room type", promotion: valid, hotel: "hotel", roomType: "other", wantErr: domain.ErrPromotionNotApplicable},
+		{name: "exhausted", promotion: exhausted, hotel: "hotel", roomType: "room-type", wantErr: domain.ErrPromotionExhausted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.promotion.CheckApplicable(tt.hotel, tt.roomType, now)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Promotion.CheckApplicable() unexpected error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Promotion.CheckApplicable() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
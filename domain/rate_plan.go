@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// RatePlan is a sellable product of a room type e.g "non-refundable 10% off" or "breakfast included"
+// A zero valued restriction means the restriction doesn't apply
+type RatePlan struct {
+	AbstractBase      `gorm:"embedded"`
+	HotelUUID         string   `json:"hotel_uuid" gorm:"index"`
+	Hotel             Hotel    `json:"hotel,omitempty" gorm:"foreignKey:HotelUUID"`
+	RoomTypeUUID      string   `json:"roomtype_uuid" gorm:"index"`
+	RoomType          RoomType `json:"room_type,omitempty" gorm:"foreignKey:RoomTypeUUID"`
+	Name              string   `json:"name" gorm:"not null"`
+	Description       string   `json:"description"`
+	Refundable        bool     `json:"refundable"`
+	BreakfastIncluded bool     `json:"breakfast_included"`
+	MinLengthOfStay   int      `json:"min_length_of_stay"`
+	MaxLengthOfStay   int      `json:"max_length_of_stay"`
+	MinAdvanceDays    int      `json:"min_advance_days"`
+	MaxAdvanceDays    int      `json:"max_advance_days"`
+}
+
+// RatePlanRate is the price and the arrival/departure restrictions of a rate plan on a given night
+type RatePlanRate struct {
+	AbstractBase      `gorm:"embedded"`
+	RatePlanUUID      string    `json:"rateplan_uuid" gorm:"uniqueIndex:idx_rate_plan_rates_plan_date"`
+	RatePlan          RatePlan  `json:"rate_plan,omitempty" gorm:"foreignKey:RatePlanUUID"`
+	Date              time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_rate_plan_rates_plan_date"`
+	Rate              int       `json:"rate"`
+	MinLengthOfStay   int       `json:"min_length_of_stay"`
+	ClosedToArrival   bool      `json:"closed_to_arrival"`
+	ClosedToDeparture bool      `json:"closed_to_departure"`
+}
+
+// Violations lists every restriction of the plan that a stay from checkIn to checkOut, booked on bookedOn, breaks.
+// rates holds the plan's nightly rates keyed by date and must cover the departure date for
+// closed to departure restrictions to be evaluated. An empty result means the stay can be sold
+func (p *RatePlan) Violations(
+	checkIn time.Time,
+	checkOut time.Time,
+	bookedOn time.Time,
+	rates map[time.Time]RatePlanRate,
+) []string {
+	violations := []string{}
+	nights := StayNights(checkIn, checkOut)
+	lengthOfStay := len(nights)
+	if lengthOfStay == 0 {
+		return append(violations, "the stay must be at least one night long")
+	}
+	arrival, departure := nights[0], TruncateToDate(checkOut)
+
+	minLengthOfStay := p.MinLengthOfStay
+	if rate, ok := rates[arrival]; ok {
+		if rate.MinLengthOfStay > minLengthOfStay {
+			minLengthOfStay = rate.MinLengthOfStay
+		}
+		if rate.ClosedToArrival {
+			violations = append(violations, fmt.Sprintf("arrivals are closed on %s", arrival.Format(DateLayout)))
+		}
+	}
+	if minLengthOfStay > 0 && lengthOfStay < minLengthOfStay {
+		violations = append(violations, fmt.Sprintf("a minimum stay of %d nights is required", minLengthOfStay))
+	}
+	if p.MaxLengthOfStay > 0 && lengthOfStay > p.MaxLengthOfStay {
+		violations = append(violations, fmt.Sprintf("the stay can't be longer than %d nights", p.MaxLengthOfStay))
+	}
+	if rate, ok := rates[departure]; ok && rate.ClosedToDeparture {
+		violations = append(violations, fmt.Sprintf("departures are closed on %s", departure.Format(DateLayout)))
+	}
+
+	advanceDays := int(arrival.Sub(TruncateToDate(bookedOn)).Hours() / 24)
+	if p.MinAdvanceDays > 0 && advanceDays < p.MinAdvanceDays {
+		violations = append(violations, fmt.Sprintf("the stay must be booked at least %d days in advance", p.MinAdvanceDays))
+	}
+	if p.MaxAdvanceDays > 0 && advanceDays > p.MaxAdvanceDays {
+		violations = append(violations, fmt.Sprintf("the stay can't be booked more than %d days in advance", p.MaxAdvanceDays))
+	}
+
+	for _, night := range nights {
+		if _, ok := rates[night]; !ok {
+			violations = append(violations, fmt.Sprintf("the plan has no rate for %s", night.Format(DateLayout)))
+		}
+	}
+	return violations
+}
+
+// Quote sums the plan's nightly rates over a stay
+func (p *RatePlan) Quote(
+	checkIn time.Time,
+	checkOut time.Time,
+	rates map[time.Time]RatePlanRate,
+) (int, error) {
+	total := 0
+	for _, night := range StayNights(checkIn, checkOut) {
+		rate, ok := rates[night]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrMissingRate, night.Format(DateLayout))
+		}
+		total += rate.Rate
+	}
+	return total, nil
+}
@@ -0,0 +1,163 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+func date(value string) time.Time {
+	d, err := domain.ParseDate(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestRatePlan_Violations(t *testing.T) {
+	// 2023-06-02 is a Friday
+	rates := map[time.Time]domain.RatePlanRate{
+		date("2023-06-01"): {Rate: 100},
+		date("2023-06-02"): {Rate: 150, MinLengthOfStay: 2},
+		date("2023-06-03"): {Rate: 150},
+		date("2023-06-04"): {Rate: 100, ClosedToArrival: true},
+		date("2023-06-05"): {Rate: 100, ClosedToDeparture: true},
+	}
+
+	type args struct {
+		ratePlan domain.RatePlan
+		checkIn  time.Time
+		checkOut time.Time
+		bookedOn time.Time
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantViolations int
+	}{
+		{
+			name: "Happy case: unrestricted plan",
+			args: args{
+				checkIn:  date("2023-06-01"),
+				checkOut: date("2023-06-02"),
+				bookedOn: date("2023-05-01"),
+			},
+			wantViolations: 0,
+		},
+		{
+			name: "Sad case: weekend minimum stay",
+			args: args{
+				checkIn:  date("2023-06-02"),
+				checkOut: date("2023-06-03"),
+				bookedOn: date("2023-05-01"),
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "Sad case: plan minimum stay",
+			args: args{
+				ratePlan: domain.RatePlan{MinLengthOfStay: 3},
+				checkIn:  date("2023-06-01"),
+				checkOut: date("2023-06-03"),
+				bookedOn: date("2023-05-01"),
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "Sad case: maximum stay",
+			args: args{
+				ratePlan: domain.RatePlan{MaxLengthOfStay: 1},
+				checkIn:  date("2023-06-01"),
+				checkOut: date("2023-06-03"),
+				bookedOn: date("2023-05-01"),
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "Sad case: closed to arrival",
+			args: args{
+				checkIn:  date("2023-06-04"),
+				checkOut: date("2023-06-05"),
+				bookedOn: date("2023-05-01"),
+			},
+			// departing on the 5th is closed as well
+			wantViolations: 2,
+		},
+		{
+			name: "Sad case: closed to departure",
+			args: args{
+				checkIn:  date("2023-06-03"),
+				checkOut: date("2023-06-05"),
+				bookedOn: date("2023-05-01"),
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "Sad case: advance purchase window not met",
+			args: args{
+				ratePlan: domain.RatePlan{MinAdvanceDays: 14},
+				checkIn:  date("2023-06-01"),
+				checkOut: date("2023-06-02"),
+				bookedOn: date("2023-05-25"),
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "Sad case: booked too far ahead",
+			args: args{
+				ratePlan: domain.RatePlan{MaxAdvanceDays: 7},
+				checkIn:  date("2023-06-01"),
+				checkOut: date("2023-06-02"),
+				bookedOn: date("2023-05-01"),
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "Sad case: night without a rate",
+			args: args{
+				checkIn:  date("2023-06-05"),
+				checkOut: date("2023-06-07"),
+				bookedOn: date("2023-05-01"),
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "Sad case: zero night stay",
+			args: args{
+				checkIn:  date("2023-06-01"),
+				checkOut: date("2023-06-01"),
+				bookedOn: date("2023-05-01"),
+			},
+			wantViolations: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := tt.args.ratePlan.Violations(tt.args.checkIn, tt.args.checkOut, tt.args.bookedOn, rates)
+			if len(violations) != tt.wantViolations {
+				t.Errorf("RatePlan.Violations() = %v, want %v violations", violations, tt.wantViolations)
+			}
+		})
+	}
+}
+
+func TestRatePlan_Quote(t *testing.T) {
+	ratePlan := domain.RatePlan{}
+	rates := map[time.Time]domain.RatePlanRate{
+		date("2023-06-01"): {Rate: 100},
+		date("2023-06-02"): {Rate: 150},
+	}
+
+	total, err := ratePlan.Quote(date("2023-06-01"), date("2023-06-03"), rates)
+	if err != nil {
+		t.Fatalf("RatePlan.Quote() unexpected error = %v", err)
+	}
+	if total != 250 {
+		t.Fatalf("expected a total of 250 but got %v", total)
+	}
+
+	if _, err := ratePlan.Quote(date("2023-06-01"), date("2023-06-04"), rates); err == nil {
+		t.Fatalf("expected an error when a night has no rate")
+	}
+}
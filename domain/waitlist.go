@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// WaitlistStatus is where a waitlist entry is in its lifecycle
+type WaitlistStatus string
+
+const (
+	// WAITING entries are waiting for a room to be freed
+	WAITING WaitlistStatus = "WAITING"
+	// OFFERED entries have been offered a time limited hold on a freed room
+	OFFERED WaitlistStatus = "OFFERED"
+	// BOOKED entries confirmed the hold they were offered
+	BOOKED WaitlistStatus = "BOOKED"
+	// LAPSED entries let the hold they were offered expire
+	LAPSED WaitlistStatus = "LAPSED"
+)
+
+// WaitlistEntry is a guest waiting for a sold out room type to free up over a stay
+type WaitlistEntry struct {
+	AbstractBase    `gorm:"embedded"`
+	GuestUUID       string         `json:"guest_uuid" gorm:"index;not null"`
+	HotelUUID       string         `json:"hotel_uuid" gorm:"not null"`
+	RoomTypeUUID    string         `json:"roomtype_uuid" gorm:"index:idx_waitlist_entries_room_type_status;not null"`
+	StartDate       time.Time      `json:"start_date" gorm:"type:date;not null"`
+	EndDate         time.Time      `json:"end_date" gorm:"type:date;not null"`
+	Status          WaitlistStatus `json:"status" gorm:"index:idx_waitlist_entries_room_type_status;not null"`
+	ReservationUUID *string        `json:"reservation_uuid,omitempty" gorm:"index"`
+	OfferedAt       *time.Time     `json:"offered_at,omitempty"`
+}
@@ -0,0 +1,164 @@
+package domain
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DeliveryStatus is where a webhook delivery is in its retry lifecycle
+type DeliveryStatus string
+
+const (
+	// DELIVERY_PENDING deliveries are waiting for their next attempt
+	DELIVERY_PENDING DeliveryStatus = "PENDING"
+	// DELIVERED deliveries were acknowledged by the receiver with a 2xx response
+	DELIVERED DeliveryStatus = "DELIVERED"
+	// DEAD_LETTERED deliveries failed MaxWebhookAttempts times and won't be retried
+	DEAD_LETTERED DeliveryStatus = "DEAD_LETTERED"
+)
+
+const (
+	// MaxWebhookAttempts is how many times a delivery is attempted before it is dead lettered
+	MaxWebhookAttempts = 8
+	// webhookBaseBackoff is the wait after the first failed attempt, it doubles after every failure
+	webhookBaseBackoff = 30 * time.Second
+	// webhookMaxBackoff caps the wait between two attempts
+	webhookMaxBackoff = time.Hour
+	// minWebhookSecretLength keeps signing secrets long enough not to be guessed
+	minWebhookSecretLength = 16
+	// WebhookDeliveryLease is how long a dispatcher has to attempt the deliveries it claimed before they
+	// are claimed again, it outlasts a batch of deliveries to receivers that time out
+	WebhookDeliveryLease = 15 * time.Minute
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, it isn't reachable from the internet either
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// WebhookSubscription is a partner's endpoint that receives a hotel's reservation and booking events
+// Empty EventTypes subscribe to every event
+type WebhookSubscription struct {
+	AbstractBase `gorm:"embedded"`
+	HotelUUID    string      `json:"hotel_uuid" gorm:"index;not null"`
+	URL          string      `json:"url" gorm:"not null"`
+	Secret       string      `json:"-" gorm:"not null"`
+	EventTypes   []EventType `json:"event_types" gorm:"serializer:json"`
+}
+
+// WebhookDelivery is an event being delivered to a subscription
+type WebhookDelivery struct {
+	AbstractBase     `gorm:"embedded"`
+	SubscriptionUUID string               `json:"subscription_uuid" gorm:"uniqueIndex:idx_webhook_deliveries_subscription_event;not null"`
+	Subscription     *WebhookSubscription `json:"-" gorm:"foreignKey:SubscriptionUUID"`
+	EventUUID        string               `json:"event_uuid" gorm:"uniqueIndex:idx_webhook_deliveries_subscription_event;not null"`
+	Event            *OutboxEvent         `json:"-" gorm:"foreignKey:EventUUID"`
+	EventType        EventType            `json:"event_type"`
+	Status           DeliveryStatus       `json:"status" gorm:"index"`
+	Attempts         int                  `json:"attempts"`
+	NextAttemptAt    time.Time            `json:"next_attempt_at" gorm:"index"`
+	LastStatusCode   int                  `json:"last_status_code,omitempty"`
+	LastError        string               `json:"last_error,omitempty"`
+	DeliveredAt      *time.Time           `json:"delivered_at,omitempty"`
+}
+
+// IsValid reports whether the event type is one the system publishes
+func (t EventType) IsValid() bool {
+	switch t {
+	case RESERVATION_HELD, RESERVATION_CREATED, RESERVATION_PAYMENT_FAILED, RESERVATION_HOLD_EXPIRED,
+		RESERVATION_CANCELLED, RESERVATION_CHECKED_IN, RESERVATION_CHECKED_OUT, RESERVATION_ARRIVAL_DUE,
+		RESERVATION_WAITLIST_OFFERED, RESERVATION_MODIFIED, BOOKING_CREATED, BOOKING_LINE_CANCELLED,
+		BOOKING_BLOCK_CONFIRMED, BOOKING_BLOCK_RELEASED:
+		return true
+	}
+	return false
+}
+
+// Validate checks that the subscription can be delivered to
+func (s *WebhookSubscription) Validate() error {
+	if s.HotelUUID == "" {
+		return fmt.Errorf("a webhook subscription needs a hotel")
+	}
+	endpoint, err := url.Parse(s.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Hostname() == "" {
+		return fmt.Errorf("invalid webhook url %q", s.URL)
+	}
+	host := strings.ToLower(strings.TrimSuffix(endpoint.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("a webhook url can't point at the service's own host")
+	}
+	if ip := net.ParseIP(host); ip != nil && IsInternalAddress(ip) {
+		return fmt.Errorf("a webhook url can't point at the internal address %s", ip)
+	}
+	if len(s.Secret) < minWebhookSecretLength {
+		return fmt.Errorf("a webhook secret must be at least %d characters long", minWebhookSecretLength)
+	}
+	for _, eventType := range s.EventTypes {
+		if !eventType.IsValid() {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+	return nil
+}
+
+// IsInternalAddress reports whether ip is an address webhooks mustn't be sent to, one that reaches the
+// service's own host or network rather than a partner: loopback, link-local, private, shared, unspecified
+// and multicast addresses. Hostnames are checked once they are resolved, when a delivery is made
+func IsInternalAddress(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() ||
+		ip.IsUnspecified() || ip.IsMulticast() || sharedAddressSpace.Contains(ip)
+}
+
+// Matches reports whether an event should be delivered to the subscription
+func (s *WebhookSubscription) Matches(event *OutboxEvent) bool {
+	if !s.Active || s.HotelUUID != event.HotelUUID {
+		return false
+	}
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, eventType := range s.EventTypes {
+		if eventType == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookBackoff is how long to wait before retrying a delivery that has failed attempts times
+func WebhookBackoff(attempts int) time.Duration {
+	return exponentialBackoff(attempts, webhookBaseBackoff, webhookMaxBackoff)
+}
+
+// exponentialBackoff is the wait after attempts failures: base after the first, doubling after every
+// other failure up to max
+func exponentialBackoff(attempts int, base, max time.Duration) time.Duration {
+	backoff := base
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= max {
+			return max
+		}
+	}
+	return backoff
+}
+
+// RecordAttempt updates the delivery with the outcome of an attempt made at the given time.
+// Failed deliveries are retried with an exponential backoff until MaxWebhookAttempts is reached
+func (d *WebhookDelivery) RecordAttempt(at time.Time, statusCode int, err error) {
+	d.Attempts++
+	d.LastStatusCode = statusCode
+	if err == nil {
+		d.Status = DELIVERED
+		d.LastError = ""
+		d.DeliveredAt = &at
+		return
+	}
+	d.LastError = err.Error()
+	if d.Attempts >= MaxWebhookAttempts {
+		d.Status = DEAD_LETTERED
+		return
+	}
+	d.NextAttemptAt = at.Add(WebhookBackoff(d.Attempts))
+}
@@ -0,0 +1,135 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 4, want: 4 * time.Minute},
+		{attempts: 20, want: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := domain.WebhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("WebhookBackoff(%v) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookDelivery_RecordAttempt(t *testing.T) {
+	at := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	failure := errors.New("receiver responded with 500")
+
+	tests := []struct {
+		name        string
+		attempts    int
+		err         error
+		wantStatus  domain.DeliveryStatus
+		wantNextRun time.Time
+	}{
+		{
+			name:       "delivered",
+			err:        nil,
+			wantStatus: domain.DELIVERED,
+		},
+		{
+			name:        "first failure is retried",
+			err:         failure,
+			wantStatus:  domain.DELIVERY_PENDING,
+			wantNextRun: at.Add(30 * time.Second),
+		},
+		{
+			name:       "last failure is dead lettered",
+			attempts:   domain.MaxWebhookAttempts - 1,
+			err:        failure,
+			wantStatus: domain.DEAD_LETTERED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := &domain.WebhookDelivery{Status: domain.DELIVERY_PENDING, Attempts: tt.attempts}
+			delivery.RecordAttempt(at, 500, tt.err)
+			if delivery.Status != tt.wantStatus {
+				t.Errorf("expected status %v but got %v", tt.wantStatus, delivery.Status)
+			}
+			if !tt.wantNextRun.IsZero() && !delivery.NextAttemptAt.Equal(tt.wantNextRun) {
+				t.Errorf("expected the next attempt at %v but got %v", tt.wantNextRun, delivery.NextAttemptAt)
+			}
+		})
+	}
+}
+
+func TestWebhookSubscription_Matches(t *testing.T) {
+	event := &domain.OutboxEvent{HotelUUID: "hotel", Type: domain.RESERVATION_CANCELLED}
+	tests := []struct {
+		name         string
+		subscription domain.WebhookSubscription
+		want         bool
+	}{
+		{
+			name:         "every event of the hotel",
+			subscription: domain.WebhookSubscription{AbstractBase: domain.AbstractBase{Active: true}, HotelUUID: "hotel"},
+			want:         true,
+		},
+		{
+			name: "filtered out event type",
+			subscription: domain.WebhookSubscription{
+				AbstractBase: domain.AbstractBase{Active: true},
+				HotelUUID:    "hotel",
+				EventTypes:   []domain.EventType{domain.RESERVATION_CREATED},
+			},
+			want: false,
+		},
+		{
+			name:         "another hotel",
+			subscription: domain.WebhookSubscription{AbstractBase: domain.AbstractBase{Active: true}, HotelUUID: "other"},
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.subscription.Matches(event); got != tt.want {
+				t.Errorf("WebhookSubscription.Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookSubscription_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "public receiver", url: "https://partner.example.com/hooks"},
+		{name: "public address", url: "https://203.0.113.10/hooks"},
+		{name: "not http", url: "ftp://partner.example.com/hooks", wantErr: true},
+		{name: "localhost", url: "http://localhost:8000/hooks", wantErr: true},
+		{name: "loopback address", url: "http://127.0.0.1/hooks", wantErr: true},
+		{name: "ipv6 loopback address", url: "http://[::1]/hooks", wantErr: true},
+		{name: "private address", url: "http://10.0.0.5/hooks", wantErr: true},
+		{name: "link-local metadata address", url: "http://169.254.169.254/latest/meta-data", wantErr: true},
+		{name: "unspecified address", url: "http://0.0.0.0/hooks", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscription := &domain.WebhookSubscription{
+				HotelUUID: "hotel",
+				URL:       tt.url,
+				Secret:    "a-very-long-secret",
+			}
+			if err := subscription.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("WebhookSubscription.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
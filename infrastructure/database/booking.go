@@ -0,0 +1,265 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateBooking claims the inventory of every line of a booking and creates it with its lines and the
+// payment authorized for it, if any. All of it happens in one transaction so that either all the rooms
+// are secured or none is
+func (p *PostgresDB) CreateBooking(
+	ctx context.Context,
+	booking *domain.Booking,
+	payment *domain.Payment,
+) (*domain.Booking, error) {
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		nights := domain.StayNights(booking.StartDate, booking.EndDate)
+		for _, line := range booking.Lines {
+			if err := claimInventory(tx, line.RoomTypeUUID, nights, line.Quantity); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(booking).Error; err != nil {
+			return err
+		}
+		if err := createBookingPayment(tx, booking, payment); err != nil {
+			return err
+		}
+		return recordBookingEvent(tx, domain.BOOKING_CREATED, booking)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new booking: %w", err)
+	}
+	return booking, nil
+}
+
+// GetBooking fetches a booking and its lines by the booking's UUID
+func (p *PostgresDB) GetBooking(
+	ctx context.Context,
+	BookingUUID string,
+) (*domain.Booking, error) {
+	var booking domain.Booking
+	if err := p.DB.WithContext(ctx).Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Where(&domain.Booking{
+		AbstractBase: domain.AbstractBase{UUID: BookingUUID},
+	}).Find(&booking).Error; err != nil {
+		return nil, err
+	}
+	if booking.UUID == "" {
+		return nil, nil
+	}
+	return &booking, nil
+}
+
+// CancelBookingLine gives back the rooms of one line of a booking and records the refund owed for them.
+// The booking is cancelled once none of its lines holds rooms
+func (p *PostgresDB) CancelBookingLine(
+	ctx context.Context,
+	BookingUUID string,
+	LineUUID string,
+) (*domain.Booking, error) {
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		booking, err := lockBooking(tx, BookingUUID)
+		if err != nil {
+			return err
+		}
+		var line *domain.BookingLine
+		for i := range booking.Lines {
+			if booking.Lines[i].UUID == LineUUID {
+				line = &booking.Lines[i]
+			}
+		}
+		if line == nil {
+			return fmt.Errorf("booking %s has no line %s", BookingUUID, LineUUID)
+		}
+		if !line.IsOpen() {
+			return fmt.Errorf("the line is already %s", line.Status)
+		}
+		nights := domain.StayNights(booking.StartDate, booking.EndDate)
+		if err := releaseInventory(tx, line.RoomTypeUUID, nights, line.Quantity); err != nil {
+			return err
+		}
+		if err := setBookingLineStatus(tx, line, domain.BOOKING_CANCELLED); err != nil {
+			return err
+		}
+		status := booking.Status
+		booking.RecalculateTotal()
+		if !hasOpenLines(booking) {
+			status = domain.BOOKING_CANCELLED
+		}
+		if err := setBookingStatus(tx, booking, status); err != nil {
+			return err
+		}
+		cancelledAt := time.Now()
+		if err := oweBookingLineRefund(tx, booking, line, cancelledAt); err != nil {
+			return err
+		}
+		event, err := domain.NewBookingLineCancelledEvent(booking, line, cancelledAt)
+		if err != nil {
+			return err
+		}
+		return storeEvent(tx, event)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't cancel booking line: %w", err)
+	}
+	return p.GetBooking(ctx, BookingUUID)
+}
+
+// ConfirmBookingBlock books the rooms of a group block that hasn't been released yet and records the
+// payment authorized for them, if any. The conditional update waits for a releaser that has locked the
+// block, and then no longer matches it
+func (p *PostgresDB) ConfirmBookingBlock(
+	ctx context.Context,
+	BookingUUID string,
+	payment *domain.Payment,
+	now time.Time,
+) (*domain.Booking, error) {
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Booking{}).
+			Where("uuid = ? AND status = ? AND release_date > ?", BookingUUID, string(domain.BOOKING_BLOCKED), now).
+			Updates(map[string]interface{}{
+				"status":     string(domain.BOOKING_CONFIRMED),
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrBlockReleased
+		}
+		if err := tx.Model(&domain.BookingLine{}).
+			Where("booking_uuid = ? AND status = ?", BookingUUID, string(domain.BOOKING_BLOCKED)).
+			Updates(map[string]interface{}{
+				"status":     string(domain.BOOKING_CONFIRMED),
+				"updated_at": now,
+			}).Error; err != nil {
+			return err
+		}
+		booking, err := lockBooking(tx, BookingUUID)
+		if err != nil {
+			return err
+		}
+		if err := createBookingPayment(tx, booking, payment); err != nil {
+			return err
+		}
+		return recordBookingEvent(tx, domain.BOOKING_BLOCK_CONFIRMED, booking)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't confirm booking block: %w", err)
+	}
+	return p.GetBooking(ctx, BookingUUID)
+}
+
+// ReleaseExpiredBlocks gives back the rooms of up to limit group blocks whose release date is before now.
+// Blocks locked by another replica are skipped rather than waited on. It returns how many blocks were released
+func (p *PostgresDB) ReleaseExpiredBlocks(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) (int, error) {
+	var blocks []domain.Booking
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND release_date <= ?", string(domain.BOOKING_BLOCKED), now).
+			Order("release_date").
+			Limit(limit).
+			Find(&blocks).Error; err != nil {
+			return err
+		}
+		for i := range blocks {
+			block := &blocks[i]
+			if err := tx.Where("booking_uuid = ?", block.UUID).Find(&block.Lines).Error; err != nil {
+				return err
+			}
+			nights := domain.StayNights(block.StartDate, block.EndDate)
+			for j := range block.Lines {
+				line := &block.Lines[j]
+				if !line.IsOpen() {
+					continue
+				}
+				if err := releaseInventory(tx, line.RoomTypeUUID, nights, line.Quantity); err != nil {
+					return err
+				}
+				if err := setBookingLineStatus(tx, line, domain.BOOKING_RELEASED); err != nil {
+					return err
+				}
+			}
+			block.RecalculateTotal()
+			if err := setBookingStatus(tx, block, domain.BOOKING_RELEASED); err != nil {
+				return err
+			}
+			if err := recordBookingEvent(tx, domain.BOOKING_BLOCK_RELEASED, block); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("infrastructure: can't release expired booking blocks: %w", err)
+	}
+	return len(blocks), nil
+}
+
+// lockBooking locks a booking for the rest of the transaction and loads its lines
+func lockBooking(tx *gorm.DB, BookingUUID string) (*domain.Booking, error) {
+	var booking domain.Booking
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("uuid = ?", BookingUUID).
+		First(&booking).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("booking_uuid = ?", BookingUUID).Order("created_at").Find(&booking.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
+// createBookingPayment records the payment authorized for a booking, bookings that weren't charged have none
+func createBookingPayment(tx *gorm.DB, booking *domain.Booking, payment *domain.Payment) error {
+	if payment == nil {
+		return nil
+	}
+	payment.BookingUUID = &booking.UUID
+	return tx.Create(payment).Error
+}
+
+// hasOpenLines reports whether any line of a booking still holds rooms
+func hasOpenLines(booking *domain.Booking) bool {
+	for _, line := range booking.Lines {
+		if line.IsOpen() {
+			return true
+		}
+	}
+	return false
+}
+
+// setBookingStatus saves a booking's status and price
+func setBookingStatus(tx *gorm.DB, booking *domain.Booking, status domain.BookingStatus) error {
+	now := time.Now()
+	booking.Status = status
+	booking.UpdatedAt = &now
+	return tx.Model(&domain.Booking{}).Where("uuid = ?", booking.UUID).Updates(map[string]interface{}{
+		"status":      string(status),
+		"total_price": booking.TotalPrice,
+		"updated_at":  now,
+	}).Error
+}
+
+// setBookingLineStatus saves a booking line's status
+func setBookingLineStatus(tx *gorm.DB, line *domain.BookingLine, status domain.BookingStatus) error {
+	now := time.Now()
+	line.Status = status
+	line.UpdatedAt = &now
+	return tx.Model(&domain.BookingLine{}).Where("uuid = ?", line.UUID).Updates(map[string]interface{}{
+		"status":     string(status),
+		"updated_at": now,
+	}).Error
+}
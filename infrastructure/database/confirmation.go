@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	// confirmationCodeIndex is the unique index that keeps confirmation codes from being reused
+	confirmationCodeIndex = "idx_reservations_confirmation_code"
+	// maxConfirmationCodeAttempts caps how many codes are drawn for a reservation, with 32^7 codes a
+	// second collision in a row means something else is wrong
+	maxConfirmationCodeAttempts = 5
+	// uniqueViolation is postgres' error code for a unique constraint violation
+	uniqueViolation = "23505"
+)
+
+// insertReservation creates a reservation with a new confirmation code. The unique index settles races
+// between concurrent bookings, a code that is already taken is rolled back to a savepoint and redrawn
+// so that the rest of the transaction survives
+func insertReservation(tx *gorm.DB, reservation *domain.Reservation) error {
+	for attempt := 1; ; attempt++ {
+		code, err := domain.NewConfirmationCode()
+		if err != nil {
+			return err
+		}
+		reservation.ConfirmationCode = code
+		if err := tx.SavePoint("confirmation_code").Error; err != nil {
+			return err
+		}
+		err = tx.Create(reservation).Error
+		if err == nil || !isUniqueViolation(err, confirmationCodeIndex) || attempt == maxConfirmationCodeAttempts {
+			return err
+		}
+		if err := tx.RollbackTo("confirmation_code").Error; err != nil {
+			return err
+		}
+	}
+}
+
+// isUniqueViolation reports whether err was raised by the unique constraint or index named constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == constraint
+}
+
+// GetReservationByConfirmationCode fetches a reservation and its guest by the reservation's confirmation code
+func (p *PostgresDB) GetReservationByConfirmationCode(
+	ctx context.Context,
+	ConfirmationCode string,
+) (*domain.Reservation, error) {
+	var reservation domain.Reservation
+	if err := p.DB.WithContext(ctx).Preload("Guest").Where(&domain.Reservation{
+		ConfirmationCode: ConfirmationCode,
+	}).Find(&reservation).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't get reservation by confirmation code: %w", err)
+	}
+	if reservation.UUID == "" {
+		return nil, nil
+	}
+	return &reservation, nil
+}
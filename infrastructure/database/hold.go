@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConfirmHold moves a HELD reservation that hasn't expired to PENDING once its payment is authorized, so
+// that the payment can be recorded within the domain.PendingPaymentTimeout. The conditional update waits for a reaper that has locked
+// the hold, and then no longer matches it
+func (p *PostgresDB) ConfirmHold(
+	ctx context.Context,
+	ReservationUUID string,
+	now time.Time,
+) (*domain.Reservation, error) {
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Reservation{}).
+			Where("uuid = ? AND status = ? AND expires_at > ?", ReservationUUID, string(domain.HELD), now).
+			Updates(map[string]interface{}{
+				"status":     string(domain.PENDING),
+				"expires_at": now.Add(domain.PendingPaymentTimeout),
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrHoldExpired
+		}
+		return settleWaitlistOffers(tx, domain.BOOKED, ReservationUUID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't confirm hold: %w", err)
+	}
+	return p.GetReservation(ctx, ReservationUUID)
+}
+
+// ReleaseExpiredHolds expires up to limit holds that lapsed before now, and fails the payment of the
+// PENDING reservations whose authorization didn't complete in time, releasing their inventory and
+// promotions. Reservations locked by another replica's reaper are skipped rather than waited on, so
+// reapers can run concurrently without releasing a reservation twice. It returns how many were released
+func (p *PostgresDB) ReleaseExpiredHolds(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) (int, error) {
+	var lapsed []domain.Reservation
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND expires_at <= ?", []string{string(domain.HELD), string(domain.PENDING)}, now).
+			Order("expires_at").
+			Limit(limit).
+			Find(&lapsed).Error; err != nil {
+			return err
+		}
+		expiredHolds := make([]string, 0, len(lapsed))
+		for i := range lapsed {
+			reservation := &lapsed[i]
+			status, eventType := domain.PAYMENT_FAILED, domain.RESERVATION_PAYMENT_FAILED
+			if reservation.Status == string(domain.HELD) {
+				status, eventType = domain.EXPIRED, domain.RESERVATION_HOLD_EXPIRED
+				expiredHolds = append(expiredHolds, reservation.UUID)
+			}
+			if err := setReservationStatus(tx, reservation, status); err != nil {
+				return err
+			}
+			nights := domain.StayNights(reservation.StartDate, reservation.EndDate)
+			if err := releaseInventory(tx, reservation.RoomTypeUUID, nights, 1); err != nil {
+				return err
+			}
+			if err := releasePromotion(tx, reservation); err != nil {
+				return err
+			}
+			if err := recordEvent(tx, eventType, reservation); err != nil {
+				return err
+			}
+		}
+		return settleWaitlistOffers(tx, domain.LAPSED, expiredHolds...)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("infrastructure: can't release expired holds: %w", err)
+	}
+	return len(lapsed), nil
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// overbookingLimitSQL computes a room type's default overbooking limit in rooms
+const overbookingLimitSQL = `CASE WHEN rt.overbooking_type = 'PERCENTAGE'
+	THEN rt.inventory * rt.overbooking_value / 100 ELSE rt.overbooking_value END`
+
+// seedInventory makes sure a room type inventory row exists for every night, seeding missing rows
+// with the room type's inventory and default overbooking limit
+func seedInventory(
+	tx *gorm.DB,
+	roomTypeUUID string,
+	nights []time.Time,
+) error {
+	if len(nights) == 0 {
+		return nil
+	}
+	now := time.Now()
+	values := make([]string, 0, len(nights))
+	args := []interface{}{now, now}
+	for _, night := range nights {
+		values = append(values, "(?, CAST(? AS date))")
+		args = append(args, uuid.New().String(), night.Format(domain.DateLayout))
+	}
+	args = append(args, roomTypeUUID)
+	query := `INSERT INTO room_type_inventories
+		(uuid, active, created_at, updated_at, hotel_uuid, room_type_uuid, date, total_inventory, total_reserved, overbooking_limit)
+		SELECT v.uuid, TRUE, ?, ?, rt.hotel_uuid, rt.uuid, v.date, rt.inventory, 0, ` + overbookingLimitSQL + `
+		FROM (VALUES ` + strings.Join(values, ", ") + `) AS v (uuid, date)
+		JOIN room_types rt ON rt.uuid = ?
+		ON CONFLICT (room_type_uuid, date) DO NOTHING`
+	return tx.Exec(query, args...).Error
+}
+
+// claimInventory reserves quantity rooms of a room type on every night, failing with domain.ErrSoldOut
+// when any of the nights doesn't have enough rooms left, overbooking allowance included.
+// It must run within a transaction so that a partial claim is rolled back
+func claimInventory(
+	tx *gorm.DB,
+	roomTypeUUID string,
+	nights []time.Time,
+	quantity int64,
+) error {
+	if len(nights) == 0 {
+		return nil
+	}
+	if err := seedInventory(tx, roomTypeUUID, nights); err != nil {
+		return fmt.Errorf("can't seed room type inventory: %w", err)
+	}
+	result := tx.Model(&domain.RoomTypeInventory{}).
+		Where("room_type_uuid = ? AND date IN ?", roomTypeUUID, dateValues(nights)).
+		Where("total_reserved + ? <= total_inventory + overbooking_limit", quantity).
+		Updates(map[string]interface{}{
+			"total_reserved": gorm.Expr("total_reserved + ?", quantity),
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("can't claim room type inventory: %w", result.Error)
+	}
+	if result.RowsAffected != int64(len(nights)) {
+		return domain.ErrSoldOut
+	}
+	return nil
+}
+
+// releaseInventory gives back quantity rooms of a room type on every night
+func releaseInventory(
+	tx *gorm.DB,
+	roomTypeUUID string,
+	nights []time.Time,
+	quantity int64,
+) error {
+	if len(nights) == 0 {
+		return nil
+	}
+	err := tx.Model(&domain.RoomTypeInventory{}).
+		Where("room_type_uuid = ? AND date IN ?", roomTypeUUID, dateValues(nights)).
+		Updates(map[string]interface{}{
+			"total_reserved": gorm.Expr("GREATEST(total_reserved - ?, 0)", quantity),
+			"updated_at":     time.Now(),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("can't release room type inventory: %w", err)
+	}
+	return nil
+}
+
+// SetOverbookingLimits overrides a room type's overbooking limit on every night, percentages are taken of
+// each night's physical inventory
+func (p *PostgresDB) SetOverbookingLimits(
+	ctx context.Context,
+	RoomTypeUUID string,
+	nights []time.Time,
+	kind domain.OverbookingType,
+	value int,
+) ([]domain.RoomTypeInventory, error) {
+	limit := gorm.Expr("?", value)
+	if kind == domain.OVERBOOKING_PERCENTAGE {
+		limit = gorm.Expr("total_inventory * ? / 100", value)
+	}
+	var inventories []domain.RoomTypeInventory
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := seedInventory(tx, RoomTypeUUID, nights); err != nil {
+			return fmt.Errorf("can't seed room type inventory: %w", err)
+		}
+		err := tx.Model(&domain.RoomTypeInventory{}).
+			Where("room_type_uuid = ? AND date IN ?", RoomTypeUUID, dateValues(nights)).
+			Updates(map[string]interface{}{
+				"overbooking_limit": limit,
+				"updated_at":        time.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("room_type_uuid = ? AND date IN ?", RoomTypeUUID, dateValues(nights)).
+			Order("date").
+			Find(&inventories).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't set overbooking limits: %w", err)
+	}
+	return inventories, nil
+}
+
+// GetOversoldNights fetches the nights between two dates (both inclusive) on which a hotel's room types
+// have more rooms confirmed, by reservations and by the lines of multi-room bookings, than physical rooms.
+// The rooms of unconfirmed group blocks aren't counted, they are released unless they are confirmed
+func (p *PostgresDB) GetOversoldNights(
+	ctx context.Context,
+	HotelUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+) ([]domain.OversoldNight, error) {
+	var nights []domain.OversoldNight
+	err := p.DB.WithContext(ctx).Raw(`WITH stays AS (
+			SELECT r.room_type_uuid, r.start_date, r.end_date, 1 AS rooms
+			FROM reservations r
+			WHERE r.deleted_at IS NULL AND r.status IN ?
+			UNION ALL
+			SELECT l.room_type_uuid, b.start_date, b.end_date, l.quantity AS rooms
+			FROM booking_lines l
+			JOIN bookings b ON b.uuid = l.booking_uuid AND b.deleted_at IS NULL
+			WHERE l.deleted_at IS NULL AND l.status = ?
+		)
+		SELECT i.room_type_uuid, i.date, i.total_inventory AS physical_rooms,
+			SUM(s.rooms)::bigint AS confirmed, SUM(s.rooms)::bigint - i.total_inventory AS walk_outs
+		FROM room_type_inventories i
+		JOIN stays s ON s.room_type_uuid = i.room_type_uuid
+			AND s.start_date::date <= i.date AND s.end_date::date > i.date
+		WHERE i.hotel_uuid = ? AND i.date BETWEEN ? AND ? AND i.deleted_at IS NULL
+		GROUP BY i.room_type_uuid, i.date, i.total_inventory
+		HAVING SUM(s.rooms) > i.total_inventory
+		ORDER BY i.date, i.room_type_uuid`,
+		[]string{string(domain.RESERVED), string(domain.CHECKED_IN)},
+		string(domain.BOOKING_CONFIRMED),
+		HotelUUID,
+		StartDate.Format(domain.DateLayout),
+		EndDate.Format(domain.DateLayout),
+	).Scan(&nights).Error
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't get oversold nights: %w", err)
+	}
+	return nights, nil
+}
+
+// dateValues formats dates as calendar dates so that postgres compares them against date columns
+// without converting them through the session's time zone
+func dateValues(dates []time.Time) []string {
+	values := make([]string, 0, len(dates))
+	for _, date := range dates {
+		values = append(values, date.Format(domain.DateLayout))
+	}
+	return values
+}
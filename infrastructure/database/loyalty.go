@@ -0,0 +1,150 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// loyaltyEntryConflict skips entries whose reservation already has an entry of the same type. A
+// reservation may have several ADJUSTMENT entries, the unique index is partial and its predicate is
+// spelled out for Postgres to match it
+var loyaltyEntryConflict = clause.OnConflict{
+	Columns:     []clause.Column{{Name: "reservation_uuid"}, {Name: "type"}},
+	TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "type <> 'ADJUSTMENT'"}}},
+	DoNothing:   true,
+}
+
+// CreateLoyaltyEntry adds an entry to a guest's loyalty ledger. An entry of the same type that the
+// reservation already has is left as is, so that a redelivered event doesn't award points twice
+func (p *PostgresDB) CreateLoyaltyEntry(
+	ctx context.Context,
+	entry *domain.LoyaltyEntry,
+) error {
+	if err := p.DB.WithContext(ctx).Clauses(loyaltyEntryConflict).Create(entry).Error; err != nil {
+		return fmt.Errorf("infrastructure: can't create loyalty entry: %w", err)
+	}
+	return nil
+}
+
+// ReverseLoyaltyEntries undoes the points a reservation earned or spent with a single REVERSAL entry.
+// It returns nil when there is nothing to reverse or the reservation was already reversed
+func (p *PostgresDB) ReverseLoyaltyEntries(
+	ctx context.Context,
+	ReservationUUID string,
+) (*domain.LoyaltyEntry, error) {
+	var reversal *domain.LoyaltyEntry
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var entries []domain.LoyaltyEntry
+		if err := tx.Where(&domain.LoyaltyEntry{ReservationUUID: ReservationUUID}).Find(&entries).Error; err != nil {
+			return err
+		}
+		points := 0
+		for _, entry := range entries {
+			if entry.Type == domain.REVERSAL {
+				return nil
+			}
+			points += entry.Points
+		}
+		if points == 0 {
+			return nil
+		}
+		reversal = &domain.LoyaltyEntry{
+			GuestUUID:       entries[0].GuestUUID,
+			ReservationUUID: ReservationUUID,
+			Type:            domain.REVERSAL,
+			Points:          -points,
+		}
+		result := tx.Clauses(loyaltyEntryConflict).Create(reversal)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// reversed concurrently
+			reversal = nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't reverse loyalty entries: %w", err)
+	}
+	return reversal, nil
+}
+
+// GetLoyaltyBalance sums the points of a guest's loyalty ledger
+func (p *PostgresDB) GetLoyaltyBalance(
+	ctx context.Context,
+	GuestUUID string,
+) (int, error) {
+	return loyaltyBalance(p.DB.WithContext(ctx), GuestUUID)
+}
+
+// GetLoyaltyEntries fetches a guest's loyalty ledger, newest entries first
+func (p *PostgresDB) GetLoyaltyEntries(
+	ctx context.Context,
+	GuestUUID string,
+) ([]domain.LoyaltyEntry, error) {
+	var entries []domain.LoyaltyEntry
+	if err := p.DB.WithContext(ctx).Where(&domain.LoyaltyEntry{GuestUUID: GuestUUID}).
+		Order("created_at DESC").
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't get loyalty entries: %w", err)
+	}
+	return entries, nil
+}
+
+// redeemLoyaltyPoints spends the points redeemed on a reservation within its transaction. The guest is
+// locked while the balance is checked so that concurrent bookings can't spend the same points
+func redeemLoyaltyPoints(tx *gorm.DB, reservation *domain.Reservation) error {
+	if reservation.RedeemedPoints <= 0 {
+		return nil
+	}
+	var guest domain.Guest
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("uuid = ?", reservation.GuestUUID).
+		First(&guest).Error; err != nil {
+		return fmt.Errorf("can't lock guest: %w", err)
+	}
+	balance, err := loyaltyBalance(tx, reservation.GuestUUID)
+	if err != nil {
+		return err
+	}
+	if balance < reservation.RedeemedPoints {
+		return domain.ErrInsufficientPoints
+	}
+	return tx.Create(&domain.LoyaltyEntry{
+		GuestUUID:       reservation.GuestUUID,
+		ReservationUUID: reservation.UUID,
+		Type:            domain.REDEMPTION,
+		Points:          -reservation.RedeemedPoints,
+	}).Error
+}
+
+// refundLoyaltyPoints gives back the points a modified reservation no longer redeems within the
+// modification's transaction, previous are the points it redeemed before
+func refundLoyaltyPoints(tx *gorm.DB, reservation *domain.Reservation, previous int) error {
+	if reservation.RedeemedPoints >= previous {
+		return nil
+	}
+	return tx.Create(&domain.LoyaltyEntry{
+		GuestUUID:       reservation.GuestUUID,
+		ReservationUUID: reservation.UUID,
+		Type:            domain.ADJUSTMENT,
+		Points:          previous - reservation.RedeemedPoints,
+	}).Error
+}
+
+// loyaltyBalance sums the points of a guest's loyalty ledger
+func loyaltyBalance(db *gorm.DB, GuestUUID string) (int, error) {
+	var balance int
+	if err := db.Model(&domain.LoyaltyEntry{}).
+		Where(&domain.LoyaltyEntry{GuestUUID: GuestUUID}).
+		Select("COALESCE(SUM(points), 0)").
+		Scan(&balance).Error; err != nil {
+		return 0, fmt.Errorf("infrastructure: can't get loyalty balance: %w", err)
+	}
+	return balance, nil
+}
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the key of the advisory lock held while migrations run so that two instances
+// never migrate the same database at once
+const migrationLockID = 4_726_310_041
+
+// migrationFilePattern matches migration files such as 0001_initial_schema.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned change to the database's schema, Down reverts Up
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// LoadMigrations reads the migrations in fsys ordered by version.
+// Every version needs both an up and a down file
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("can't list migrations: %w", err)
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s isn't named <version>_<name>.<up|down>.sql", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s has an invalid version", entry.Name())
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, match[2])
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("can't read migration %s: %w", entry.Name(), err)
+		}
+		if match[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrations returns the migrations embedded in the binary
+func Migrations() ([]Migration, error) {
+	fsys, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("can't open embedded migrations: %w", err)
+	}
+	return LoadMigrations(fsys)
+}
+
+// LatestSchemaVersion is the version the embedded migrations bring the database to
+func LatestSchemaVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// SchemaVersion returns the version of the last migration applied to the database, 0 when none has been
+func SchemaVersion(ctx context.Context, db *gorm.DB) (int, error) {
+	if !db.WithContext(ctx).Migrator().HasTable("schema_migrations") {
+		return 0, nil
+	}
+	var version int
+	if err := db.WithContext(ctx).
+		Raw("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").
+		Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("infrastructure: can't read the schema version: %v", err)
+	}
+	return version, nil
+}
+
+// CheckSchemaVersion returns an error unless the database has been migrated to the latest embedded migration
+func CheckSchemaVersion(ctx context.Context, db *gorm.DB) error {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+	current, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	switch {
+	case current < latest:
+		return fmt.Errorf("the database schema is at version %d but version %d is required, run `migrate up`", current, latest)
+	case current > latest:
+		return fmt.Errorf("the database schema is at version %d which is newer than this build's version %d", current, latest)
+	}
+	return nil
+}
+
+// MigrateUp applies the embedded migrations the database hasn't had yet and returns how many were applied
+func MigrateUp(ctx context.Context, db *gorm.DB) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	applied := 0
+	err = withMigrationLock(ctx, db, func(conn *gorm.DB) error {
+		current, err := SchemaVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range migrations {
+			if migration.Version <= current {
+				continue
+			}
+			if err := applyMigration(conn, migration, migration.Up, true); err != nil {
+				return err
+			}
+			log.Infof("applied migration %d_%s", migration.Version, migration.Name)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the last steps migrations applied to the database and returns how many were reverted
+func MigrateDown(ctx context.Context, db *gorm.DB, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("the number of migrations to revert must be positive")
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	reverted := 0
+	err = withMigrationLock(ctx, db, func(conn *gorm.DB) error {
+		current, err := SchemaVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := migrations[i]
+			if migration.Version > current {
+				continue
+			}
+			if err := applyMigration(conn, migration, migration.Down, false); err != nil {
+				return err
+			}
+			log.Infof("reverted migration %d_%s", migration.Version, migration.Name)
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// withMigrationLock runs fn on a single connection holding the migration advisory lock.
+// The lock belongs to the session, so every statement has to go through the same connection
+func withMigrationLock(ctx context.Context, db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+			return fmt.Errorf("infrastructure: can't take the migration lock: %v", err)
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID).Error; err != nil {
+				log.Errorf("can't release the migration lock: %v", err)
+			}
+		}()
+
+		if err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+			version bigint PRIMARY KEY,
+			name text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)`).Error; err != nil {
+			return fmt.Errorf("infrastructure: can't create the schema_migrations table: %v", err)
+		}
+		return fn(conn)
+	})
+}
+
+// applyMigration runs one direction of a migration and records it in the same transaction,
+// so that a failed migration leaves neither its changes nor its version behind
+func applyMigration(conn *gorm.DB, migration Migration, statements string, up bool) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(statements).Error; err != nil {
+			return fmt.Errorf("infrastructure: can't run migration %d_%s: %v", migration.Version, migration.Name, err)
+		}
+		record := "DELETE FROM schema_migrations WHERE version = @version"
+		if up {
+			record = "INSERT INTO schema_migrations (version, name) VALUES (@version, @name)"
+		}
+		if err := tx.Exec(record, map[string]interface{}{
+			"version": migration.Version,
+			"name":    migration.Name,
+		}).Error; err != nil {
+			return fmt.Errorf("infrastructure: can't record migration %d_%s: %v", migration.Version, migration.Name, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,88 @@
+package database_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/database"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(body string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(body)}
+	}
+	tests := []struct {
+		name         string
+		fsys         fstest.MapFS
+		wantVersions []int
+		wantErr      bool
+	}{
+		{
+			name: "ordered by version",
+			fsys: fstest.MapFS{
+				"0010_add_index.up.sql":        file("CREATE INDEX"),
+				"0010_add_index.down.sql":      file("DROP INDEX"),
+				"0002_add_column.up.sql":       file("ALTER TABLE"),
+				"0002_add_column.down.sql":     file("ALTER TABLE"),
+				"0001_initial_schema.up.sql":   file("CREATE TABLE"),
+				"0001_initial_schema.down.sql": file("DROP TABLE"),
+			},
+			wantVersions: []int{1, 2, 10},
+		},
+		{
+			name: "missing down file",
+			fsys: fstest.MapFS{
+				"0001_initial_schema.up.sql": file("CREATE TABLE"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"0001_initial_schema.up.sql":   file("CREATE TABLE"),
+				"0001_initial_schema.down.sql": file("DROP TABLE"),
+				"0001_other.up.sql":            file("CREATE TABLE"),
+				"0001_other.down.sql":          file("DROP TABLE"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "badly named file",
+			fsys: fstest.MapFS{
+				"initial_schema.sql": file("CREATE TABLE"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := database.LoadMigrations(tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(migrations) != len(tt.wantVersions) {
+				t.Fatalf("LoadMigrations() returned %d migrations, want %d", len(migrations), len(tt.wantVersions))
+			}
+			for i, version := range tt.wantVersions {
+				if migrations[i].Version != version {
+					t.Errorf("migration %d has version %d, want %d", i, migrations[i].Version, version)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	migrations, err := database.Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatalf("expected the embedded migrations to start at version 1")
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version != migrations[i-1].Version+1 {
+			t.Errorf("migration %d_%s doesn't follow version %d", migrations[i].Version, migrations[i].Name, migrations[i-1].Version)
+		}
+	}
+}
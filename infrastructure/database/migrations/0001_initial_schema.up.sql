@@ -0,0 +1,384 @@
+-- The schema as it was created by gorm's AutoMigrate. Every statement is idempotent so that databases
+-- created before versioned migrations existed can be brought under them, 0002 adds the columns their
+-- older tables lack
+
+CREATE TABLE IF NOT EXISTS guests (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    first_name text,
+    last_name text,
+    email text UNIQUE,
+    age bigint
+);
+CREATE INDEX IF NOT EXISTS idx_guests_deleted_at ON guests (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_guests_first_name ON guests (first_name);
+CREATE INDEX IF NOT EXISTS idx_guests_last_name ON guests (last_name);
+
+CREATE TABLE IF NOT EXISTS hotels (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text,
+    address text,
+    location text,
+    email text,
+    logo_url text,
+    brand_color text
+);
+CREATE INDEX IF NOT EXISTS idx_hotels_deleted_at ON hotels (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_hotels_location ON hotels (location);
+
+CREATE TABLE IF NOT EXISTS room_types (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    hotel_uuid text,
+    inventory bigint,
+    reserved bigint,
+    overbooking_type text,
+    overbooking_value bigint,
+    CONSTRAINT fk_room_types_hotel FOREIGN KEY (hotel_uuid) REFERENCES hotels (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_room_types_deleted_at ON room_types (deleted_at);
+
+CREATE TABLE IF NOT EXISTS rooms (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    room_type_uuid text,
+    hotel_uuid text,
+    available boolean DEFAULT false,
+    CONSTRAINT fk_rooms_room_type FOREIGN KEY (room_type_uuid) REFERENCES room_types (uuid),
+    CONSTRAINT fk_rooms_hotel FOREIGN KEY (hotel_uuid) REFERENCES hotels (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_rooms_deleted_at ON rooms (deleted_at);
+
+CREATE TABLE IF NOT EXISTS rates (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    hotel_uuid text,
+    room_type_uuid text,
+    rate bigint,
+    date date,
+    CONSTRAINT fk_rates_hotel FOREIGN KEY (hotel_uuid) REFERENCES hotels (uuid),
+    CONSTRAINT fk_rates_room_type FOREIGN KEY (room_type_uuid) REFERENCES room_types (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_rates_deleted_at ON rates (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rates_hotel_room_type_date ON rates (hotel_uuid, room_type_uuid, date);
+
+CREATE TABLE IF NOT EXISTS room_type_inventories (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    hotel_uuid text,
+    room_type_uuid text,
+    date date,
+    total_inventory bigint,
+    total_reserved bigint,
+    overbooking_limit bigint,
+    CONSTRAINT fk_room_type_inventories_hotel FOREIGN KEY (hotel_uuid) REFERENCES hotels (uuid),
+    CONSTRAINT fk_room_type_inventories_room_type FOREIGN KEY (room_type_uuid) REFERENCES room_types (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_room_type_inventories_deleted_at ON room_type_inventories (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_room_type_inventories_hotel_uuid ON room_type_inventories (hotel_uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_room_type_inventories_room_type_date ON room_type_inventories (room_type_uuid, date);
+
+CREATE TABLE IF NOT EXISTS rate_plans (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    hotel_uuid text,
+    room_type_uuid text,
+    name text NOT NULL,
+    description text,
+    refundable boolean,
+    breakfast_included boolean,
+    min_length_of_stay bigint,
+    max_length_of_stay bigint,
+    min_advance_days bigint,
+    max_advance_days bigint,
+    CONSTRAINT fk_rate_plans_hotel FOREIGN KEY (hotel_uuid) REFERENCES hotels (uuid),
+    CONSTRAINT fk_rate_plans_room_type FOREIGN KEY (room_type_uuid) REFERENCES room_types (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_rate_plans_deleted_at ON rate_plans (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_rate_plans_hotel_uuid ON rate_plans (hotel_uuid);
+CREATE INDEX IF NOT EXISTS idx_rate_plans_room_type_uuid ON rate_plans (room_type_uuid);
+
+CREATE TABLE IF NOT EXISTS rate_plan_rates (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    rate_plan_uuid text,
+    date date,
+    rate bigint,
+    min_length_of_stay bigint,
+    closed_to_arrival boolean,
+    closed_to_departure boolean,
+    CONSTRAINT fk_rate_plan_rates_rate_plan FOREIGN KEY (rate_plan_uuid) REFERENCES rate_plans (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_rate_plan_rates_deleted_at ON rate_plan_rates (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rate_plan_rates_plan_date ON rate_plan_rates (rate_plan_uuid, date);
+
+CREATE TABLE IF NOT EXISTS pricing_rules (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    hotel_uuid text,
+    room_type_uuid text,
+    name text,
+    type text NOT NULL,
+    multiplier decimal NOT NULL,
+    priority bigint,
+    min_occupancy decimal,
+    max_occupancy decimal,
+    weekday bigint,
+    min_lead_days bigint,
+    max_lead_days bigint,
+    CONSTRAINT fk_pricing_rules_hotel FOREIGN KEY (hotel_uuid) REFERENCES hotels (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_pricing_rules_deleted_at ON pricing_rules (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_pricing_rules_hotel_uuid ON pricing_rules (hotel_uuid);
+CREATE INDEX IF NOT EXISTS idx_pricing_rules_room_type_uuid ON pricing_rules (room_type_uuid);
+
+CREATE TABLE IF NOT EXISTS promotions (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    code text NOT NULL,
+    description text,
+    discount_type text NOT NULL,
+    value decimal,
+    valid_from timestamptz,
+    valid_until timestamptz,
+    hotel_uuids text,
+    room_type_uuids text,
+    max_uses bigint,
+    max_uses_per_guest bigint,
+    used_count bigint
+);
+CREATE INDEX IF NOT EXISTS idx_promotions_deleted_at ON promotions (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotions_code ON promotions (code);
+
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    promotion_uuid text,
+    guest_uuid text,
+    reservation_uuid text,
+    discount bigint,
+    CONSTRAINT fk_promotion_redemptions_promotion FOREIGN KEY (promotion_uuid) REFERENCES promotions (uuid),
+    CONSTRAINT fk_promotion_redemptions_guest FOREIGN KEY (guest_uuid) REFERENCES guests (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_deleted_at ON promotion_redemptions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_promotion_uuid ON promotion_redemptions (promotion_uuid);
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_guest_uuid ON promotion_redemptions (guest_uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promotion_redemptions_reservation_uuid ON promotion_redemptions (reservation_uuid);
+
+CREATE TABLE IF NOT EXISTS reservations (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    guest_uuid text,
+    hotel_uuid text,
+    room_type_uuid text,
+    rate_plan_uuid text,
+    start_date timestamptz NOT NULL,
+    end_date timestamptz NOT NULL,
+    promo_code text,
+    promotion_uuid text,
+    discount bigint,
+    total_price bigint,
+    status text,
+    confirmation_code text,
+    expires_at timestamptz,
+    arrival_reminder_at timestamptz,
+    redeemed_points bigint,
+    CONSTRAINT fk_reservations_guest FOREIGN KEY (guest_uuid) REFERENCES guests (uuid),
+    CONSTRAINT fk_reservations_hotel FOREIGN KEY (hotel_uuid) REFERENCES hotels (uuid),
+    CONSTRAINT fk_reservations_room_type FOREIGN KEY (room_type_uuid) REFERENCES room_types (uuid),
+    CONSTRAINT fk_reservations_rate_plan FOREIGN KEY (rate_plan_uuid) REFERENCES rate_plans (uuid),
+    CONSTRAINT fk_reservations_promotion FOREIGN KEY (promotion_uuid) REFERENCES promotions (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_reservations_deleted_at ON reservations (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_reservations_expires_at ON reservations (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reservations_confirmation_code ON reservations (confirmation_code)
+    WHERE confirmation_code <> '';
+
+CREATE TABLE IF NOT EXISTS payments (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    reservation_uuid text,
+    amount bigint,
+    currency text,
+    status text,
+    gateway_reference text,
+    captured_amount bigint,
+    refunded_amount bigint,
+    failure_reason text,
+    CONSTRAINT fk_payments_reservation FOREIGN KEY (reservation_uuid) REFERENCES reservations (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_payments_deleted_at ON payments (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_reservation_uuid ON payments (reservation_uuid);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    aggregate_uuid text NOT NULL,
+    hotel_uuid text,
+    type text NOT NULL,
+    payload jsonb NOT NULL,
+    occurred_at timestamptz,
+    published_at timestamptz,
+    attempts bigint,
+    last_error text
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_deleted_at ON outbox_events (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_aggregate_uuid ON outbox_events (aggregate_uuid);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_hotel_uuid ON outbox_events (hotel_uuid);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events (published_at);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    hotel_uuid text NOT NULL,
+    url text NOT NULL,
+    secret text NOT NULL,
+    event_types text
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_hotel_uuid ON webhook_subscriptions (hotel_uuid);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    subscription_uuid text NOT NULL,
+    event_uuid text NOT NULL,
+    event_type text,
+    status text,
+    attempts bigint,
+    next_attempt_at timestamptz,
+    last_status_code bigint,
+    last_error text,
+    delivered_at timestamptz,
+    CONSTRAINT fk_webhook_deliveries_subscription FOREIGN KEY (subscription_uuid) REFERENCES webhook_subscriptions (uuid),
+    CONSTRAINT fk_webhook_deliveries_event FOREIGN KEY (event_uuid) REFERENCES outbox_events (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_event ON webhook_deliveries (subscription_uuid, event_uuid);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    guest_uuid text NOT NULL,
+    hotel_uuid text NOT NULL,
+    room_type_uuid text NOT NULL,
+    start_date date NOT NULL,
+    end_date date NOT NULL,
+    status text NOT NULL,
+    reservation_uuid text,
+    offered_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_deleted_at ON waitlist_entries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_guest_uuid ON waitlist_entries (guest_uuid);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_room_type_status ON waitlist_entries (room_type_uuid, status);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_reservation_uuid ON waitlist_entries (reservation_uuid);
+
+CREATE TABLE IF NOT EXISTS bookings (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    guest_uuid text,
+    hotel_uuid text,
+    group_name text,
+    start_date timestamptz NOT NULL,
+    end_date timestamptz NOT NULL,
+    status text,
+    release_date timestamptz,
+    total_price bigint,
+    CONSTRAINT fk_bookings_guest FOREIGN KEY (guest_uuid) REFERENCES guests (uuid),
+    CONSTRAINT fk_bookings_hotel FOREIGN KEY (hotel_uuid) REFERENCES hotels (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_bookings_deleted_at ON bookings (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_bookings_hotel_uuid ON bookings (hotel_uuid);
+CREATE INDEX IF NOT EXISTS idx_bookings_release_date ON bookings (release_date);
+
+CREATE TABLE IF NOT EXISTS booking_lines (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    booking_uuid text NOT NULL,
+    room_type_uuid text,
+    rate_plan_uuid text,
+    quantity bigint,
+    occupants bigint,
+    unit_price bigint,
+    total_price bigint,
+    status text,
+    CONSTRAINT fk_bookings_lines FOREIGN KEY (booking_uuid) REFERENCES bookings (uuid),
+    CONSTRAINT fk_booking_lines_room_type FOREIGN KEY (room_type_uuid) REFERENCES room_types (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_booking_lines_deleted_at ON booking_lines (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_booking_lines_booking_uuid ON booking_lines (booking_uuid);
+
+CREATE TABLE IF NOT EXISTS loyalty_entries (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    guest_uuid text NOT NULL,
+    reservation_uuid text NOT NULL,
+    type text NOT NULL,
+    points bigint
+);
+CREATE INDEX IF NOT EXISTS idx_loyalty_entries_deleted_at ON loyalty_entries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_loyalty_entries_guest_uuid ON loyalty_entries (guest_uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_entries_reservation_type ON loyalty_entries (reservation_uuid, type);
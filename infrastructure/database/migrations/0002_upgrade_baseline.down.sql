@@ -0,0 +1 @@
+-- The columns and indexes this adds are part of the schema 0001 creates, reverting 0001 reverts them
@@ -0,0 +1,50 @@
+-- Databases created by AutoMigrate before versioned migrations already had guests, hotels, room_types,
+-- rooms, rates and reservations, so 0001 skipped those tables there. This brings them up to the schema
+-- 0001 creates. On a database 0001 created every statement is a no-op
+
+ALTER TABLE hotels ADD COLUMN IF NOT EXISTS email text;
+ALTER TABLE hotels ADD COLUMN IF NOT EXISTS logo_url text;
+ALTER TABLE hotels ADD COLUMN IF NOT EXISTS brand_color text;
+UPDATE hotels SET email = '' WHERE email IS NULL;
+UPDATE hotels SET logo_url = '' WHERE logo_url IS NULL;
+UPDATE hotels SET brand_color = '' WHERE brand_color IS NULL;
+
+ALTER TABLE room_types ADD COLUMN IF NOT EXISTS overbooking_type text;
+ALTER TABLE room_types ADD COLUMN IF NOT EXISTS overbooking_value bigint;
+UPDATE room_types SET overbooking_type = '' WHERE overbooking_type IS NULL;
+UPDATE room_types SET overbooking_value = 0 WHERE overbooking_value IS NULL;
+
+-- rates were dated with a timestamp, a night is a date in the database's time zone. Duplicate nights of
+-- a room type have to be removed by hand before the unique index can be built
+ALTER TABLE rates ALTER COLUMN date TYPE date USING date::date;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rates_hotel_room_type_date ON rates (hotel_uuid, room_type_uuid, date);
+
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS rate_plan_uuid text;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS promo_code text;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS promotion_uuid text;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS discount bigint;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS total_price bigint;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS confirmation_code text;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS arrival_reminder_at timestamptz;
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS redeemed_points bigint;
+UPDATE reservations SET promo_code = '' WHERE promo_code IS NULL;
+UPDATE reservations SET discount = 0 WHERE discount IS NULL;
+UPDATE reservations SET total_price = 0 WHERE total_price IS NULL;
+UPDATE reservations SET confirmation_code = '' WHERE confirmation_code IS NULL;
+UPDATE reservations SET redeemed_points = 0 WHERE redeemed_points IS NULL;
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_reservations_rate_plan') THEN
+        ALTER TABLE reservations ADD CONSTRAINT fk_reservations_rate_plan
+            FOREIGN KEY (rate_plan_uuid) REFERENCES rate_plans (uuid);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_reservations_promotion') THEN
+        ALTER TABLE reservations ADD CONSTRAINT fk_reservations_promotion
+            FOREIGN KEY (promotion_uuid) REFERENCES promotions (uuid);
+    END IF;
+END
+$$;
+CREATE INDEX IF NOT EXISTS idx_reservations_expires_at ON reservations (expires_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reservations_confirmation_code ON reservations (confirmation_code)
+    WHERE confirmation_code <> '';
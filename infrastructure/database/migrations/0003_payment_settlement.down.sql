@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_payments_settle_at;
+ALTER TABLE payments DROP COLUMN IF EXISTS settlement_error;
+ALTER TABLE payments DROP COLUMN IF EXISTS settlement_attempts;
+ALTER TABLE payments DROP COLUMN IF EXISTS settle_at;
+ALTER TABLE payments DROP COLUMN IF EXISTS refund_due;
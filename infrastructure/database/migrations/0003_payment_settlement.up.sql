@@ -0,0 +1,7 @@
+-- A cancellation records the refund it owes in its own transaction, the payment settler makes the
+-- refunds the gateway failed to make
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS refund_due bigint DEFAULT 0;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS settle_at timestamptz;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS settlement_attempts bigint DEFAULT 0;
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS settlement_error text DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_payments_settle_at ON payments (settle_at);
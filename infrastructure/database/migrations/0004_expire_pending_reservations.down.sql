@@ -0,0 +1 @@
+-- The expiries given to PENDING reservations are left, the reservations they released stay released
@@ -0,0 +1,5 @@
+-- PENDING reservations lapse like holds do once their payment isn't authorized in time. The ones left
+-- PENDING before they had an expiry are given one, so that the reaper releases their inventory
+UPDATE reservations
+SET expires_at = COALESCE(updated_at, created_at, now()) + interval '10 minutes'
+WHERE status = 'PENDING' AND expires_at IS NULL;
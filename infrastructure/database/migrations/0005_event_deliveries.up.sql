@@ -0,0 +1,21 @@
+-- Every consumer of the outbox gets a delivery of each event, so that it is retried on its own
+CREATE TABLE IF NOT EXISTS event_deliveries (
+    uuid text PRIMARY KEY,
+    active boolean DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    consumer text NOT NULL,
+    event_uuid text NOT NULL,
+    occurred_at timestamptz,
+    status text,
+    attempts bigint,
+    next_attempt_at timestamptz,
+    last_error text,
+    delivered_at timestamptz,
+    CONSTRAINT fk_event_deliveries_event FOREIGN KEY (event_uuid) REFERENCES outbox_events (uuid)
+);
+CREATE INDEX IF NOT EXISTS idx_event_deliveries_deleted_at ON event_deliveries (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_deliveries_consumer_event ON event_deliveries (consumer, event_uuid);
+CREATE INDEX IF NOT EXISTS idx_event_deliveries_status ON event_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_event_deliveries_next_attempt_at ON event_deliveries (next_attempt_at);
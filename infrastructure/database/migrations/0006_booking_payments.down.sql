@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_payments_booking_uuid;
+ALTER TABLE payments DROP COLUMN IF EXISTS booking_uuid;
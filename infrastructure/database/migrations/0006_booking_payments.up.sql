@@ -0,0 +1,4 @@
+-- Multi-room bookings are paid for like reservations, a payment belongs to a reservation or a booking
+ALTER TABLE payments ADD COLUMN IF NOT EXISTS booking_uuid text
+    CONSTRAINT fk_payments_booking REFERENCES bookings (uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_booking_uuid ON payments (booking_uuid);
@@ -0,0 +1,4 @@
+-- The index can't hold several adjustments of a reservation, the points they gave back are taken again
+DELETE FROM loyalty_entries WHERE type = 'ADJUSTMENT';
+DROP INDEX IF EXISTS idx_loyalty_entries_reservation_type;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_entries_reservation_type ON loyalty_entries (reservation_uuid, type);
@@ -0,0 +1,5 @@
+-- A modified reservation whose lower price needs fewer of its redeemed points is given the rest back
+-- with an ADJUSTMENT entry, one per modification, the other entry types stay one per reservation
+DROP INDEX IF EXISTS idx_loyalty_entries_reservation_type;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loyalty_entries_reservation_type ON loyalty_entries (reservation_uuid, type)
+    WHERE type <> 'ADJUSTMENT';
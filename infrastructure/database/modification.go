@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ModifyReservation moves a reservation to the dates, room type and price of modified in one transaction,
+// and reprices its payment in it, see domain.Payment.Reprice. A higher price is paid by the authorization
+// given by reference, the reference of the authorization it replaced is returned to be voided.
+// The old nights are released before the new ones are claimed so that a stay can be shifted within a
+// fully booked room type, and nothing changes when the new nights are sold out or the payment can't be
+// repriced. Redeemed points the new price no longer needs are given back to the guest
+func (p *PostgresDB) ModifyReservation(
+	ctx context.Context,
+	modified *domain.Reservation,
+	authorization string,
+) (*domain.Reservation, string, error) {
+	var reservation domain.Reservation
+	var replaced string
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&domain.Reservation{
+			AbstractBase: domain.AbstractBase{UUID: modified.UUID},
+			Status:       modified.Status,
+		}).First(&reservation).Error; err != nil {
+			return fmt.Errorf("no %s reservation %s: %w", modified.Status, modified.UUID, err)
+		}
+		oldNights := domain.StayNights(reservation.StartDate, reservation.EndDate)
+		if err := releaseInventory(tx, reservation.RoomTypeUUID, oldNights, 1); err != nil {
+			return err
+		}
+		newNights := domain.StayNights(modified.StartDate, modified.EndDate)
+		if err := claimInventory(tx, modified.RoomTypeUUID, newNights, 1); err != nil {
+			return err
+		}
+
+		previous := reservation
+		now := time.Now()
+		reservation.RoomTypeUUID = modified.RoomTypeUUID
+		reservation.RatePlanUUID = modified.RatePlanUUID
+		reservation.StartDate = modified.StartDate
+		reservation.EndDate = modified.EndDate
+		reservation.TotalPrice = modified.TotalPrice
+		reservation.Discount = modified.Discount
+		reservation.RedeemedPoints = modified.RedeemedPoints
+		reservation.UpdatedAt = &now
+		if err := tx.Model(&reservation).Select(
+			"room_type_uuid", "rate_plan_uuid", "start_date", "end_date",
+			"total_price", "discount", "redeemed_points", "updated_at",
+		).Updates(&reservation).Error; err != nil {
+			return err
+		}
+		if err := refundLoyaltyPoints(tx, &reservation, previous.RedeemedPoints); err != nil {
+			return err
+		}
+		var err error
+		if replaced, err = repricePayment(tx, &reservation, authorization, now); err != nil {
+			return err
+		}
+		event, err := domain.NewReservationModifiedEvent(&reservation, &previous, now)
+		if err != nil {
+			return err
+		}
+		return storeEvent(tx, event)
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("infrastructure: can't modify reservation: %w", err)
+	}
+	return &reservation, replaced, nil
+}
+
+// repricePayment adjusts the payment of a modified reservation to its new price within the modification's
+// transaction. Reservations that aren't paid for yet, such as holds, have no payment
+func repricePayment(
+	tx *gorm.DB,
+	reservation *domain.Reservation,
+	authorization string,
+	at time.Time,
+) (string, error) {
+	payment, err := lockPayment(tx, "reservation_uuid = ?", reservation.UUID)
+	if err != nil {
+		return "", err
+	}
+	if payment == nil {
+		if authorization != "" {
+			return "", fmt.Errorf("reservation %s has no payment to authorize its new price for", reservation.UUID)
+		}
+		return "", nil
+	}
+	replaced, err := payment.Reprice(reservation.TotalPrice, authorization, at)
+	if err != nil {
+		return "", err
+	}
+	payment.UpdatedAt = &at
+	if err := tx.Model(payment).Select(
+		"amount", "status", "gateway_reference", "captured_amount",
+		"refund_due", "settle_at", "settlement_attempts", "settlement_error", "updated_at",
+	).Updates(payment).Error; err != nil {
+		return "", err
+	}
+	return replaced, nil
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetGuest fetches a guest by their UUID
+func (p *PostgresDB) GetGuest(
+	ctx context.Context,
+	GuestUUID string,
+) (*domain.Guest, error) {
+	var guest domain.Guest
+	if err := p.DB.WithContext(ctx).Where(&domain.Guest{
+		AbstractBase: domain.AbstractBase{UUID: GuestUUID},
+	}).Find(&guest).Error; err != nil {
+		return nil, err
+	}
+	if guest.UUID == "" {
+		return nil, nil
+	}
+	return &guest, nil
+}
+
+// GetHotel fetches a hotel by its UUID
+func (p *PostgresDB) GetHotel(
+	ctx context.Context,
+	HotelUUID string,
+) (*domain.Hotel, error) {
+	var hotel domain.Hotel
+	if err := p.DB.WithContext(ctx).Where(&domain.Hotel{
+		AbstractBase: domain.AbstractBase{UUID: HotelUUID},
+	}).Find(&hotel).Error; err != nil {
+		return nil, err
+	}
+	if hotel.UUID == "" {
+		return nil, nil
+	}
+	return &hotel, nil
+}
+
+// RecordArrivalReminders records an arrival due event for up to limit RESERVED stays starting on the
+// arrival date whose guests haven't been reminded yet. Stays locked by another replica are skipped
+func (p *PostgresDB) RecordArrivalReminders(
+	ctx context.Context,
+	arrivalDate time.Time,
+	limit int,
+) (int, error) {
+	var reservations []domain.Reservation
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND start_date::date = ? AND arrival_reminder_at IS NULL",
+				string(domain.RESERVED), arrivalDate.Format(domain.DateLayout)).
+			Limit(limit).
+			Find(&reservations).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		for i := range reservations {
+			reservation := &reservations[i]
+			reservation.ArrivalReminderAt = &now
+			if err := tx.Model(reservation).Update("arrival_reminder_at", now).Error; err != nil {
+				return err
+			}
+			if err := recordEvent(tx, domain.RESERVATION_ARRIVAL_DUE, reservation); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("infrastructure: can't record arrival reminders: %w", err)
+	}
+	return len(reservations), nil
+}
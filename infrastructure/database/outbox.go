@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordEvent writes a reservation event to the outbox within the transaction that made the change
+func recordEvent(
+	tx *gorm.DB,
+	eventType domain.EventType,
+	reservation *domain.Reservation,
+) error {
+	event, err := domain.NewReservationEvent(eventType, reservation, time.Now())
+	if err != nil {
+		return err
+	}
+	return storeEvent(tx, event)
+}
+
+// recordBookingEvent writes a booking event to the outbox within the transaction that made the change
+func recordBookingEvent(
+	tx *gorm.DB,
+	eventType domain.EventType,
+	booking *domain.Booking,
+) error {
+	event, err := domain.NewBookingEvent(eventType, booking, time.Now())
+	if err != nil {
+		return err
+	}
+	return storeEvent(tx, event)
+}
+
+// storeEvent writes an event built by the caller to the outbox within the transaction that made the change
+func storeEvent(tx *gorm.DB, event *domain.OutboxEvent) error {
+	if err := tx.Create(event).Error; err != nil {
+		return fmt.Errorf("can't record %s event: %w", event.Type, err)
+	}
+	return nil
+}
+
+// RelayOutboxEvents queues up to limit unpublished events, oldest first, for delivery to every consumer
+// and marks them published. Events locked by another relay are skipped, so relays on several replicas
+// don't queue the same event concurrently, and a delivery already queued for a consumer isn't queued
+// again. No consumer runs in the relay's transaction: each one claims its deliveries with
+// ClaimEventDeliveries, so a failing consumer doesn't hold back the outbox or the other consumers
+func (p *PostgresDB) RelayOutboxEvents(
+	ctx context.Context,
+	limit int,
+	consumers []string,
+) (int, error) {
+	var events []domain.OutboxEvent
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL").
+			Order("occurred_at, created_at").
+			Limit(limit).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		now := time.Now()
+		deliveries := make([]*domain.EventDelivery, 0, len(events)*len(consumers))
+		eventUUIDs := make([]string, 0, len(events))
+		for _, event := range events {
+			eventUUIDs = append(eventUUIDs, event.UUID)
+			for _, consumer := range consumers {
+				deliveries = append(deliveries, &domain.EventDelivery{
+					Consumer:      consumer,
+					EventUUID:     event.UUID,
+					OccurredAt:    event.OccurredAt,
+					Status:        domain.DELIVERY_PENDING,
+					NextAttemptAt: now,
+				})
+			}
+		}
+		if len(deliveries) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "consumer"}, {Name: "event_uuid"}},
+				DoNothing: true,
+			}).Create(&deliveries).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&domain.OutboxEvent{}).Where("uuid IN ?", eventUUIDs).Updates(map[string]interface{}{
+			"published_at": now,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   "",
+		}).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("infrastructure: can't relay outbox events: %w", err)
+	}
+	return len(events), nil
+}
+
+// ClaimEventDeliveries claims up to limit pending deliveries of a consumer that are due, oldest event
+// first, by putting their next attempt off for the domain.EventDeliveryLease. The claim is committed
+// before the events are consumed, so no row stays locked while the consumer runs, and the deliveries of
+// a consumer that dies are claimed again once the lease is over
+func (p *PostgresDB) ClaimEventDeliveries(
+	ctx context.Context,
+	consumer string,
+	now time.Time,
+	limit int,
+) ([]domain.EventDelivery, error) {
+	var deliveries []domain.EventDelivery
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("consumer = ? AND status = ? AND next_attempt_at <= ?", consumer, string(domain.DELIVERY_PENDING), now).
+			Order("next_attempt_at, occurred_at").
+			Limit(limit).
+			Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+		deliveryUUIDs := make([]string, 0, len(deliveries))
+		eventUUIDs := make([]string, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			deliveryUUIDs = append(deliveryUUIDs, delivery.UUID)
+			eventUUIDs = append(eventUUIDs, delivery.EventUUID)
+		}
+		leasedUntil := now.Add(domain.EventDeliveryLease)
+		if err := tx.Model(&domain.EventDelivery{}).Where("uuid IN ?", deliveryUUIDs).
+			Update("next_attempt_at", leasedUntil).Error; err != nil {
+			return err
+		}
+		var events []domain.OutboxEvent
+		if err := tx.Where("uuid IN ?", eventUUIDs).Find(&events).Error; err != nil {
+			return err
+		}
+		eventsByUUID := make(map[string]*domain.OutboxEvent, len(events))
+		for i := range events {
+			eventsByUUID[events[i].UUID] = &events[i]
+		}
+		for i := range deliveries {
+			deliveries[i].NextAttemptAt = leasedUntil
+			deliveries[i].Event = eventsByUUID[deliveries[i].EventUUID]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't claim event deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RecordEventDelivery saves the outcome of handing an event to its consumer
+func (p *PostgresDB) RecordEventDelivery(
+	ctx context.Context,
+	delivery *domain.EventDelivery,
+) error {
+	now := time.Now()
+	delivery.UpdatedAt = &now
+	if err := p.DB.WithContext(ctx).Model(delivery).Select(
+		"status", "attempts", "next_attempt_at", "last_error", "delivered_at", "updated_at",
+	).Updates(delivery).Error; err != nil {
+		return fmt.Errorf("infrastructure: can't record event delivery: %w", err)
+	}
+	return nil
+}
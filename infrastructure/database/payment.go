@@ -0,0 +1,279 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetReservation fetches a reservation by its UUID
+func (p *PostgresDB) GetReservation(
+	ctx context.Context,
+	ReservationUUID string,
+) (*domain.Reservation, error) {
+	var reservation domain.Reservation
+	if err := p.DB.WithContext(ctx).Where(&domain.Reservation{
+		AbstractBase: domain.AbstractBase{UUID: ReservationUUID},
+	}).Find(&reservation).Error; err != nil {
+		return nil, err
+	}
+	if reservation.UUID == "" {
+		return nil, nil
+	}
+	return &reservation, nil
+}
+
+// GetPayment fetches the payment made for a reservation
+func (p *PostgresDB) GetPayment(
+	ctx context.Context,
+	ReservationUUID string,
+) (*domain.Payment, error) {
+	var payment domain.Payment
+	if err := p.DB.WithContext(ctx).Where("reservation_uuid = ?", ReservationUUID).Find(&payment).Error; err != nil {
+		return nil, err
+	}
+	if payment.UUID == "" {
+		return nil, nil
+	}
+	return &payment, nil
+}
+
+// GetBookingPayment fetches the payment made for a multi-room booking
+func (p *PostgresDB) GetBookingPayment(
+	ctx context.Context,
+	BookingUUID string,
+) (*domain.Payment, error) {
+	var payment domain.Payment
+	if err := p.DB.WithContext(ctx).Where("booking_uuid = ?", BookingUUID).Find(&payment).Error; err != nil {
+		return nil, err
+	}
+	if payment.UUID == "" {
+		return nil, nil
+	}
+	return &payment, nil
+}
+
+// ConfirmReservationPayment records an authorized payment and moves its pending reservation to RESERVED
+func (p *PostgresDB) ConfirmReservationPayment(
+	ctx context.Context,
+	payment *domain.Payment,
+) (*domain.Reservation, error) {
+	var reservation domain.Reservation
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingReservation(tx, *payment.ReservationUUID, &reservation); err != nil {
+			return err
+		}
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+		// a confirmed reservation no longer lapses
+		now := time.Now()
+		reservation.Status = string(domain.RESERVED)
+		reservation.ExpiresAt = nil
+		reservation.UpdatedAt = &now
+		if err := tx.Model(&reservation).Select("status", "expires_at", "updated_at").Updates(&reservation).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, domain.RESERVATION_CREATED, &reservation)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't confirm reservation payment: %w", err)
+	}
+	return &reservation, nil
+}
+
+// FailReservationPayment records a declined payment, moves its pending reservation to PAYMENT_FAILED
+// and releases the inventory and promotion the reservation had claimed
+func (p *PostgresDB) FailReservationPayment(
+	ctx context.Context,
+	payment *domain.Payment,
+) (*domain.Reservation, error) {
+	var reservation domain.Reservation
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockPendingReservation(tx, *payment.ReservationUUID, &reservation); err != nil {
+			return err
+		}
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+		if err := setReservationStatus(tx, &reservation, domain.PAYMENT_FAILED); err != nil {
+			return err
+		}
+		nights := domain.StayNights(reservation.StartDate, reservation.EndDate)
+		if err := releaseInventory(tx, reservation.RoomTypeUUID, nights, 1); err != nil {
+			return err
+		}
+		if err := releasePromotion(tx, &reservation); err != nil {
+			return err
+		}
+		return recordEvent(tx, domain.RESERVATION_PAYMENT_FAILED, &reservation)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't fail reservation payment: %w", err)
+	}
+	return &reservation, nil
+}
+
+// UpdatePayment saves a payment's status, amounts and settlement
+func (p *PostgresDB) UpdatePayment(
+	ctx context.Context,
+	payment *domain.Payment,
+) (*domain.Payment, error) {
+	now := time.Now()
+	payment.UpdatedAt = &now
+	if err := p.DB.WithContext(ctx).Model(payment).Select(
+		"status", "captured_amount", "refunded_amount", "failure_reason",
+		"refund_due", "settle_at", "settlement_attempts", "settlement_error", "updated_at",
+	).Updates(payment).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't update payment: %w", err)
+	}
+	return payment, nil
+}
+
+// ClaimDuePayments claims up to limit payments that were due to be settled before now, oldest first, by
+// putting their next settlement off for the domain.PaymentSettlementLease. The claim is committed before
+// the payments are settled with the gateway, so no row stays locked while the gateway is called, and a
+// settler that dies leaves its payments to be claimed again once the lease is over
+func (p *PostgresDB) ClaimDuePayments(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]domain.Payment, error) {
+	var payments []domain.Payment
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("settle_at <= ?", now).
+			Order("settle_at").
+			Limit(limit).
+			Find(&payments).Error; err != nil {
+			return err
+		}
+		settleAt := now.Add(domain.PaymentSettlementLease)
+		for i := range payments {
+			payments[i].SettleAt = &settleAt
+			if err := tx.Model(&payments[i]).Select("settle_at").Updates(&payments[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't claim due payments: %w", err)
+	}
+	return payments, nil
+}
+
+// lockPendingReservation loads a PENDING reservation, locking its row until the transaction ends
+func lockPendingReservation(
+	tx *gorm.DB,
+	ReservationUUID string,
+	reservation *domain.Reservation,
+) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&domain.Reservation{
+		AbstractBase: domain.AbstractBase{UUID: ReservationUUID},
+		Status:       string(domain.PENDING),
+	}).First(reservation).Error
+}
+
+// setReservationStatus moves a reservation to a new status
+func setReservationStatus(
+	tx *gorm.DB,
+	reservation *domain.Reservation,
+	status domain.ReservationStatus,
+) error {
+	now := time.Now()
+	reservation.Status = string(status)
+	reservation.UpdatedAt = &now
+	return tx.Model(reservation).Select("status", "updated_at").Updates(reservation).Error
+}
+
+// oweCancellationRefund records the refund owed on the payment of a reservation cancelled at the given
+// time, in the cancellation's transaction so that a refund the gateway fails to make isn't lost
+func oweCancellationRefund(
+	tx *gorm.DB,
+	reservation *domain.Reservation,
+	cancelledAt time.Time,
+) error {
+	payment, err := lockPayment(tx, "reservation_uuid = ?", reservation.UUID)
+	if err != nil || payment == nil {
+		return err
+	}
+	ratePlan, err := findRatePlan(tx, reservation.RatePlanUUID)
+	if err != nil {
+		return err
+	}
+	refund := domain.RefundAmount(reservation, ratePlan, payment, cancelledAt)
+	return oweRefund(tx, payment, refund, cancelledAt)
+}
+
+// oweBookingLineRefund records the refund owed on the payment of a booking whose line was cancelled at
+// the given time, in the cancellation's transaction. Group blocks that were never confirmed have no payment
+func oweBookingLineRefund(
+	tx *gorm.DB,
+	booking *domain.Booking,
+	line *domain.BookingLine,
+	cancelledAt time.Time,
+) error {
+	payment, err := lockPayment(tx, "booking_uuid = ?", booking.UUID)
+	if err != nil || payment == nil {
+		return err
+	}
+	ratePlan, err := findRatePlan(tx, line.RatePlanUUID)
+	if err != nil {
+		return err
+	}
+	refund := domain.BookingLineRefund(booking, line, ratePlan, cancelledAt)
+	return oweRefund(tx, payment, refund, cancelledAt)
+}
+
+// lockPayment loads the payment matching a condition, locking its row until the transaction ends.
+// It returns nil when there is no such payment
+func lockPayment(tx *gorm.DB, query string, args ...interface{}) (*domain.Payment, error) {
+	var payment domain.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(query, args...).
+		Find(&payment).Error; err != nil {
+		return nil, err
+	}
+	if payment.UUID == "" {
+		return nil, nil
+	}
+	return &payment, nil
+}
+
+// findRatePlan loads the rate plan a stay was booked on, it returns nil for stays booked without one
+func findRatePlan(tx *gorm.DB, RatePlanUUID *string) (*domain.RatePlan, error) {
+	if RatePlanUUID == nil {
+		return nil, nil
+	}
+	var ratePlan domain.RatePlan
+	if err := tx.Where(&domain.RatePlan{
+		AbstractBase: domain.AbstractBase{UUID: *RatePlanUUID},
+	}).Find(&ratePlan).Error; err != nil {
+		return nil, err
+	}
+	if ratePlan.UUID == "" {
+		return nil, nil
+	}
+	return &ratePlan, nil
+}
+
+// oweRefund saves the refund owed on a payment, see domain.Payment.OweRefund
+func oweRefund(
+	tx *gorm.DB,
+	payment *domain.Payment,
+	refund int,
+	at time.Time,
+) error {
+	if !payment.OweRefund(refund, at) {
+		return nil
+	}
+	payment.UpdatedAt = &at
+	return tx.Model(payment).Select(
+		"refund_due", "settle_at", "settlement_attempts", "settlement_error", "updated_at",
+	).Updates(payment).Error
+}
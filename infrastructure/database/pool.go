@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/metrics"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/tracing"
+)
+
+// maxConnectBackoff caps the wait between two attempts to reach the database
+const maxConnectBackoff = 30 * time.Second
+
+// slowQueryThreshold is how long a query takes before it is logged as slow
+const slowQueryThreshold = 200 * time.Millisecond
+
+// Connect opens a connection pool on the database cfg describes and waits until the database answers,
+// retrying cfg.ConnectRetries times with an exponential backoff starting at cfg.ConnectBackoff
+func Connect(ctx context.Context, cfg config.DatabaseConfig) (*gorm.DB, error) {
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
+		cfg.Host,
+		cfg.User,
+		cfg.Password,
+		cfg.Name,
+		cfg.Port,
+		cfg.SSLMode,
+		cfg.TimeZone,
+	)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		DisableAutomaticPing: true,
+		// queries are logged with the request they were made for
+		Logger: logging.NewGormLogger(slowQueryThreshold),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't open connection to postgres database: %w", err)
+	}
+	for _, plugin := range []gorm.Plugin{metrics.GormPlugin{}, tracing.GormPlugin{}} {
+		if err := db.Use(plugin); err != nil {
+			return nil, fmt.Errorf("can't instrument database queries with %s: %w", plugin.Name(), err)
+		}
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("can't get the connection pool: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := pingWithRetry(ctx, sqlDB, cfg.ConnectRetries, cfg.ConnectBackoff); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// pingWithRetry pings the database until it answers, waiting twice as long after every failed attempt
+func pingWithRetry(ctx context.Context, db *sql.DB, retries int, backoff time.Duration) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = db.PingContext(ctx); err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			break
+		}
+		log.Warnf("can't reach postgres database, retrying in %s (attempt %d of %d): %v", backoff, attempt+1, retries+1, err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("can't reach postgres database: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+	return fmt.Errorf("can't reach postgres database after %d attempt(s): %w", retries+1, err)
+}
+
+// Stats returns the statistics of the database's connection pool
+func (p *PostgresDB) Stats() (sql.DBStats, error) {
+	sqlDB, err := p.DB.DB()
+	if err != nil {
+		return sql.DBStats{}, fmt.Errorf("infrastructure: can't get the connection pool: %v", err)
+	}
+	return sqlDB.Stats(), nil
+}
+
+// SQLDB returns the database's connection pool
+func (p *PostgresDB) SQLDB() (*sql.DB, error) {
+	return p.DB.DB()
+}
+
+// Ping checks that the database answers
+func (p *PostgresDB) Ping(ctx context.Context) error {
+	sqlDB, err := p.DB.DB()
+	if err != nil {
+		return fmt.Errorf("infrastructure: can't get the connection pool: %v", err)
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close closes the database's connection pool
+func (p *PostgresDB) Close() error {
+	sqlDB, err := p.DB.DB()
+	if err != nil {
+		return fmt.Errorf("infrastructure: can't get the connection pool: %v", err)
+	}
+	return sqlDB.Close()
+}
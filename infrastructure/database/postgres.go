@@ -3,15 +3,20 @@ package database
 import (
 	"context"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
 	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// rateUpsertBatchSize keeps a single upsert statement well below postgres' bind parameter limit
+const rateUpsertBatchSize = 1000
+
 // PostgresDB sets up a Postgresql database layer within the service
 type PostgresDB struct {
 	DB *gorm.DB
@@ -24,51 +29,27 @@ func (p *PostgresDB) Checkpreconditions() {
 	}
 }
 
-// NewPostgresDB initializes a new postgres db instance
-func NewPostgresDB() *PostgresDB {
-	db := PostgresDB{
-		DB: Init(),
-	}
-	db.Checkpreconditions()
-	return &db
-}
-
-// Migrate runs the databas's migrations
-func Migrate(db *gorm.DB) {
-	tables := []interface{}{
-		&domain.Guest{},
-		&domain.Hotel{},
-		&domain.RoomType{},
-		&domain.Room{},
-		&domain.Rate{},
-		&domain.Reservation{},
-	}
-	for _, table := range tables {
-		if err := db.AutoMigrate(table); err != nil {
-			log.Panicf("can't run migrations on table %v: err: %v", table, err)
-		}
-	}
-}
-
-// Init initializes a new gorm DB instance by connecting to the database specified
-func Init() *gorm.DB {
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Africa/Nairobi",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_PORT"),
-	)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// NewPostgresDB connects to the database cfg describes and checks that its schema is at the version of
+// the embedded migrations, see MigrateUp. A single PostgresDB and its connection pool is meant to be shared
+func NewPostgresDB(ctx context.Context, cfg config.DatabaseConfig) (*PostgresDB, error) {
+	db, err := Connect(ctx, cfg)
 	if err != nil {
-		log.Fatalf("can't open connection to postgres database: %v", err)
+		return nil, err
 	}
 	log.Info("Database connected successfully.")
-	Migrate(db)
-	log.Info("Database migrations ran successfully.")
-	return db
+	if err := CheckSchemaVersion(ctx, db); err != nil {
+		return nil, fmt.Errorf("database schema check failed: %w", err)
+	}
+	// the repositories' queries are bounded, migrations run on a connection of their own and take as long
+	// as they need
+	if err := db.Use(TimeoutPlugin{Read: cfg.ReadTimeout, Write: cfg.WriteTimeout}); err != nil {
+		return nil, fmt.Errorf("can't bound database queries: %w", err)
+	}
+	p := PostgresDB{
+		DB: db,
+	}
+	p.Checkpreconditions()
+	return &p, nil
 }
 
 // GetReservations fetches all reservations from the database
@@ -76,7 +57,7 @@ func (p *PostgresDB) GetReservations(
 	ctx context.Context,
 ) ([]domain.Reservation, error) {
 	var reservations []domain.Reservation
-	if err := p.DB.Where(&domain.Reservation{}).Find(&reservations).Error; err != nil {
+	if err := p.DB.WithContext(ctx).Where(&domain.Reservation{}).Find(&reservations).Error; err != nil {
 		return nil, err
 	}
 
@@ -88,7 +69,7 @@ func (p *PostgresDB) GetHotels(
 	ctx context.Context,
 ) ([]domain.Hotel, error) {
 	var hotels []domain.Hotel
-	if err := p.DB.Where(&domain.Hotel{}).Find(&hotels).Error; err != nil {
+	if err := p.DB.WithContext(ctx).Where(&domain.Hotel{}).Find(&hotels).Error; err != nil {
 		return nil, err
 	}
 	return hotels, nil
@@ -99,7 +80,7 @@ func (p *PostgresDB) GetRooms(
 	ctx context.Context,
 ) ([]domain.Room, error) {
 	var rooms []domain.Room
-	if err := p.DB.Where(&domain.Room{}).Find(&rooms).Error; err != nil {
+	if err := p.DB.WithContext(ctx).Where(&domain.Room{}).Find(&rooms).Error; err != nil {
 		return nil, err
 	}
 	return rooms, nil
@@ -110,7 +91,7 @@ func (p *PostgresDB) GetGuests(
 	ctx context.Context,
 ) ([]domain.Guest, error) {
 	var guests []domain.Guest
-	if err := p.DB.Where(&domain.Guest{}).Find(&guests).Error; err != nil {
+	if err := p.DB.WithContext(ctx).Where(&domain.Guest{}).Find(&guests).Error; err != nil {
 		return nil, err
 	}
 	return guests, nil
@@ -121,7 +102,7 @@ func (p *PostgresDB) GetRoomTypes(
 	ctx context.Context,
 ) ([]domain.RoomType, error) {
 	var roomTypes []domain.RoomType
-	if err := p.DB.Where(&domain.RoomType{}).Find(&roomTypes).Error; err != nil {
+	if err := p.DB.WithContext(ctx).Where(&domain.RoomType{}).Find(&roomTypes).Error; err != nil {
 		return nil, err
 	}
 	return roomTypes, nil
@@ -132,12 +113,29 @@ func (p *PostgresDB) GetRates(
 	ctx context.Context,
 ) ([]domain.Rate, error) {
 	var rates []domain.Rate
-	if err := p.DB.Where(&domain.Rate{}).Find(&rates).Error; err != nil {
+	if err := p.DB.WithContext(ctx).Where(&domain.Rate{}).Find(&rates).Error; err != nil {
 		return nil, err
 	}
 	return rates, nil
 }
 
+// GetRoomType fetches a room type by its UUID
+func (p *PostgresDB) GetRoomType(
+	ctx context.Context,
+	RoomTypeUUID string,
+) (*domain.RoomType, error) {
+	var roomType domain.RoomType
+	if err := p.DB.WithContext(ctx).Where(&domain.RoomType{
+		AbstractBase: domain.AbstractBase{UUID: RoomTypeUUID},
+	}).Find(&roomType).Error; err != nil {
+		return nil, err
+	}
+	if roomType.UUID == "" {
+		return nil, nil
+	}
+	return &roomType, nil
+}
+
 // GetRate fetches a rate for a specific room type
 func (p *PostgresDB) GetRate(
 	ctx context.Context,
@@ -145,7 +143,7 @@ func (p *PostgresDB) GetRate(
 	RoomTypeUUID string,
 ) (*domain.Rate, error) {
 	var rate domain.Rate
-	if err := p.DB.Where(&domain.Rate{
+	if err := p.DB.WithContext(ctx).Where(&domain.Rate{
 		HotelUUID:    HotelUUID,
 		RoomTypeUUID: RoomTypeUUID,
 	}).Find(&rate).Error; err != nil {
@@ -165,7 +163,7 @@ func (p *PostgresDB) GetRoom(
 	HotelUUID string,
 ) (*domain.Room, error) {
 	var room domain.Room
-	if err := p.DB.Where(&domain.Room{
+	if err := p.DB.WithContext(ctx).Where(&domain.Room{
 		HotelUUID:    HotelUUID,
 		RoomTypeUUID: RoomTypeUUID,
 	}).Find(&room).Error; err != nil {
@@ -182,8 +180,8 @@ func (p *PostgresDB) CreateRoomType(
 	ctx context.Context,
 	roomType *domain.RoomType,
 ) (*domain.RoomType, error) {
-	if err := p.DB.Create(roomType).Error; err != nil {
-		return nil, fmt.Errorf("infrastructure: can't create a new room type: %v", err)
+	if err := p.DB.WithContext(ctx).Create(roomType).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new room type: %w", err)
 	}
 	return roomType, nil
 }
@@ -193,19 +191,75 @@ func (p *PostgresDB) CreateRate(
 	ctx context.Context,
 	rate *domain.Rate,
 ) (*domain.Rate, error) {
-	if err := p.DB.Create(rate).Error; err != nil {
-		return nil, fmt.Errorf("infrastructure: can't create a new rate: %v", err)
+	if err := p.DB.WithContext(ctx).Create(rate).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new rate: %w", err)
 	}
 	return rate, nil
 }
 
+// UpsertRates inserts the given nightly rates, overwriting the rate of any (hotel, room type, date)
+// that already exists. It returns how many of the rates were newly inserted
+func (p *PostgresDB) UpsertRates(
+	ctx context.Context,
+	rates []*domain.Rate,
+) (int64, error) {
+	var inserted int64
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(rates); start += rateUpsertBatchSize {
+			end := start + rateUpsertBatchSize
+			if end > len(rates) {
+				end = len(rates)
+			}
+			count, err := upsertRateBatch(tx, rates[start:end])
+			if err != nil {
+				return err
+			}
+			inserted += count
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("infrastructure: can't upsert rates: %w", err)
+	}
+	return inserted, nil
+}
+
+// upsertRateBatch upserts a batch of rates in a single statement.
+// xmax is only zero for rows that were inserted (rather than updated) by the statement
+func upsertRateBatch(tx *gorm.DB, rates []*domain.Rate) (int64, error) {
+	now := time.Now()
+	values := make([]string, 0, len(rates))
+	args := make([]interface{}, 0, len(rates)*7)
+	for _, rate := range rates {
+		values = append(values, "(?, TRUE, ?, ?, ?, ?, ?, ?)")
+		args = append(args, uuid.New().String(), now, now, rate.HotelUUID, rate.RoomTypeUUID, rate.Rate, rate.Date.Format(domain.DateLayout))
+	}
+	query := `INSERT INTO rates (uuid, active, created_at, updated_at, hotel_uuid, room_type_uuid, rate, date)
+		VALUES ` + strings.Join(values, ", ") + `
+		ON CONFLICT (hotel_uuid, room_type_uuid, date) DO UPDATE
+		SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at, active = TRUE, deleted_at = NULL
+		RETURNING (xmax = 0) AS inserted`
+
+	var results []struct{ Inserted bool }
+	if err := tx.Raw(query, args...).Scan(&results).Error; err != nil {
+		return 0, err
+	}
+	var inserted int64
+	for _, result := range results {
+		if result.Inserted {
+			inserted++
+		}
+	}
+	return inserted, nil
+}
+
 // Creates a Guest for a particular reservation
 func (p *PostgresDB) CreateGuest(
 	ctx context.Context,
 	guest *domain.Guest,
 ) (*domain.Guest, error) {
-	if err := p.DB.Create(guest).Error; err != nil {
-		return nil, fmt.Errorf("infrastructure: can't create a new guest: %v", err)
+	if err := p.DB.WithContext(ctx).Create(guest).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new guest: %w", err)
 	}
 	return guest, nil
 }
@@ -215,19 +269,50 @@ func (p *PostgresDB) CreateReservation(
 	ctx context.Context,
 	reservation *domain.Reservation,
 ) (*domain.Reservation, error) {
-	if err := p.DB.Create(reservation).Error; err != nil {
-		return nil, fmt.Errorf("infrastructure: can't create a new reservation: %v", err)
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return createReservation(tx, reservation)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new reservation: %w", err)
 	}
 	return reservation, nil
 }
 
+// createReservation claims a reservation's inventory, creates it, redeems its promotion and loyalty
+// points and records its event within a transaction
+func createReservation(tx *gorm.DB, reservation *domain.Reservation) error {
+	nights := domain.StayNights(reservation.StartDate, reservation.EndDate)
+	if err := claimInventory(tx, reservation.RoomTypeUUID, nights, 1); err != nil {
+		return err
+	}
+	if err := insertReservation(tx, reservation); err != nil {
+		return err
+	}
+	if err := redeemLoyaltyPoints(tx, reservation); err != nil {
+		return err
+	}
+	if reservation.PromotionUUID != nil {
+		if err := redeemPromotion(tx, reservation); err != nil {
+			return err
+		}
+	}
+	switch domain.ReservationStatus(reservation.Status) {
+	case domain.HELD:
+		return recordEvent(tx, domain.RESERVATION_HELD, reservation)
+	case domain.RESERVED:
+		return recordEvent(tx, domain.RESERVATION_CREATED, reservation)
+	}
+	// PENDING reservations record their event once the payment is settled
+	return nil
+}
+
 // CreateHotel creates a new hotel
 func (p *PostgresDB) CreateHotel(
 	ctx context.Context,
 	hotel *domain.Hotel,
 ) (*domain.Hotel, error) {
-	if err := p.DB.Create(hotel).Error; err != nil {
-		return nil, fmt.Errorf("infrastructure: can't create a new hotel: %v", err)
+	if err := p.DB.WithContext(ctx).Create(hotel).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new hotel: %w", err)
 	}
 	return hotel, nil
 }
@@ -237,8 +322,8 @@ func (p *PostgresDB) CreateRoom(
 	ctx context.Context,
 	room *domain.Room,
 ) (*domain.Room, error) {
-	if err := p.DB.Create(room).Error; err != nil {
-		return nil, fmt.Errorf("infrastructure: can't create a new room: %v", err)
+	if err := p.DB.WithContext(ctx).Create(room).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new room: %w", err)
 	}
 	return room, nil
 }
@@ -250,18 +335,34 @@ func (p *PostgresDB) CancelReservation(
 	RoomTypeUUID string,
 ) (*domain.Reservation, error) {
 	var reservation domain.Reservation
-	if err := p.DB.Where(&domain.Reservation{
-		Status:       string(domain.RESERVED),
-		GuestUUID:    GuestUUID,
-		RoomTypeUUID: RoomTypeUUID,
-	}).First(&reservation).Error; err != nil {
-		return nil, err
-	}
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&domain.Reservation{
+			Status:       string(domain.RESERVED),
+			GuestUUID:    GuestUUID,
+			RoomTypeUUID: RoomTypeUUID,
+		}).First(&reservation).Error; err != nil {
+			return err
+		}
 
-	reservation.Status = string(domain.CANCELLED)
-	now := time.Now()
-	reservation.UpdatedAt = &now
-	if err := p.DB.Save(&reservation).Error; err != nil {
+		reservation.Status = string(domain.CANCELLED)
+		now := time.Now()
+		reservation.UpdatedAt = &now
+		if err := tx.Save(&reservation).Error; err != nil {
+			return err
+		}
+		nights := domain.StayNights(reservation.StartDate, reservation.EndDate)
+		if err := releaseInventory(tx, reservation.RoomTypeUUID, nights, 1); err != nil {
+			return err
+		}
+		if err := releasePromotion(tx, &reservation); err != nil {
+			return err
+		}
+		if err := oweCancellationRefund(tx, &reservation, now); err != nil {
+			return err
+		}
+		return recordEvent(tx, domain.RESERVATION_CANCELLED, &reservation)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &reservation, nil
@@ -2,17 +2,50 @@ package database_test
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
 	"github.com/MelvinKim/Hotel-Reservation-System/domain"
 	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/database"
 	"github.com/brianvoe/gofakeit/v6"
 )
 
+// testDatabase is the database the tests run against, configured like the service
+var testDatabase config.DatabaseConfig
+
+// TestMain brings the test database's schema up to date before the tests run. Tests that don't need
+// the database still run when it can't be reached
+func TestMain(m *testing.M) {
+	if cfg, err := config.Load(); err == nil {
+		testDatabase = cfg.Database
+	}
+	// the tests that need the database fail on their own when it can't be reached, don't wait for it
+	testDatabase.ConnectRetries = 0
+	if db, err := database.Connect(context.Background(), testDatabase); err == nil {
+		if _, err := database.MigrateUp(context.Background(), db); err != nil {
+			fmt.Fprintf(os.Stderr, "can't migrate the test database: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	os.Exit(m.Run())
+}
+
+// newTestPostgresDB connects to the test database
+func newTestPostgresDB(t *testing.T) *database.PostgresDB {
+	t.Helper()
+	p, err := database.NewPostgresDB(context.Background(), testDatabase)
+	if err != nil {
+		t.Fatalf("can't connect to the test database: %v", err)
+	}
+	return p
+}
+
 func TestPostgresDB_CreateGuest(t *testing.T) {
 	ctx := context.Background()
-	p := database.NewPostgresDB()
+	p := newTestPostgresDB(t)
 	guest := &domain.Guest{
 		FirstName: gofakeit.FirstName(),
 		LastName:  gofakeit.LastName(),
@@ -62,7 +95,7 @@ func TestPostgresDB_CreateGuest(t *testing.T) {
 
 func TestPostgresDB_GetGuests(t *testing.T) {
 	ctx := context.Background()
-	p := database.NewPostgresDB()
+	p := newTestPostgresDB(t)
 	type args struct {
 		ctx context.Context
 	}
@@ -110,7 +143,7 @@ func TestPostgresDB_GetGuests(t *testing.T) {
 
 func TestPostgresDB_CreateHotel(t *testing.T) {
 	ctx := context.Background()
-	p := database.NewPostgresDB()
+	p := newTestPostgresDB(t)
 	hotel := &domain.Hotel{
 		Name:     gofakeit.Name(),
 		Address:  gofakeit.Address().Address,
@@ -158,7 +191,7 @@ func TestPostgresDB_CreateHotel(t *testing.T) {
 
 func TestPostgresDB_GetHotels(t *testing.T) {
 	ctx := context.Background()
-	p := database.NewPostgresDB()
+	p := newTestPostgresDB(t)
 	hotel := &domain.Hotel{
 		Name:     gofakeit.Name(),
 		Address:  gofakeit.Address().Address,
@@ -206,7 +239,7 @@ func TestPostgresDB_GetHotels(t *testing.T) {
 
 func TestPostgresDB_CreateRoom(t *testing.T) {
 	ctx := context.Background()
-	p := database.NewPostgresDB()
+	p := newTestPostgresDB(t)
 	hotel := &domain.Hotel{
 		Name:     gofakeit.Name(),
 		Address:  gofakeit.Address().Address,
@@ -284,7 +317,7 @@ func TestPostgresDB_CreateRoom(t *testing.T) {
 
 func TestPostgresDB_GetRooms(t *testing.T) {
 	ctx := context.Background()
-	p := database.NewPostgresDB()
+	p := newTestPostgresDB(t)
 	hotel := &domain.Hotel{
 		Name:     gofakeit.Name(),
 		Address:  gofakeit.Address().Address,
@@ -361,7 +394,7 @@ func TestPostgresDB_GetRooms(t *testing.T) {
 
 func TestPostgresDB_CreateReservation(t *testing.T) {
 	ctx := context.Background()
-	p := database.NewPostgresDB()
+	p := newTestPostgresDB(t)
 	guest := &domain.Guest{
 		FirstName: gofakeit.FirstName(),
 		LastName:  gofakeit.LastName(),
@@ -456,7 +489,7 @@ func TestPostgresDB_CreateReservation(t *testing.T) {
 
 func TestPostgresDB_GetReservations(t *testing.T) {
 	ctx := context.Background()
-	p := database.NewPostgresDB()
+	p := newTestPostgresDB(t)
 	guest := &domain.Guest{
 		FirstName: gofakeit.FirstName(),
 		LastName:  gofakeit.LastName(),
@@ -549,7 +582,7 @@ func TestPostgresDB_GetReservations(t *testing.T) {
 
 func TestPostgresDB_CancelReservation(t *testing.T) {
 	ctx := context.Background()
-	p := database.NewPostgresDB()
+	p := newTestPostgresDB(t)
 	guest := &domain.Guest{
 		FirstName: gofakeit.FirstName(),
 		LastName:  gofakeit.LastName(),
@@ -648,3 +681,309 @@ func TestPostgresDB_CancelReservation(t *testing.T) {
 		})
 	}
 }
+
+func TestPostgresDB_UpsertRates(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPostgresDB(t)
+	hotel := &domain.Hotel{
+		Name:     gofakeit.Name(),
+		Address:  gofakeit.Address().Address,
+		Location: gofakeit.City(),
+	}
+	createdHotel, err := p.CreateHotel(ctx, hotel)
+	if err != nil {
+		t.Errorf("Can't create test hotel: %v", err)
+		return
+	}
+	createdRoomType, err := p.CreateRoomType(ctx, &domain.RoomType{
+		HotelUUID: createdHotel.UUID,
+		Inventory: 50,
+	})
+	if err != nil {
+		t.Errorf("Can't create test roomType: %v", err)
+		return
+	}
+	newRates := func(rate int) []*domain.Rate {
+		start := domain.TruncateToDate(time.Now())
+		rates := []*domain.Rate{}
+		for i := 0; i < 3; i++ {
+			rates = append(rates, &domain.Rate{
+				HotelUUID:    createdHotel.UUID,
+				RoomTypeUUID: createdRoomType.UUID,
+				Rate:         rate,
+				Date:         start.AddDate(0, 0, i),
+			})
+		}
+		return rates
+	}
+
+	type args struct {
+		ctx   context.Context
+		rates []*domain.Rate
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantInserted int64
+		wantErr      bool
+	}{
+		{
+			name: "Happy Case: new nights are inserted",
+			args: args{
+				ctx:   ctx,
+				rates: newRates(100),
+			},
+			wantInserted: 3,
+			wantErr:      false,
+		},
+		{
+			name: "Happy Case: existing nights are updated",
+			args: args{
+				ctx:   ctx,
+				rates: newRates(120),
+			},
+			wantInserted: 0,
+			wantErr:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inserted, err := p.UpsertRates(tt.args.ctx, tt.args.rates)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PostgresDB.UpsertRates() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if inserted != tt.wantInserted {
+				t.Fatalf("expected %v rates to be inserted, but got %v", tt.wantInserted, inserted)
+			}
+		})
+	}
+}
+
+func TestPostgresDB_CreateReservation_PromotionCap(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPostgresDB(t)
+	createdHotel, err := p.CreateHotel(ctx, &domain.Hotel{
+		Name:     gofakeit.Name(),
+		Address:  gofakeit.Address().Address,
+		Location: gofakeit.City(),
+	})
+	if err != nil {
+		t.Errorf("Can't create test hotel: %v", err)
+		return
+	}
+	createdRoomType, err := p.CreateRoomType(ctx, &domain.RoomType{
+		HotelUUID: createdHotel.UUID,
+		Inventory: 50,
+	})
+	if err != nil {
+		t.Errorf("Can't create test roomType: %v", err)
+		return
+	}
+	promotion, err := p.CreatePromotion(ctx, &domain.Promotion{
+		Code:         gofakeit.LetterN(12),
+		DiscountType: domain.PERCENTAGE,
+		Value:        10,
+		ValidFrom:    time.Now().Add(-time.Hour),
+		MaxUses:      1,
+	})
+	if err != nil {
+		t.Errorf("Can't create test promotion: %v", err)
+		return
+	}
+
+	const attempts = 5
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		guest, err := p.CreateGuest(ctx, &domain.Guest{
+			FirstName: gofakeit.FirstName(),
+			LastName:  gofakeit.LastName(),
+			Email:     gofakeit.Email(),
+		})
+		if err != nil {
+			t.Errorf("Can't create test guest profile: %v", err)
+			return
+		}
+		go func(guest *domain.Guest) {
+			start := domain.TruncateToDate(time.Now()).AddDate(0, 0, 10)
+			_, err := p.CreateReservation(ctx, &domain.Reservation{
+				GuestUUID:     guest.UUID,
+				HotelUUID:     createdHotel.UUID,
+				RoomTypeUUID:  createdRoomType.UUID,
+				StartDate:     start,
+				EndDate:       start.AddDate(0, 0, 1),
+				PromotionUUID: &promotion.UUID,
+				Status:        string(domain.RESERVED),
+			})
+			errs <- err
+		}(guest)
+	}
+
+	redeemed := 0
+	for i := 0; i < attempts; i++ {
+		if err := <-errs; err == nil {
+			redeemed++
+		}
+	}
+	if redeemed != 1 {
+		t.Fatalf("expected the promotion to be redeemed exactly once, but it was redeemed %v times", redeemed)
+	}
+}
+
+func TestPostgresDB_ReleaseExpiredHolds(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPostgresDB(t)
+	createdHotel, err := p.CreateHotel(ctx, &domain.Hotel{
+		Name:     gofakeit.Name(),
+		Address:  gofakeit.Address().Address,
+		Location: gofakeit.City(),
+	})
+	if err != nil {
+		t.Errorf("Can't create test hotel: %v", err)
+		return
+	}
+	createdRoomType, err := p.CreateRoomType(ctx, &domain.RoomType{
+		HotelUUID: createdHotel.UUID,
+		Inventory: 50,
+	})
+	if err != nil {
+		t.Errorf("Can't create test roomType: %v", err)
+		return
+	}
+
+	const holds = 6
+	start := domain.TruncateToDate(time.Now()).AddDate(0, 0, 10)
+	expired := time.Now().Add(-time.Minute)
+	for i := 0; i < holds; i++ {
+		guest, err := p.CreateGuest(ctx, &domain.Guest{
+			FirstName: gofakeit.FirstName(),
+			LastName:  gofakeit.LastName(),
+			Email:     gofakeit.Email(),
+		})
+		if err != nil {
+			t.Errorf("Can't create test guest profile: %v", err)
+			return
+		}
+		if _, err := p.CreateReservation(ctx, &domain.Reservation{
+			GuestUUID:    guest.UUID,
+			HotelUUID:    createdHotel.UUID,
+			RoomTypeUUID: createdRoomType.UUID,
+			StartDate:    start,
+			EndDate:      start.AddDate(0, 0, 2),
+			Status:       string(domain.HELD),
+			ExpiresAt:    &expired,
+		}); err != nil {
+			t.Errorf("Can't create test hold: %v", err)
+			return
+		}
+	}
+
+	// two reapers running at once, as they would on two replicas, must release every hold once
+	results := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			released, err := p.ReleaseExpiredHolds(ctx, time.Now(), holds)
+			if err != nil {
+				t.Errorf("PostgresDB.ReleaseExpiredHolds() unexpected error = %v", err)
+			}
+			results <- released
+		}()
+	}
+	released := <-results + <-results
+	if released != holds {
+		t.Fatalf("expected %v holds to be released but got %v", holds, released)
+	}
+
+	inventories, err := p.GetRoomTypeInventories(ctx, createdHotel.UUID, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("Can't get inventory: %v", err)
+	}
+	for _, inventory := range inventories {
+		if inventory.TotalReserved != 0 {
+			t.Errorf("expected the inventory of %v to be released but %v rooms are reserved", inventory.Date, inventory.TotalReserved)
+		}
+	}
+}
+
+func TestPostgresDB_RelayOutboxEvents(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPostgresDB(t)
+	createdHotel, err := p.CreateHotel(ctx, &domain.Hotel{
+		Name:     gofakeit.Name(),
+		Address:  gofakeit.Address().Address,
+		Location: gofakeit.City(),
+	})
+	if err != nil {
+		t.Errorf("Can't create test hotel: %v", err)
+		return
+	}
+	createdRoomType, err := p.CreateRoomType(ctx, &domain.RoomType{
+		HotelUUID: createdHotel.UUID,
+		Inventory: 5,
+	})
+	if err != nil {
+		t.Errorf("Can't create test roomType: %v", err)
+		return
+	}
+	guest, err := p.CreateGuest(ctx, &domain.Guest{
+		FirstName: gofakeit.FirstName(),
+		LastName:  gofakeit.LastName(),
+		Email:     gofakeit.Email(),
+	})
+	if err != nil {
+		t.Errorf("Can't create test guest profile: %v", err)
+		return
+	}
+	start := domain.TruncateToDate(time.Now()).AddDate(0, 0, 10)
+	reservation, err := p.CreateReservation(ctx, &domain.Reservation{
+		GuestUUID:    guest.UUID,
+		HotelUUID:    createdHotel.UUID,
+		RoomTypeUUID: createdRoomType.UUID,
+		StartDate:    start,
+		EndDate:      start.AddDate(0, 0, 1),
+		Status:       string(domain.RESERVED),
+	})
+	if err != nil {
+		t.Fatalf("Can't create test reservation: %v", err)
+	}
+	if _, err := p.CancelReservation(ctx, guest.UUID, createdRoomType.UUID); err != nil {
+		t.Fatalf("Can't cancel test reservation: %v", err)
+	}
+
+	// relay everything, including events left behind by other tests, to a consumer of this test's own
+	consumer := "test_" + gofakeit.UUID()
+	for {
+		published, err := p.RelayOutboxEvents(ctx, 100, []string{consumer})
+		if err != nil {
+			t.Fatalf("PostgresDB.RelayOutboxEvents() unexpected error = %v", err)
+		}
+		if published == 0 {
+			break
+		}
+	}
+	var types []domain.EventType
+	for {
+		deliveries, err := p.ClaimEventDeliveries(ctx, consumer, time.Now(), 100)
+		if err != nil {
+			t.Fatalf("PostgresDB.ClaimEventDeliveries() unexpected error = %v", err)
+		}
+		if len(deliveries) == 0 {
+			break
+		}
+		for i := range deliveries {
+			if deliveries[i].Event == nil {
+				t.Fatalf("expected the event of delivery %v to be loaded", deliveries[i].UUID)
+			}
+			if deliveries[i].Event.AggregateUUID == reservation.UUID {
+				types = append(types, deliveries[i].Event.Type)
+			}
+			deliveries[i].RecordAttempt(time.Now(), nil)
+			if err := p.RecordEventDelivery(ctx, &deliveries[i]); err != nil {
+				t.Fatalf("PostgresDB.RecordEventDelivery() unexpected error = %v", err)
+			}
+		}
+	}
+	if len(types) != 2 || types[0] != domain.RESERVATION_CREATED || types[1] != domain.RESERVATION_CANCELLED {
+		t.Fatalf("expected the created and cancelled events to be consumed in order but got %v", types)
+	}
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+// CreatePricingRule creates a new dynamic pricing rule for a hotel
+func (p *PostgresDB) CreatePricingRule(
+	ctx context.Context,
+	rule *domain.PricingRule,
+) (*domain.PricingRule, error) {
+	if err := p.DB.WithContext(ctx).Create(rule).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new pricing rule: %w", err)
+	}
+	return rule, nil
+}
+
+// GetPricingRules fetches the active pricing rules of a hotel
+func (p *PostgresDB) GetPricingRules(
+	ctx context.Context,
+	HotelUUID string,
+) ([]domain.PricingRule, error) {
+	var rules []domain.PricingRule
+	if err := p.DB.WithContext(ctx).Where(&domain.PricingRule{
+		HotelUUID:    HotelUUID,
+		AbstractBase: domain.AbstractBase{Active: true},
+	}).Order("created_at").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
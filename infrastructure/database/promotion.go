@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+)
+
+// CreatePromotion creates a new promotion
+func (p *PostgresDB) CreatePromotion(
+	ctx context.Context,
+	promotion *domain.Promotion,
+) (*domain.Promotion, error) {
+	if err := p.DB.WithContext(ctx).Create(promotion).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new promotion: %w", err)
+	}
+	return promotion, nil
+}
+
+// GetPromotionByCode fetches a promotion by its promo code
+func (p *PostgresDB) GetPromotionByCode(
+	ctx context.Context,
+	Code string,
+) (*domain.Promotion, error) {
+	var promotion domain.Promotion
+	if err := p.DB.WithContext(ctx).Where(&domain.Promotion{Code: Code}).Find(&promotion).Error; err != nil {
+		return nil, err
+	}
+	if promotion.UUID == "" {
+		return nil, nil
+	}
+	return &promotion, nil
+}
+
+// redeemPromotion records a reservation's use of its promotion. Incrementing the promotion's usage
+// locks its row until the transaction ends, so concurrent redemptions of the same promotion are
+// serialized and neither the overall nor the per guest cap can be exceeded
+func redeemPromotion(
+	tx *gorm.DB,
+	reservation *domain.Reservation,
+) error {
+	result := tx.Model(&domain.Promotion{}).
+		Where("uuid = ? AND active = TRUE AND (max_uses = 0 OR used_count < max_uses)", *reservation.PromotionUUID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("can't redeem promotion: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrPromotionExhausted
+	}
+
+	var promotion domain.Promotion
+	if err := tx.Where("uuid = ?", *reservation.PromotionUUID).First(&promotion).Error; err != nil {
+		return fmt.Errorf("can't get promotion: %w", err)
+	}
+	if promotion.MaxUsesPerGuest > 0 {
+		var guestUses int64
+		if err := tx.Model(&domain.PromotionRedemption{}).Where(&domain.PromotionRedemption{
+			PromotionUUID: promotion.UUID,
+			GuestUUID:     reservation.GuestUUID,
+		}).Count(&guestUses).Error; err != nil {
+			return fmt.Errorf("can't count the guest's redemptions: %w", err)
+		}
+		if guestUses >= int64(promotion.MaxUsesPerGuest) {
+			return domain.ErrPromotionExhausted
+		}
+	}
+
+	return tx.Create(&domain.PromotionRedemption{
+		PromotionUUID:   promotion.UUID,
+		GuestUUID:       reservation.GuestUUID,
+		ReservationUUID: reservation.UUID,
+		Discount:        reservation.Discount,
+	}).Error
+}
+
+// releasePromotion gives a cancelled reservation's promotion use back
+func releasePromotion(
+	tx *gorm.DB,
+	reservation *domain.Reservation,
+) error {
+	if reservation.PromotionUUID == nil {
+		return nil
+	}
+	result := tx.Where(&domain.PromotionRedemption{ReservationUUID: reservation.UUID}).
+		Delete(&domain.PromotionRedemption{})
+	if result.Error != nil {
+		return fmt.Errorf("can't release promotion: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	return tx.Model(&domain.Promotion{}).
+		Where("uuid = ? AND used_count > 0", *reservation.PromotionUUID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
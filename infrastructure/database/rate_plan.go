@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm/clause"
+)
+
+// CreateRatePlan creates a new rate plan for a room type
+func (p *PostgresDB) CreateRatePlan(
+	ctx context.Context,
+	ratePlan *domain.RatePlan,
+) (*domain.RatePlan, error) {
+	if err := p.DB.WithContext(ctx).Create(ratePlan).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new rate plan: %w", err)
+	}
+	return ratePlan, nil
+}
+
+// UpsertRatePlanRates sets the nightly rates of a rate plan, overwriting the nights that already have a rate
+func (p *PostgresDB) UpsertRatePlanRates(
+	ctx context.Context,
+	rates []*domain.RatePlanRate,
+) error {
+	err := p.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "rate_plan_uuid"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"rate", "min_length_of_stay", "closed_to_arrival", "closed_to_departure", "updated_at",
+		}),
+	}).CreateInBatches(rates, rateUpsertBatchSize).Error
+	if err != nil {
+		return fmt.Errorf("infrastructure: can't upsert rate plan rates: %w", err)
+	}
+	return nil
+}
+
+// GetRatePlan fetches a rate plan by its UUID
+func (p *PostgresDB) GetRatePlan(
+	ctx context.Context,
+	RatePlanUUID string,
+) (*domain.RatePlan, error) {
+	var ratePlan domain.RatePlan
+	if err := p.DB.WithContext(ctx).Where(&domain.RatePlan{
+		AbstractBase: domain.AbstractBase{UUID: RatePlanUUID},
+	}).Find(&ratePlan).Error; err != nil {
+		return nil, err
+	}
+	if ratePlan.UUID == "" {
+		return nil, nil
+	}
+	return &ratePlan, nil
+}
+
+// GetRatePlans fetches the active rate plans of a hotel
+func (p *PostgresDB) GetRatePlans(
+	ctx context.Context,
+	HotelUUID string,
+) ([]domain.RatePlan, error) {
+	var ratePlans []domain.RatePlan
+	if err := p.DB.WithContext(ctx).Where(&domain.RatePlan{
+		HotelUUID:    HotelUUID,
+		AbstractBase: domain.AbstractBase{Active: true},
+	}).Find(&ratePlans).Error; err != nil {
+		return nil, err
+	}
+	return ratePlans, nil
+}
+
+// GetRatePlanRates fetches the nightly rates of the given rate plans between two dates (both inclusive)
+func (p *PostgresDB) GetRatePlanRates(
+	ctx context.Context,
+	RatePlanUUIDs []string,
+	StartDate time.Time,
+	EndDate time.Time,
+) ([]domain.RatePlanRate, error) {
+	var rates []domain.RatePlanRate
+	if len(RatePlanUUIDs) == 0 {
+		return rates, nil
+	}
+	if err := p.DB.WithContext(ctx).Where(
+		"rate_plan_uuid IN ? AND date BETWEEN ? AND ?",
+		RatePlanUUIDs,
+		StartDate.Format(domain.DateLayout),
+		EndDate.Format(domain.DateLayout),
+	).Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// GetHotelRoomTypes fetches the room types of a hotel
+func (p *PostgresDB) GetHotelRoomTypes(
+	ctx context.Context,
+	HotelUUID string,
+) ([]domain.RoomType, error) {
+	var roomTypes []domain.RoomType
+	if err := p.DB.WithContext(ctx).Where(&domain.RoomType{HotelUUID: HotelUUID}).Find(&roomTypes).Error; err != nil {
+		return nil, err
+	}
+	return roomTypes, nil
+}
+
+// GetRoomTypeInventories fetches a hotel's per night room type inventory between two dates (both inclusive)
+// Nights that have never been booked have no row
+func (p *PostgresDB) GetRoomTypeInventories(
+	ctx context.Context,
+	HotelUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+) ([]domain.RoomTypeInventory, error) {
+	var inventories []domain.RoomTypeInventory
+	if err := p.DB.WithContext(ctx).Where(
+		"hotel_uuid = ? AND date BETWEEN ? AND ?",
+		HotelUUID,
+		StartDate.Format(domain.DateLayout),
+		EndDate.Format(domain.DateLayout),
+	).Find(&inventories).Error; err != nil {
+		return nil, err
+	}
+	return inventories, nil
+}
+
+// GetRatesInRange fetches the nightly rates of a room type between two dates (both inclusive)
+func (p *PostgresDB) GetRatesInRange(
+	ctx context.Context,
+	RoomTypeUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+) ([]domain.Rate, error) {
+	var rates []domain.Rate
+	if err := p.DB.WithContext(ctx).Where(
+		"room_type_uuid = ? AND date BETWEEN ? AND ?",
+		RoomTypeUUID,
+		StartDate.Format(domain.DateLayout),
+		EndDate.Format(domain.DateLayout),
+	).Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CheckInReservation moves a RESERVED reservation to CHECKED_IN
+func (p *PostgresDB) CheckInReservation(
+	ctx context.Context,
+	ReservationUUID string,
+) (*domain.Reservation, error) {
+	return p.transitionReservation(ctx, ReservationUUID, domain.RESERVED, domain.CHECKED_IN, domain.RESERVATION_CHECKED_IN)
+}
+
+// CheckOutReservation moves a CHECKED_IN reservation to CHECKED_OUT
+func (p *PostgresDB) CheckOutReservation(
+	ctx context.Context,
+	ReservationUUID string,
+) (*domain.Reservation, error) {
+	return p.transitionReservation(ctx, ReservationUUID, domain.CHECKED_IN, domain.CHECKED_OUT, domain.RESERVATION_CHECKED_OUT)
+}
+
+// transitionReservation moves a reservation between two statuses, recording the event of the change
+func (p *PostgresDB) transitionReservation(
+	ctx context.Context,
+	ReservationUUID string,
+	from domain.ReservationStatus,
+	to domain.ReservationStatus,
+	eventType domain.EventType,
+) (*domain.Reservation, error) {
+	var reservation domain.Reservation
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&domain.Reservation{
+			AbstractBase: domain.AbstractBase{UUID: ReservationUUID},
+			Status:       string(from),
+		}).First(&reservation).Error; err != nil {
+			return fmt.Errorf("no %s reservation %s: %w", from, ReservationUUID, err)
+		}
+		if err := setReservationStatus(tx, &reservation, to); err != nil {
+			return err
+		}
+		return recordEvent(tx, eventType, &reservation)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't move reservation to %s: %w", to, err)
+	}
+	return &reservation, nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+// timeoutKey is where the bounded context of a query is kept on the gorm statement
+const timeoutKey = "timeout:context"
+
+// boundContext is the context a query runs with, and the one it was made with
+type boundContext struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// TimeoutPlugin gives up on the queries that run past their deadline: the reads after Read and the
+// writes, and every query of a transaction, after Write, unless the query's context expires first. A zero
+// timeout leaves queries to their context. A query cut short, by its timeout or by its caller going away,
+// fails with a *domain.TimeoutError, so queries have to be made with DB.WithContext
+type TimeoutPlugin struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+// Name names the plugin for gorm
+func (TimeoutPlugin) Name() string {
+	return "timeout"
+}
+
+// Initialize registers the plugin's callbacks around every kind of query. Row queries are left to their
+// context: their rows are read after the callbacks have run
+func (p TimeoutPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	for _, err := range []error{
+		callback.Create().Before("gorm:create").Register("timeout:before_create", p.bound(p.Write)),
+		callback.Create().After("gorm:create").Register("timeout:after_create", unbound("create")),
+		callback.Query().Before("gorm:query").Register("timeout:before_query", p.bound(p.Read)),
+		callback.Query().After("gorm:query").Register("timeout:after_query", unbound("query")),
+		callback.Update().Before("gorm:update").Register("timeout:before_update", p.bound(p.Write)),
+		callback.Update().After("gorm:update").Register("timeout:after_update", unbound("update")),
+		callback.Delete().Before("gorm:delete").Register("timeout:before_delete", p.bound(p.Write)),
+		callback.Delete().After("gorm:delete").Register("timeout:after_delete", unbound("delete")),
+		callback.Raw().Before("gorm:raw").Register("timeout:before_raw", p.bound(p.Write)),
+		callback.Raw().After("gorm:raw").Register("timeout:after_raw", unbound("raw")),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bound returns the callback that bounds a query by timeout, or by the write timeout within a transaction
+// where reads lock the rows the transaction writes
+func (p TimeoutPlugin) bound(timeout time.Duration) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		limit := timeout
+		if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+			limit = p.Write
+		}
+		parent := db.Statement.Context
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if limit > 0 {
+			ctx, cancel = context.WithTimeout(parent, limit)
+		} else {
+			ctx, cancel = context.WithCancel(parent)
+		}
+		db.Statement.Context = ctx
+		db.InstanceSet(timeoutKey, boundContext{parent: parent, ctx: ctx, cancel: cancel})
+	}
+}
+
+// unbound returns the callback that releases the bound of a query of the operation once it has run, and
+// reports its error as a *domain.TimeoutError when the query was cut short: whatever the driver returned,
+// the query didn't complete
+func unbound(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(timeoutKey)
+		if !ok {
+			return
+		}
+		bound, ok := value.(boundContext)
+		if !ok {
+			return
+		}
+		defer bound.cancel()
+		if db.Error != nil && bound.ctx.Err() != nil {
+			if db.Statement.Table != "" {
+				operation += " " + db.Statement.Table
+			}
+			db.Error = &domain.TimeoutError{Operation: operation, Err: bound.ctx.Err()}
+		}
+		// the statement may be reused, its next query gets a bound of its own
+		db.Statement.Context = bound.parent
+	}
+}
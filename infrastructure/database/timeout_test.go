@@ -0,0 +1,175 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/database"
+)
+
+// errNoRows is what slowPool answers the reads that complete, it can't build rows
+var errNoRows = errors.New("no rows")
+
+// slowPool answers every query after delay, or with the context's error when it is done first, the way a
+// query cancelled by the driver would
+type slowPool struct {
+	delay time.Duration
+}
+
+func (p slowPool) wait(ctx context.Context) error {
+	select {
+	case <-time.After(p.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p slowPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (p slowPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (p slowPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	return nil, errNoRows
+}
+
+func (p slowPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p slowPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &slowTx{p}, nil
+}
+
+// slowTx is a transaction on a slowPool
+type slowTx struct {
+	slowPool
+}
+
+func (slowTx) Commit() error {
+	return nil
+}
+
+func (slowTx) Rollback() error {
+	return nil
+}
+
+func TestTimeoutPlugin(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	read := func(db *gorm.DB) error {
+		var guests []map[string]interface{}
+		return db.Table("guests").Find(&guests).Error
+	}
+	write := func(db *gorm.DB) error {
+		return db.Table("guests").Where("age < ?", 18).Update("age", 18).Error
+	}
+	cases := []struct {
+		name         string
+		ctx          context.Context
+		readTimeout  time.Duration
+		writeTimeout time.Duration
+		query        func(db *gorm.DB) error
+		wantErr      error
+		wantCause    error
+	}{
+		{
+			name:        "read within its timeout",
+			ctx:         context.Background(),
+			readTimeout: time.Second,
+			query:       read,
+			wantErr:     errNoRows,
+		},
+		{
+			name:        "read past its timeout",
+			ctx:         context.Background(),
+			readTimeout: time.Millisecond,
+			query:       read,
+			wantCause:   context.DeadlineExceeded,
+		},
+		{
+			name:         "write gets the write timeout",
+			ctx:          context.Background(),
+			readTimeout:  time.Millisecond,
+			writeTimeout: time.Second,
+			query:        write,
+		},
+		{
+			name:         "write past its timeout",
+			ctx:          context.Background(),
+			readTimeout:  time.Second,
+			writeTimeout: time.Millisecond,
+			query:        write,
+			wantCause:    context.DeadlineExceeded,
+		},
+		{
+			name:         "read in a transaction gets the write timeout",
+			ctx:          context.Background(),
+			readTimeout:  time.Millisecond,
+			writeTimeout: time.Second,
+			query: func(db *gorm.DB) error {
+				return db.Transaction(read)
+			},
+			wantErr: errNoRows,
+		},
+		{
+			name:  "zero timeout leaves the query unbounded",
+			ctx:   context.Background(),
+			query: write,
+		},
+		{
+			name:        "cancelled by the caller",
+			ctx:         cancelled,
+			readTimeout: time.Second,
+			query:       read,
+			wantCause:   context.Canceled,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{
+				ConnPool:             slowPool{delay: 20 * time.Millisecond},
+				DisableAutomaticPing: true,
+			})
+			if err != nil {
+				t.Fatalf("gorm.Open() error = %v", err)
+			}
+			if err := db.Use(database.TimeoutPlugin{Read: tt.readTimeout, Write: tt.writeTimeout}); err != nil {
+				t.Fatalf("db.Use() error = %v", err)
+			}
+			err = tt.query(db.WithContext(tt.ctx))
+			var timeout *domain.TimeoutError
+			if tt.wantCause == nil {
+				if !errors.Is(err, tt.wantErr) || errors.As(err, &timeout) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if !errors.As(err, &timeout) {
+				t.Fatalf("error = %v, want a *domain.TimeoutError", err)
+			}
+			if !errors.Is(err, tt.wantCause) {
+				t.Fatalf("error = %v, want it caused by %v", err, tt.wantCause)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateWaitlistEntry adds a guest to a room type's waitlist
+func (p *PostgresDB) CreateWaitlistEntry(
+	ctx context.Context,
+	entry *domain.WaitlistEntry,
+) (*domain.WaitlistEntry, error) {
+	if err := p.DB.WithContext(ctx).Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new waitlist entry: %w", err)
+	}
+	return entry, nil
+}
+
+// GetWaitlist fetches the WAITING entries of a room type whose stays overlap the given nights,
+// first come first served
+func (p *PostgresDB) GetWaitlist(
+	ctx context.Context,
+	RoomTypeUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+) ([]domain.WaitlistEntry, error) {
+	var entries []domain.WaitlistEntry
+	if err := p.DB.WithContext(ctx).Where(
+		"room_type_uuid = ? AND status = ? AND start_date < ? AND end_date > ?",
+		RoomTypeUUID,
+		string(domain.WAITING),
+		EndDate.Format(domain.DateLayout),
+		StartDate.Format(domain.DateLayout),
+	).Order("created_at").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// OfferWaitlistEntry creates a hold for a WAITING entry and marks the entry OFFERED in one transaction,
+// recording the event that notifies the guest of the offer
+func (p *PostgresDB) OfferWaitlistEntry(
+	ctx context.Context,
+	entry *domain.WaitlistEntry,
+	hold *domain.Reservation,
+) (*domain.Reservation, error) {
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked domain.WaitlistEntry
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(&domain.WaitlistEntry{
+			AbstractBase: domain.AbstractBase{UUID: entry.UUID},
+			Status:       domain.WAITING,
+		}).Limit(1).Find(&locked)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrWaitlistEntryTaken
+		}
+		if err := createReservation(tx, hold); err != nil {
+			return err
+		}
+		now := time.Now()
+		entry.Status = domain.OFFERED
+		entry.ReservationUUID = &hold.UUID
+		entry.OfferedAt = &now
+		entry.UpdatedAt = &now
+		if err := tx.Model(entry).Select("status", "reservation_uuid", "offered_at", "updated_at").Updates(entry).Error; err != nil {
+			return err
+		}
+		return recordEvent(tx, domain.RESERVATION_WAITLIST_OFFERED, hold)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't offer waitlist entry: %w", err)
+	}
+	return hold, nil
+}
+
+// settleWaitlistOffers moves the waitlist entries offered the given holds to a new status
+func settleWaitlistOffers(tx *gorm.DB, status domain.WaitlistStatus, ReservationUUIDs ...string) error {
+	if len(ReservationUUIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&domain.WaitlistEntry{}).
+		Where("reservation_uuid IN ? AND status = ?", ReservationUUIDs, string(domain.OFFERED)).
+		Updates(map[string]interface{}{"status": string(status), "updated_at": time.Now()}).Error
+}
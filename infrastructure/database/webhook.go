@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateWebhookSubscription creates a new webhook subscription
+func (p *PostgresDB) CreateWebhookSubscription(
+	ctx context.Context,
+	subscription *domain.WebhookSubscription,
+) (*domain.WebhookSubscription, error) {
+	if err := p.DB.WithContext(ctx).Create(subscription).Error; err != nil {
+		return nil, fmt.Errorf("infrastructure: can't create a new webhook subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// CreateWebhookDeliveries queues deliveries, ignoring the ones already queued for the same
+// subscription and event so that a republished event isn't delivered twice
+func (p *PostgresDB) CreateWebhookDeliveries(
+	ctx context.Context,
+	deliveries []*domain.WebhookDelivery,
+) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := p.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subscription_uuid"}, {Name: "event_uuid"}},
+		DoNothing: true,
+	}).Create(&deliveries).Error; err != nil {
+		return fmt.Errorf("infrastructure: can't queue webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookSubscriptions fetches the active webhook subscriptions of a hotel
+func (p *PostgresDB) GetWebhookSubscriptions(
+	ctx context.Context,
+	HotelUUID string,
+) ([]domain.WebhookSubscription, error) {
+	var subscriptions []domain.WebhookSubscription
+	if err := p.DB.WithContext(ctx).Where(&domain.WebhookSubscription{
+		AbstractBase: domain.AbstractBase{Active: true},
+		HotelUUID:    HotelUUID,
+	}).Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetWebhookDeliveries fetches the deliveries made to a subscription, newest first
+func (p *PostgresDB) GetWebhookDeliveries(
+	ctx context.Context,
+	SubscriptionUUID string,
+) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	if err := p.DB.WithContext(ctx).Where(&domain.WebhookDelivery{SubscriptionUUID: SubscriptionUUID}).
+		Order("created_at DESC").
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimWebhookDeliveries claims up to limit pending deliveries that are due, oldest first, by putting
+// their next attempt off for the domain.WebhookDeliveryLease. Deliveries claimed by another dispatcher
+// are skipped. The claim is committed before the deliveries are attempted, so no row stays locked while
+// receivers are called, and the deliveries of a dispatcher that dies are claimed again once the lease is over
+func (p *PostgresDB) ClaimWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", string(domain.DELIVERY_PENDING), now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error; err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+		deliveryUUIDs := make([]string, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			deliveryUUIDs = append(deliveryUUIDs, delivery.UUID)
+		}
+		leasedUntil := now.Add(domain.WebhookDeliveryLease)
+		if err := tx.Model(&domain.WebhookDelivery{}).Where("uuid IN ?", deliveryUUIDs).
+			Update("next_attempt_at", leasedUntil).Error; err != nil {
+			return err
+		}
+		for i := range deliveries {
+			deliveries[i].NextAttemptAt = leasedUntil
+		}
+		return loadDeliveryRelations(tx, deliveries)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("infrastructure: can't claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RecordWebhookDelivery saves the outcome of an attempt to deliver a webhook
+func (p *PostgresDB) RecordWebhookDelivery(
+	ctx context.Context,
+	delivery *domain.WebhookDelivery,
+) error {
+	now := time.Now()
+	delivery.UpdatedAt = &now
+	if err := p.DB.WithContext(ctx).Model(delivery).Select(
+		"status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at", "updated_at",
+	).Updates(delivery).Error; err != nil {
+		return fmt.Errorf("infrastructure: can't record webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// loadDeliveryRelations loads the subscription and event of every delivery.
+// They aren't preloaded so that the deliveries' row locks don't extend to them
+func loadDeliveryRelations(tx *gorm.DB, deliveries []domain.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	subscriptionUUIDs := make([]string, 0, len(deliveries))
+	eventUUIDs := make([]string, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		subscriptionUUIDs = append(subscriptionUUIDs, delivery.SubscriptionUUID)
+		eventUUIDs = append(eventUUIDs, delivery.EventUUID)
+	}
+	var subscriptions []domain.WebhookSubscription
+	if err := tx.Where("uuid IN ?", subscriptionUUIDs).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	var events []domain.OutboxEvent
+	if err := tx.Where("uuid IN ?", eventUUIDs).Find(&events).Error; err != nil {
+		return err
+	}
+	subscriptionsByUUID := make(map[string]*domain.WebhookSubscription, len(subscriptions))
+	for i := range subscriptions {
+		subscriptionsByUUID[subscriptions[i].UUID] = &subscriptions[i]
+	}
+	eventsByUUID := make(map[string]*domain.OutboxEvent, len(events))
+	for i := range events {
+		eventsByUUID[events[i].UUID] = &events[i]
+	}
+	for i := range deliveries {
+		deliveries[i].Subscription = subscriptionsByUUID[deliveries[i].SubscriptionUUID]
+		deliveries[i].Event = eventsByUUID[deliveries[i].EventUUID]
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/metrics"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -49,6 +50,7 @@ func (cache *redisCache) Get(key string) *domain.Guest {
 	client := cache.getClient()
 
 	val, err := client.Get(ctx, key).Result()
+	metrics.CacheRequests.WithLabelValues("guest", metrics.CacheResult(err == nil)).Inc()
 	if err != nil {
 		return nil
 	}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// EventPublisher delivers outbox events to a downstream system.
+// Events can be delivered more than once, so publishers and their consumers must be idempotent,
+// the event's UUID identifies it across deliveries
+type EventPublisher interface {
+	Publish(ctx context.Context, event *domain.OutboxEvent) error
+}
+
+// PublisherFunc adapts a function to an EventPublisher
+type PublisherFunc func(ctx context.Context, event *domain.OutboxEvent) error
+
+// Publish calls the function
+func (f PublisherFunc) Publish(ctx context.Context, event *domain.OutboxEvent) error {
+	return f(ctx, event)
+}
+
+// MemoryPublisher keeps published events in memory, it is meant for tests
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []domain.OutboxEvent
+}
+
+// NewMemoryPublisher initializes a new in-memory publisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish records the event
+func (m *MemoryPublisher) Publish(ctx context.Context, event *domain.OutboxEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, *event)
+	return nil
+}
+
+// Events returns the events published so far
+func (m *MemoryPublisher) Events() []domain.OutboxEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]domain.OutboxEvent(nil), m.events...)
+}
+
+// LogPublisher logs every event, it is used when no downstream system is configured
+type LogPublisher struct{}
+
+// Publish logs the event
+func (LogPublisher) Publish(ctx context.Context, event *domain.OutboxEvent) error {
+	log.WithFields(log.Fields{
+		"event_uuid":     event.UUID,
+		"type":           event.Type,
+		"aggregate_uuid": event.AggregateUUID,
+	}).Info("published reservation event")
+	return nil
+}
+
+// Consumer is an EventPublisher every outbox event is delivered to. The deliveries of a consumer are
+// tracked apart from the other consumers' under its name, so a consumer mustn't be renamed
+type Consumer struct {
+	Name      string
+	Publisher EventPublisher
+}
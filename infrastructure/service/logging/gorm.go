@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger logs gorm's messages and queries through logrus, with the fields of the request they were
+// made for: failed queries as errors, slow ones as warnings and the others at the trace level
+type GormLogger struct {
+	// SlowThreshold is how long a query takes before it is logged as slow, zero never does
+	SlowThreshold time.Duration
+	level         gormlogger.LogLevel
+}
+
+// NewGormLogger logs the queries slower than slowThreshold as warnings
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{SlowThreshold: slowThreshold, level: gormlogger.Info}
+}
+
+// LogMode returns a copy of the logger logging at level, gorm silences sessions with it
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		FromContext(ctx).Infof(msg, data...)
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		FromContext(ctx).Warnf(msg, data...)
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		FromContext(ctx).Errorf(msg, data...)
+	}
+}
+
+// Trace logs a query once it has run. Lookups of records that don't exist aren't logged as failures,
+// the callers decide whether that is one
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	query := func() *log.Entry {
+		sql, rows := fc()
+		return FromContext(ctx).WithFields(log.Fields{
+			"sql":         sql,
+			"rows":        rows,
+			"duration_ms": elapsed.Milliseconds(),
+		})
+	}
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		query().WithError(err).Error("query failed")
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.level >= gormlogger.Warn:
+		query().Warn("slow query")
+	case l.level >= gormlogger.Info && log.IsLevelEnabled(log.TraceLevel):
+		query().Trace("query")
+	}
+}
@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the id that correlates the logs of a request across services
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID matches the request ids accepted from callers, anything else is replaced so that a
+// caller can't forge log lines or blow up their size
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// routeParameters are the query parameters and route variables that identify who or what a request is
+// about, they are logged with every line of the request
+var routeParameters = []string{"guest_uuid", "hotel_uuid"}
+
+// responseRecorder remembers the status code and size of the response a handler wrote
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(body []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(body)
+	r.bytes += n
+	return n, err
+}
+
+// Middleware gives every request an id, the caller's X-Request-ID when it sent a valid one, echoes it
+// in the response and logs the request once it has been served. The request's context carries a log,
+// see FromContext, so that every line logged while serving it can be correlated
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ctx := WithLog(r.Context(), log.Fields{
+			"request_id": requestID,
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"remote_ip":  r.RemoteAddr,
+		})
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		entry := FromContext(ctx).WithFields(log.Fields{
+			"status":      recorder.status,
+			"bytes":       recorder.bytes,
+			"duration_ms": time.Since(started).Milliseconds(),
+		})
+		if recorder.status >= http.StatusInternalServerError {
+			entry.Error("request failed")
+			return
+		}
+		entry.Info("request served")
+	})
+}
+
+// RouteMiddleware adds the mux route template, the trace and span ids and the guest and hotel the
+// request is about to its log. It has to run inside the router, after the tracing middleware
+func RouteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fields := log.Fields{}
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				fields["route"] = template
+			}
+		}
+		if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+			fields["trace_id"] = span.TraceID().String()
+			fields["span_id"] = span.SpanID().String()
+		}
+		vars := mux.Vars(r)
+		query := r.URL.Query()
+		for _, parameter := range routeParameters {
+			if value := vars[parameter]; value != "" {
+				fields[parameter] = value
+			} else if value := query.Get(parameter); value != "" {
+				fields[parameter] = value
+			}
+		}
+		AddFields(r.Context(), fields)
+		next.ServeHTTP(w, r)
+	})
+}
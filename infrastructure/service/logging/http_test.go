@@ -0,0 +1,82 @@
+package logging_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+)
+
+func TestMiddleware(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	r := mux.NewRouter()
+	r.Use(logging.RouteMiddleware)
+	r.Path("/api/v1/reservations/{uuid}").Methods(http.MethodPatch).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.AddFields(r.Context(), log.Fields{"guest_uuid": "guest"})
+		logging.FromContext(r.Context()).Info("modifying reservation")
+		w.WriteHeader(http.StatusConflict)
+	})
+	h := logging.Middleware(r)
+
+	tests := []struct {
+		name          string
+		requestID     string
+		wantRequestID string
+	}{
+		{
+			name:          "caller's id is propagated",
+			requestID:     "edge-1f3a.42",
+			wantRequestID: "edge-1f3a.42",
+		},
+		{
+			name: "missing id is generated",
+		},
+		{
+			name:      "invalid id is replaced",
+			requestID: "forged\nline",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook.Reset()
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/reservations/first?hotel_uuid=hotel", nil)
+			if tt.requestID != "" {
+				req.Header.Set(logging.RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			requestID := w.Header().Get(logging.RequestIDHeader)
+			switch {
+			case tt.wantRequestID != "" && requestID != tt.wantRequestID:
+				t.Fatalf("response request id = %q, want %q", requestID, tt.wantRequestID)
+			case tt.wantRequestID == "" && (requestID == "" || requestID == tt.requestID):
+				t.Fatalf("response request id = %q, want a generated one", requestID)
+			}
+
+			entries := hook.AllEntries()
+			if len(entries) != 2 {
+				t.Fatalf("logged %d lines, want the handler's and the request's", len(entries))
+			}
+			for _, entry := range entries {
+				if entry.Data["request_id"] != requestID {
+					t.Errorf("%q logged request id %v, want %q", entry.Message, entry.Data["request_id"], requestID)
+				}
+				if entry.Data["route"] != "/api/v1/reservations/{uuid}" || entry.Data["hotel_uuid"] != "hotel" {
+					t.Errorf("%q logged %v, want the route and hotel", entry.Message, entry.Data)
+				}
+			}
+			served := hook.LastEntry()
+			if served.Data["status"] != http.StatusConflict || served.Data["guest_uuid"] != "guest" {
+				t.Errorf("request logged %v, want its status and the guest the handler added", served.Data)
+			}
+		})
+	}
+}
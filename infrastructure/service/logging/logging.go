@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
+)
+
+// contextKey keys the request's log in a context
+type contextKey struct{}
+
+// requestLog holds the fields every log line of a request carries. Middleware that runs before the
+// route is known creates it, and the handlers further down add to it
+type requestLog struct {
+	mu     sync.Mutex
+	fields log.Fields
+}
+
+// Setup makes logrus write the logs at cfg.Level and above to stdout, in cfg.Format
+func Setup(cfg config.LogConfig) error {
+	level, err := log.ParseLevel(strings.ToLower(cfg.Level))
+	if err != nil {
+		return fmt.Errorf("can't set the log level: %w", err)
+	}
+	log.SetLevel(level)
+	log.SetOutput(os.Stdout)
+	switch cfg.Format {
+	case config.LogFormatText:
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	default:
+		log.SetFormatter(&log.JSONFormatter{})
+	}
+	return nil
+}
+
+// WithLog returns a copy of ctx carrying a request log with fields
+func WithLog(ctx context.Context, fields log.Fields) context.Context {
+	copied := make(log.Fields, len(fields))
+	for key, value := range fields {
+		copied[key] = value
+	}
+	return context.WithValue(ctx, contextKey{}, &requestLog{fields: copied})
+}
+
+// AddFields adds fields to every line logged for the request ctx belongs to, including the line the
+// middleware logs once the request has been served. It does nothing outside of a request
+func AddFields(ctx context.Context, fields log.Fields) {
+	requestLog, ok := ctx.Value(contextKey{}).(*requestLog)
+	if !ok {
+		return
+	}
+	requestLog.mu.Lock()
+	defer requestLog.mu.Unlock()
+	for key, value := range fields {
+		requestLog.fields[key] = value
+	}
+}
+
+// FromContext returns the logger of the request ctx belongs to, or the standard logger outside of a request
+func FromContext(ctx context.Context) *log.Entry {
+	requestLog, ok := ctx.Value(contextKey{}).(*requestLog)
+	if !ok {
+		return log.NewEntry(log.StandardLogger())
+	}
+	requestLog.mu.Lock()
+	defer requestLog.mu.Unlock()
+	return log.WithFields(requestLog.fields)
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// unmatchedRoute labels the requests that didn't match any route, so that unknown paths can't create
+// an unbounded number of series
+const unmatchedRoute = "unmatched"
+
+// statusRecorder remembers the status code a handler wrote
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Middleware records the latency and status code of every request under its mux route template,
+// e.g. /api/v1/reservations/{uuid}, rather than its path
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		route := unmatchedRoute
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		HTTPRequestDuration.
+			WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).
+			Observe(time.Since(started).Seconds())
+	})
+}
@@ -0,0 +1,38 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/metrics"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(metrics.Middleware)
+	r.Path("/api/v1/reservations/{uuid}").Methods(http.MethodPatch).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+	r.Path("/metrics").Handler(metrics.Handler())
+
+	for _, uuid := range []string{"first", "second"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPatch, "/api/v1/reservations/"+uuid, nil))
+	}
+
+	observed := testutil.CollectAndCount(metrics.HTTPRequestDuration, "hotel_http_request_duration_seconds")
+	if observed != 1 {
+		t.Fatalf("expected requests to different reservations to share one series, got %d", observed)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := `hotel_http_request_duration_seconds_count{method="PATCH",route="/api/v1/reservations/{uuid}",status="409"} 2`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected /metrics to expose %s", want)
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric of the service
+const namespace = "hotel"
+
+var (
+	// HTTPRequestDuration is the latency of HTTP requests by route template, method and status code
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// RateLimitedRequests counts the requests rejected because their client ran out of its rate limit
+	RateLimitedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected because their client exceeded the rate limit, by rate limit.",
+	}, []string{"limit"})
+
+	// DBQueryDuration is the latency of database queries by operation and table
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Latency of database queries by operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	// DBQueryErrors counts the database queries that failed, not finding a record isn't a failure
+	DBQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Database queries that failed by operation and table.",
+	}, []string{"operation", "table"})
+
+	// CacheRequests counts cache lookups by cache and result, hit or miss
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	// ReservationsCreated counts the reservations booked, i.e. whose payment was authorized, per hotel
+	ReservationsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reservations",
+		Name:      "created_total",
+		Help:      "Reservations booked per hotel.",
+	}, []string{"hotel_uuid"})
+
+	// ReservationsCancelled counts the reservations cancelled per hotel
+	ReservationsCancelled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reservations",
+		Name:      "cancelled_total",
+		Help:      "Reservations cancelled per hotel.",
+	}, []string{"hotel_uuid"})
+
+	// ReservationsSoldOut counts the reservations and holds rejected because the room type was sold out
+	ReservationsSoldOut = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reservations",
+		Name:      "sold_out_total",
+		Help:      "Reservations and holds rejected because the room type was sold out, per hotel.",
+	}, []string{"hotel_uuid"})
+)
+
+// CacheResult labels a cache lookup
+func CacheResult(hit bool) string {
+	if hit {
+		return "hit"
+	}
+	return "miss"
+}
+
+// RegisterDBStats exposes the statistics of a database connection pool, such as its open, in use and
+// idle connections and how long callers waited for one
+func RegisterDBStats(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the metrics in the prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,19 @@
+package metrics
+
+// Reservations records the outcomes of reservations in their prometheus counters
+type Reservations struct{}
+
+// ReservationCreated counts a reservation whose payment was authorized
+func (Reservations) ReservationCreated(HotelUUID string) {
+	ReservationsCreated.WithLabelValues(HotelUUID).Inc()
+}
+
+// ReservationCancelled counts a cancelled reservation
+func (Reservations) ReservationCancelled(HotelUUID string) {
+	ReservationsCancelled.WithLabelValues(HotelUUID).Inc()
+}
+
+// ReservationSoldOut counts a reservation or hold rejected because its room type was sold out
+func (Reservations) ReservationSoldOut(HotelUUID string) {
+	ReservationsSoldOut.WithLabelValues(HotelUUID).Inc()
+}
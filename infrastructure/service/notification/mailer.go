@@ -0,0 +1,176 @@
+package notification
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Message is an HTML email
+type Message struct {
+	From    string
+	ReplyTo string
+	To      string
+	Subject string
+	HTML    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// smtpTimeout bounds the whole exchange with the SMTP relay when the context has no earlier deadline
+const smtpTimeout = 30 * time.Second
+
+// SMTPMailer sends emails through an SMTP relay
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// From is used when a message doesn't have a sender
+	From string
+	// Timeout bounds the exchange with the relay, from dialing it to quitting
+	Timeout time.Duration
+}
+
+// NewSMTPMailer initializes a new SMTP mailer, authenticating with PLAIN auth when a username is set
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{Host: host, Port: port, Username: username, Password: password, From: from, Timeout: smtpTimeout}
+}
+
+// Send delivers the message to the SMTP relay, giving up when the context is done or the Timeout is
+// over, whichever comes first. The session is upgraded to TLS when the relay supports it
+func (s *SMTPMailer) Send(ctx context.Context, message Message) error {
+	if message.From == "" {
+		message.From = s.From
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	if err := s.send(ctx, message); err != nil {
+		if ctx.Err() != nil {
+			// the session was cut short, whatever step it was at
+			return fmt.Errorf("can't send email to %s: %w: %v", message.To, ctx.Err(), err)
+		}
+		return fmt.Errorf("can't send email to %s: %w", message.To, err)
+	}
+	return nil
+}
+
+// send runs the SMTP session of a message. The connection is closed as soon as the context is done, so
+// no step of the session can outlive it
+func (s *SMTPMailer) send(ctx context.Context, message Message) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.Host, strconv.Itoa(s.Port)))
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(message.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(encode(message)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// FileMailer writes every email to a file in Dir instead of sending it, it is meant for development
+type FileMailer struct {
+	Dir string
+	mu  sync.Mutex
+	n   int
+}
+
+// NewFileMailer initializes a new mailer writing emails to dir
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{Dir: dir}
+}
+
+// Send writes the message to a .eml file
+func (f *FileMailer) Send(ctx context.Context, message Message) error {
+	f.mu.Lock()
+	f.n++
+	name := fmt.Sprintf("%s-%03d.eml", time.Now().Format("20060102T150405"), f.n)
+	f.mu.Unlock()
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return fmt.Errorf("can't create mail directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(f.Dir, name), encode(message), 0o644); err != nil {
+		return fmt.Errorf("can't write email to %s: %w", message.To, err)
+	}
+	return nil
+}
+
+// LogMailer logs every email instead of sending it
+type LogMailer struct{}
+
+// Send logs the message
+func (LogMailer) Send(ctx context.Context, message Message) error {
+	log.WithFields(log.Fields{"to": message.To, "subject": message.Subject}).Info("email sent to log")
+	return nil
+}
+
+// encode formats a message as a MIME email
+func encode(message Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", message.From)
+	if message.ReplyTo != "" {
+		fmt.Fprintf(&b, "Reply-To: %s\r\n", message.ReplyTo)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", message.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	b.WriteString(message.HTML)
+	return []byte(b.String())
+}
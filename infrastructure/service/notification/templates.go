@@ -0,0 +1,81 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// Kind is a kind of guest email
+type Kind string
+
+const (
+	CONFIRMATION Kind = "confirmation"
+	CANCELLATION Kind = "cancellation"
+	REMINDER     Kind = "reminder"
+	// WAITLIST_OFFER tells a waitlisted guest a room has been held for them
+	WAITLIST_OFFER Kind = "waitlist_offer"
+)
+
+// defaultBrandColor is used for hotels that haven't set a brand color
+const defaultBrandColor = "#1f4e79"
+
+// subjects are the subject lines of every kind of email
+var subjects = map[Kind]string{
+	CONFIRMATION:   "Your reservation at %s is confirmed",
+	CANCELLATION:   "Your reservation at %s has been cancelled",
+	REMINDER:       "Your stay at %s is coming up",
+	WAITLIST_OFFER: "A room you were waiting for at %s is available",
+}
+
+// Data is what email templates are rendered with
+type Data struct {
+	Hotel       domain.Hotel
+	Guest       domain.Guest
+	Reservation domain.ReservationEvent
+}
+
+// BrandColor is the hotel's brand color or the default one
+func (d Data) BrandColor() string {
+	if d.Hotel.BrandColor != "" {
+		return d.Hotel.BrandColor
+	}
+	return defaultBrandColor
+}
+
+// Templates renders guest emails, every kind of email shares the branded layout
+type Templates struct {
+	templates map[Kind]*template.Template
+}
+
+// NewTemplates parses the embedded email templates
+func NewTemplates() (*Templates, error) {
+	t := &Templates{templates: map[Kind]*template.Template{}}
+	for kind := range subjects {
+		parsed, err := template.ParseFS(templateFiles, "templates/layout.html", fmt.Sprintf("templates/%s.html", kind))
+		if err != nil {
+			return nil, fmt.Errorf("can't parse %s email template: %w", kind, err)
+		}
+		t.templates[kind] = parsed
+	}
+	return t, nil
+}
+
+// Render renders the subject and HTML body of an email
+func (t *Templates) Render(kind Kind, data Data) (string, string, error) {
+	parsed, ok := t.templates[kind]
+	if !ok {
+		return "", "", fmt.Errorf("unknown email kind %q", kind)
+	}
+	var body bytes.Buffer
+	if err := parsed.ExecuteTemplate(&body, "layout", data); err != nil {
+		return "", "", fmt.Errorf("can't render %s email: %w", kind, err)
+	}
+	return fmt.Sprintf(subjects[kind], data.Hotel.Name), body.String(), nil
+}
@@ -0,0 +1,199 @@
+package notification_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/notification"
+)
+
+func TestTemplates_Render(t *testing.T) {
+	templates, err := notification.NewTemplates()
+	if err != nil {
+		t.Fatalf("NewTemplates() unexpected error = %v", err)
+	}
+	data := notification.Data{
+		Hotel:       domain.Hotel{Name: "Sea View", BrandColor: "#005f73"},
+		Guest:       domain.Guest{FirstName: "<Amina>"},
+		Reservation: domain.ReservationEvent{StartDate: "2023-06-01", EndDate: "2023-06-04", TotalPrice: 300},
+	}
+
+	tests := []struct {
+		kind        notification.Kind
+		wantSubject string
+		wantBody    string
+	}{
+		{kind: notification.CONFIRMATION, wantSubject: "Your reservation at Sea View is confirmed", wantBody: "Total price: 300"},
+		{kind: notification.CANCELLATION, wantSubject: "Your reservation at Sea View has been cancelled", wantBody: "has been cancelled"},
+		{kind: notification.REMINDER, wantSubject: "Your stay at Sea View is coming up", wantBody: "looking forward"},
+		{kind: notification.WAITLIST_OFFER, wantSubject: "A room you were waiting for at Sea View is available", wantBody: "holding it for you"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			subject, body, err := templates.Render(tt.kind, data)
+			if err != nil {
+				t.Fatalf("Templates.Render() unexpected error = %v", err)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("expected subject %q but got %q", tt.wantSubject, subject)
+			}
+			for _, want := range []string{tt.wantBody, "#005f73", "2023-06-01", "&lt;Amina&gt;"} {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected the body to contain %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := t.TempDir()
+	mailer := notification.NewFileMailer(dir)
+	err := mailer.Send(context.Background(), notification.Message{
+		From:    "bookings@example.com",
+		To:      "guest@example.com",
+		Subject: "Your reservation is confirmed",
+		HTML:    "<p>See you soon</p>",
+	})
+	if err != nil {
+		t.Fatalf("FileMailer.Send() unexpected error = %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one email to be written but got %v", len(files))
+	}
+	content, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(content), "To: guest@example.com") || !strings.Contains(string(content), "<p>See you soon</p>") {
+		t.Errorf("unexpected email %s", content)
+	}
+}
+
+// serveSMTP answers the SMTP sessions of listener, stalling after the greeting when stall is set, and
+// sends the data of every message it receives on messages
+func serveSMTP(listener net.Listener, stall bool, messages chan<- string) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			if stall {
+				// never greets, the way a relay that is overloaded or blackholed behaves
+				_, _ = bufio.NewReader(conn).ReadString(0)
+				return
+			}
+			r := bufio.NewReader(conn)
+			reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+			reply("220 test ESMTP")
+			var data strings.Builder
+			inData := false
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if inData {
+					if line == ".\r\n" {
+						inData = false
+						messages <- data.String()
+						reply("250 queued")
+						continue
+					}
+					data.WriteString(line)
+					continue
+				}
+				switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+				case "EHLO", "HELO", "MAIL", "RCPT":
+					reply("250 ok")
+				case "DATA":
+					inData = true
+					reply("354 go ahead")
+				case "QUIT":
+					reply("221 bye")
+					return
+				default:
+					reply("502 not implemented")
+				}
+			}
+		}(conn)
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		stall     bool
+		wantErr   bool
+		wantCause error
+	}{
+		{
+			name: "sent to the relay",
+			ctx:  context.Background(),
+		},
+		{
+			name:      "relay that never answers",
+			ctx:       context.Background(),
+			stall:     true,
+			wantErr:   true,
+			wantCause: context.DeadlineExceeded,
+		},
+		{
+			name:      "cancelled by the caller",
+			ctx:       cancelled,
+			wantErr:   true,
+			wantCause: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("can't listen: %v", err)
+			}
+			defer listener.Close()
+			messages := make(chan string, 1)
+			go serveSMTP(listener, tt.stall, messages)
+			host, port, _ := net.SplitHostPort(listener.Addr().String())
+			portNumber, _ := strconv.Atoi(port)
+			mailer := notification.NewSMTPMailer(host, portNumber, "", "", "hotel@example.com")
+			mailer.Timeout = 200 * time.Millisecond
+
+			started := time.Now()
+			err = mailer.Send(tt.ctx, notification.Message{To: "guest@example.com", Subject: "Welcome", HTML: "<p>Hi</p>"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SMTPMailer.Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantCause != nil && !errors.Is(err, tt.wantCause) {
+				t.Errorf("SMTPMailer.Send() error = %v, want it caused by %v", err, tt.wantCause)
+			}
+			if elapsed := time.Since(started); elapsed > time.Second {
+				t.Errorf("SMTPMailer.Send() took %v, it should give up after its timeout", elapsed)
+			}
+			if tt.wantErr {
+				return
+			}
+			select {
+			case data := <-messages:
+				if !strings.Contains(data, "Subject: Welcome") {
+					t.Errorf("expected the message to be sent but the relay got %q", data)
+				}
+			default:
+				t.Errorf("expected the relay to receive the message")
+			}
+		})
+	}
+}
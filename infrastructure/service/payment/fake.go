@@ -0,0 +1,125 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/google/uuid"
+)
+
+// DeclinedPaymentMethod is a payment method the fake gateway always declines
+const DeclinedPaymentMethod = "tok_declined"
+
+// fakeAuthorization is the fake gateway's record of an authorization
+type fakeAuthorization struct {
+	authorized int
+	captured   int
+	refunded   int
+	voided     bool
+}
+
+// FakeGateway is an in-memory domain.PaymentGateway that approves every payment method except
+// DeclinedPaymentMethod. It enforces the same amount rules as a real provider
+type FakeGateway struct {
+	mu             sync.Mutex
+	authorizations map[string]*fakeAuthorization
+	idempotency    map[string]string
+}
+
+// NewFakeGateway initializes a new in-memory payment gateway
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{
+		authorizations: map[string]*fakeAuthorization{},
+		idempotency:    map[string]string{},
+	}
+}
+
+// Authorize holds funds on the payment method
+func (f *FakeGateway) Authorize(ctx context.Context, request domain.AuthorizeRequest) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if request.PaymentMethod == DeclinedPaymentMethod {
+		return "", ErrDeclined
+	}
+	if request.Amount <= 0 {
+		return "", fmt.Errorf("can't authorize a non positive amount %d", request.Amount)
+	}
+	if reference, ok := f.idempotency[request.IdempotencyKey]; ok && request.IdempotencyKey != "" {
+		return reference, nil
+	}
+	reference := "fake_" + uuid.New().String()
+	f.authorizations[reference] = &fakeAuthorization{authorized: request.Amount}
+	if request.IdempotencyKey != "" {
+		f.idempotency[request.IdempotencyKey] = reference
+	}
+	return reference, nil
+}
+
+// Capture collects up to the authorized amount
+func (f *FakeGateway) Capture(ctx context.Context, reference string, amount int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	authorization, err := f.get(reference)
+	if err != nil {
+		return err
+	}
+	if authorization.voided {
+		return fmt.Errorf("authorization %s has been voided", reference)
+	}
+	if amount <= 0 || authorization.captured+amount > authorization.authorized {
+		return fmt.Errorf("can't capture %d of the %d authorized", amount, authorization.authorized-authorization.captured)
+	}
+	authorization.captured += amount
+	return nil
+}
+
+// Refund gives back up to the captured amount
+func (f *FakeGateway) Refund(ctx context.Context, reference string, amount int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	authorization, err := f.get(reference)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 || authorization.refunded+amount > authorization.captured {
+		return fmt.Errorf("can't refund %d of the %d captured", amount, authorization.captured-authorization.refunded)
+	}
+	authorization.refunded += amount
+	return nil
+}
+
+// Void releases an authorization that hasn't been captured
+func (f *FakeGateway) Void(ctx context.Context, reference string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	authorization, err := f.get(reference)
+	if err != nil {
+		return err
+	}
+	if authorization.captured > 0 {
+		return fmt.Errorf("authorization %s has already been captured", reference)
+	}
+	authorization.voided = true
+	return nil
+}
+
+// Balance reports how much of an authorization has been captured and refunded
+func (f *FakeGateway) Balance(reference string) (captured int, refunded int, voided bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	authorization, ok := f.authorizations[reference]
+	if !ok {
+		return 0, 0, false
+	}
+	return authorization.captured, authorization.refunded, authorization.voided
+}
+
+func (f *FakeGateway) get(reference string) (*fakeAuthorization, error) {
+	authorization, ok := f.authorizations[reference]
+	if !ok {
+		return nil, fmt.Errorf("unknown authorization %s", reference)
+	}
+	return authorization, nil
+}
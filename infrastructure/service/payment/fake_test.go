@@ -0,0 +1,59 @@
+package payment_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/payment"
+	"github.com/brianvoe/gofakeit/v6"
+)
+
+func TestFakeGateway_Authorize(t *testing.T) {
+	ctx := context.Background()
+	gateway := payment.NewFakeGateway()
+	key := gofakeit.UUID()
+
+	reference, err := gateway.Authorize(ctx, domain.AuthorizeRequest{Amount: 500, PaymentMethod: "tok_visa", IdempotencyKey: key})
+	if err != nil {
+		t.Fatalf("FakeGateway.Authorize() unexpected error = %v", err)
+	}
+	retried, err := gateway.Authorize(ctx, domain.AuthorizeRequest{Amount: 500, PaymentMethod: "tok_visa", IdempotencyKey: key})
+	if err != nil || retried != reference {
+		t.Errorf("expected a retried authorization to return %v but got %v, %v", reference, retried, err)
+	}
+	_, err = gateway.Authorize(ctx, domain.AuthorizeRequest{Amount: 500, PaymentMethod: payment.DeclinedPaymentMethod})
+	if !errors.Is(err, payment.ErrDeclined) {
+		t.Errorf("expected the payment to be declined but got %v", err)
+	}
+}
+
+func TestFakeGateway_CaptureRefundVoid(t *testing.T) {
+	ctx := context.Background()
+	gateway := payment.NewFakeGateway()
+	reference, err := gateway.Authorize(ctx, domain.AuthorizeRequest{Amount: 500, PaymentMethod: "tok_visa"})
+	if err != nil {
+		t.Fatalf("FakeGateway.Authorize() unexpected error = %v", err)
+	}
+
+	if err := gateway.Capture(ctx, reference, 600); err == nil {
+		t.Errorf("expected capturing more than was authorized to fail")
+	}
+	if err := gateway.Capture(ctx, reference, 500); err != nil {
+		t.Fatalf("FakeGateway.Capture() unexpected error = %v", err)
+	}
+	if err := gateway.Refund(ctx, reference, 200); err != nil {
+		t.Fatalf("FakeGateway.Refund() unexpected error = %v", err)
+	}
+	if err := gateway.Refund(ctx, reference, 400); err == nil {
+		t.Errorf("expected refunding more than was captured to fail")
+	}
+	if err := gateway.Void(ctx, reference); err == nil {
+		t.Errorf("expected voiding a captured authorization to fail")
+	}
+	captured, refunded, voided := gateway.Balance(reference)
+	if captured != 500 || refunded != 200 || voided {
+		t.Errorf("expected 500 captured and 200 refunded but got %v, %v, voided %v", captured, refunded, voided)
+	}
+}
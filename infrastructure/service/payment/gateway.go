@@ -0,0 +1,11 @@
+package payment
+
+import (
+	"fmt"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+// ErrDeclined is returned when the payment provider refuses to authorize a payment, it is a
+// domain.ErrPaymentDeclined
+var ErrDeclined = fmt.Errorf("%w by the payment provider", domain.ErrPaymentDeclined)
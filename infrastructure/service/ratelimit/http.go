@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/metrics"
+)
+
+// defaultPolicy names the limit shared by the routes that have none of their own
+const defaultPolicy = "default"
+
+// keyPrefix namespaces the buckets in a shared store
+const keyPrefix = "ratelimit"
+
+// policy is a named limit
+type policy struct {
+	name  string
+	limit Limit
+}
+
+// Limiter rejects the requests of the clients that exceed their limit on a route
+type Limiter struct {
+	store Store
+	// routes are the limits of the routes that have their own, keyed by method and route template
+	routes         map[string]policy
+	fallback       policy
+	trustedProxies []*net.IPNet
+}
+
+// NewLimiter limits requests as cfg says, counting them in store
+func NewLimiter(store Store, cfg config.RateLimitConfig) (*Limiter, error) {
+	trustedProxies, err := ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	routes := make(map[string]policy, len(cfg.Routes))
+	for route, limit := range cfg.Routes {
+		routes[route] = policy{name: route, limit: PerMinute(limit.PerMinute, limit.Burst)}
+	}
+	return &Limiter{
+		store:          store,
+		routes:         routes,
+		fallback:       policy{name: defaultPolicy, limit: PerMinute(cfg.Default.PerMinute, cfg.Default.Burst)},
+		trustedProxies: trustedProxies,
+	}, nil
+}
+
+// ParseTrustedProxies parses a list of IPs and CIDRs
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("trusted proxy %q isn't an IP or a CIDR", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q isn't an IP or a CIDR", proxy)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Middleware answers 429 Too Many Requests, with a Retry-After header, to the clients that have run out
+// of requests on the route. It has to run inside the router, it looks the route's limit up by its
+// template. Requests are let through when the store can't be reached, limiting is best effort
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := l.policy(r)
+		key := strings.Join([]string{keyPrefix, policy.name, l.client(r)}, ":")
+		decision, err := l.store.Take(r.Context(), key, policy.limit)
+		if err != nil {
+			logging.FromContext(r.Context()).WithError(err).Error("can't apply the rate limit, letting the request through")
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !decision.Allowed {
+			metrics.RateLimitedRequests.WithLabelValues(policy.name).Inc()
+			retryAfter := int(math.Ceil(decision.RetryAfter.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, fmt.Sprintf("too many requests, retry in %d seconds", retryAfter), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// policy returns the limit of the route r matched
+func (l *Limiter) policy(r *http.Request) policy {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return l.fallback
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return l.fallback
+	}
+	if policy, ok := l.routes[r.Method+" "+template]; ok {
+		return policy
+	}
+	return l.fallback
+}
+
+// client identifies who made r by its IP, the API doesn't authenticate its callers
+func (l *Limiter) client(r *http.Request) string {
+	return "ip:" + l.ClientIP(r)
+}
+
+// ClientIP returns the IP r came from. X-Forwarded-For is only believed when r came through a trusted
+// proxy, and only as far back as the proxies are trusted: the first address from the right that isn't a
+// trusted proxy is the client, any address before it may have been forged by the client
+func (l *Limiter) ClientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !l.trusted(net.ParseIP(remote)) {
+		return remote
+	}
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	client := remote
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		address := strings.TrimSpace(forwarded[i])
+		ip := net.ParseIP(address)
+		if ip == nil {
+			break
+		}
+		client = ip.String()
+		if !l.trusted(ip) {
+			break
+		}
+	}
+	return client
+}
+
+// trusted reports whether ip belongs to a trusted proxy
+func (l *Limiter) trusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range l.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/ratelimit"
+)
+
+// failingStore can't be reached
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Decision, error) {
+	return ratelimit.Decision{}, errors.New("connection refused")
+}
+
+// newRouter limits a reservation route on its own and the availability and guest routes by the default limit
+func newRouter(t *testing.T, store ratelimit.Store) *mux.Router {
+	t.Helper()
+	limiter, err := ratelimit.NewLimiter(store, config.RateLimitConfig{
+		TrustedProxies: []string{"10.0.0.10"},
+		Default:        config.RateLimit{PerMinute: 1, Burst: 2},
+		Routes: map[string]config.RateLimit{
+			"POST /api/v1/reservation": {PerMinute: 1, Burst: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+	r := mux.NewRouter()
+	r.Use(limiter.Middleware)
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	r.Path("/api/v1/reservation").Methods(http.MethodPost).HandlerFunc(ok)
+	r.Path("/api/v1/availability").Methods(http.MethodGet).HandlerFunc(ok)
+	r.Path("/api/v1/guest").Methods(http.MethodPost).HandlerFunc(ok)
+	return r
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	type request struct {
+		method     string
+		path       string
+		remoteAddr string
+		forwarded  string
+		wantStatus int
+	}
+	tests := []struct {
+		name     string
+		store    ratelimit.Store
+		requests []request
+	}{
+		{
+			name:  "route limit",
+			store: ratelimit.NewMemoryStore(),
+			requests: []request{
+				{method: http.MethodPost, path: "/api/v1/reservation", remoteAddr: "192.0.2.1:4000", wantStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/api/v1/reservation", remoteAddr: "192.0.2.1:4001", wantStatus: http.StatusTooManyRequests},
+				// another client has a bucket of its own
+				{method: http.MethodPost, path: "/api/v1/reservation", remoteAddr: "192.0.2.2:4000", wantStatus: http.StatusOK},
+				// the default limit is counted apart from the route's
+				{method: http.MethodGet, path: "/api/v1/availability", remoteAddr: "192.0.2.1:4000", wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name:  "routes without a limit share the default",
+			store: ratelimit.NewMemoryStore(),
+			requests: []request{
+				{method: http.MethodGet, path: "/api/v1/availability", remoteAddr: "192.0.2.1:4000", wantStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/api/v1/guest", remoteAddr: "192.0.2.1:4000", wantStatus: http.StatusOK},
+				{method: http.MethodGet, path: "/api/v1/availability", remoteAddr: "192.0.2.1:4000", wantStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:  "clients behind the trusted proxy are told apart",
+			store: ratelimit.NewMemoryStore(),
+			requests: []request{
+				{method: http.MethodPost, path: "/api/v1/reservation", remoteAddr: "10.0.0.10:5000", forwarded: "198.51.100.1", wantStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/api/v1/reservation", remoteAddr: "10.0.0.10:5000", forwarded: "198.51.100.2", wantStatus: http.StatusOK},
+				// a client can't get a fresh bucket by forging the header
+				{method: http.MethodPost, path: "/api/v1/reservation", remoteAddr: "10.0.0.10:5000", forwarded: "203.0.113.9, 198.51.100.1", wantStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:  "forwarded header is ignored from untrusted clients",
+			store: ratelimit.NewMemoryStore(),
+			requests: []request{
+				{method: http.MethodPost, path: "/api/v1/reservation", remoteAddr: "192.0.2.1:4000", forwarded: "198.51.100.1", wantStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/api/v1/reservation", remoteAddr: "192.0.2.1:4000", forwarded: "198.51.100.2", wantStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name:  "requests go through when the store can't be reached",
+			store: failingStore{},
+			requests: []request{
+				{method: http.MethodPost, path: "/api/v1/reservation", remoteAddr: "192.0.2.1:4000", wantStatus: http.StatusOK},
+				{method: http.MethodPost, path: "/api/v1/reservation", remoteAddr: "192.0.2.1:4000", wantStatus: http.StatusOK},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRouter(t, tt.store)
+			for i, request := range tt.requests {
+				req := httptest.NewRequest(request.method, request.path, nil)
+				req.RemoteAddr = request.remoteAddr
+				if request.forwarded != "" {
+					req.Header.Set("X-Forwarded-For", request.forwarded)
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				if w.Code != request.wantStatus {
+					t.Fatalf("request %d status = %d, want %d", i, w.Code, request.wantStatus)
+				}
+				if w.Code != http.StatusTooManyRequests {
+					continue
+				}
+				// a token comes back every minute
+				retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+				if err != nil || retryAfter < 1 || retryAfter > 60 {
+					t.Fatalf("request %d Retry-After = %q, want between 1 and 60 seconds", i, w.Header().Get("Retry-After"))
+				}
+			}
+		})
+	}
+}
+
+func TestLimiter_ClientIP(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), config.RateLimitConfig{
+		TrustedProxies: []string{"10.0.0.0/8", "2001:db8::1"},
+		Default:        config.RateLimit{PerMinute: 1, Burst: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "192.0.2.1:4000",
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "untrusted client's header is ignored",
+			remoteAddr: "192.0.2.1:4000",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "192.0.2.1",
+		},
+		{
+			name:       "client behind a chain of trusted proxies",
+			remoteAddr: "10.0.0.10:5000",
+			forwarded:  []string{"203.0.113.9, 198.51.100.1", "10.1.2.3"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "trusted IPv6 proxy",
+			remoteAddr: "[2001:db8::1]:5000",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "garbage stops the walk",
+			remoteAddr: "10.0.0.10:5000",
+			forwarded:  []string{"198.51.100.1, unknown"},
+			want:       "10.0.0.10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, forwarded := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", forwarded)
+			}
+			if got := limiter.ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store forgets the buckets that have refilled
+const sweepInterval = time.Minute
+
+// Limit is a token bucket: it holds up to Burst tokens and refills at Rate tokens a second.
+// Every request takes a token and is rejected when there is none left
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute lets perMinute requests a minute through on average, and up to burst at once
+func PerMinute(perMinute float64, burst int) Limit {
+	return Limit{Rate: perMinute / 60, Burst: burst}
+}
+
+// Decision is whether a request may go through, and when the next one may when it can't
+type Decision struct {
+	Allowed bool
+	// Remaining is how many more requests may go through right away
+	Remaining int
+	// RetryAfter is how long until the next token is available, zero when the request is allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets of the clients
+type Store interface {
+	// Take takes a token from the bucket at key, which is full the first time it is used
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+// bucket is the state of one client's token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled, the bucket can be forgotten after it
+	full time.Time
+}
+
+// MemoryStore keeps the buckets in memory. Every replica of the service counts on its own, so a client
+// can make as many times more requests as there are replicas
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Take takes a token from the bucket at key
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	decision := take(b, limit, now)
+	b.full = now.Add(seconds((float64(limit.Burst) - b.tokens) / limit.Rate))
+	return decision, nil
+}
+
+// sweep forgets the buckets that have refilled, they are the same as the ones that don't exist
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !b.full.After(now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// take refills b for the time elapsed since it was last updated and takes a token from it
+func take(b *bucket, limit Limit, now time.Time) Decision {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	}
+	b.updated = now
+	if b.tokens < 1 {
+		return Decision{RetryAfter: seconds((1 - b.tokens) / limit.Rate)}
+	}
+	b.tokens--
+	return Decision{Allowed: true, Remaining: int(b.tokens)}
+}
+
+// seconds converts a number of seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
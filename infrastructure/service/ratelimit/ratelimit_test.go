@@ -0,0 +1,35 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/ratelimit"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	ctx := context.Background()
+	store := ratelimit.NewMemoryStore()
+	// a token every 10ms
+	limit := ratelimit.Limit{Rate: 100, Burst: 2}
+
+	for i, wantRemaining := range []int{1, 0} {
+		decision, err := store.Take(ctx, "client", limit)
+		if err != nil || !decision.Allowed || decision.Remaining != wantRemaining {
+			t.Fatalf("take %d = %+v, %v, want allowed with %d remaining", i, decision, err, wantRemaining)
+		}
+	}
+	decision, err := store.Take(ctx, "client", limit)
+	if err != nil || decision.Allowed {
+		t.Fatalf("take past the burst = %+v, %v, want it rejected", decision, err)
+	}
+	if decision.RetryAfter <= 0 || decision.RetryAfter > 10*time.Millisecond {
+		t.Fatalf("RetryAfter = %s, want at most the time a token takes to come back", decision.RetryAfter)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if decision, err := store.Take(ctx, "client", limit); err != nil || !decision.Allowed {
+		t.Fatalf("take once refilled = %+v, %v, want it allowed", decision, err)
+	}
+}
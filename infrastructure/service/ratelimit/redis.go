@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// takeScript takes a token from the bucket at KEYS[1] refilling at ARGV[1] tokens a second up to ARGV[2].
+// It runs atomically on redis' clock, so that every replica sees the same buckets whatever their clocks.
+// The bucket expires once it has refilled. Floats are returned as strings, redis truncates Lua numbers
+var takeScript = redis.NewScript(`
+redis.replicate_commands()
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+	tokens = burst
+	updated = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry_after = (1 - tokens) / rate
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens), tostring(retry_after)}
+`)
+
+// RedisStore keeps the buckets in redis so that the replicas of the service share them
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore keeps the buckets in the redis client connects to
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Take takes a token from the bucket at key
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	result, err := takeScript.Run(ctx, s.client, []string{key}, limit.Rate, limit.Burst).Slice()
+	if err != nil {
+		return Decision{}, fmt.Errorf("infrastructure: can't take a rate limit token: %v", err)
+	}
+	if len(result) != 3 {
+		return Decision{}, fmt.Errorf("infrastructure: unexpected rate limit script result %v", result)
+	}
+	allowed, _ := result[0].(int64)
+	tokens, err := parseFloat(result[1])
+	if err != nil {
+		return Decision{}, err
+	}
+	retryAfter, err := parseFloat(result[2])
+	if err != nil {
+		return Decision{}, err
+	}
+	if allowed != 1 {
+		return Decision{RetryAfter: seconds(retryAfter)}, nil
+	}
+	return Decision{Allowed: true, Remaining: int(tokens)}, nil
+}
+
+// parseFloat parses a float the rate limit script returned as a string
+func parseFloat(value interface{}) (float64, error) {
+	text, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("infrastructure: unexpected rate limit script value %v", value)
+	}
+	parsed, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("infrastructure: unexpected rate limit script value %q: %v", text, err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,49 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/tracing"
+)
+
+func TestGormPlugin(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	// a dry run builds the statements without a database
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("can't open gorm: %v", err)
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		t.Fatalf("can't use the tracing plugin: %v", err)
+	}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "usecase.GetGuest")
+	var guests []domain.Guest
+	db.WithContext(ctx).Where(&domain.Guest{Email: "guest@example.com"}).Find(&guests)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected a query span and its parent, got %d spans", len(spans))
+	}
+	query := spans[0]
+	if query.Name() != "gorm.query guests" {
+		t.Errorf("query span is named %q, want %q", query.Name(), "gorm.query guests")
+	}
+	if query.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected the query span to be a child of the span in the query's context")
+	}
+}
@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+const (
+	// SignatureHeader carries the timestamp and HMAC-SHA256 signature of a delivery as t=<unix>,v1=<hex>
+	SignatureHeader = "X-Webhook-Signature"
+	// EventTypeHeader carries the type of the delivered event
+	EventTypeHeader = "X-Webhook-Event"
+	// EventIDHeader carries the event's UUID, receivers use it to ignore redelivered events
+	EventIDHeader = "X-Webhook-Event-ID"
+	// requestTimeout bounds how long a receiver can take to acknowledge a delivery
+	requestTimeout = 10 * time.Second
+)
+
+// Sender POSTs signed events to webhook receivers
+type Sender struct {
+	Client *http.Client
+	// Now is the clock used to timestamp signatures
+	Now func() time.Time
+}
+
+// ErrInternalAddress is returned when a receiver resolves to an address of the service's own network
+var ErrInternalAddress = errors.New("webhook receivers can't be on an internal address")
+
+// NewSender initializes a new webhook sender. It refuses to connect to internal addresses, whatever the
+// receiver's hostname resolves to when the delivery is made and wherever it redirects to, and it ignores
+// proxy settings so that it connects to receivers itself
+func NewSender() *Sender {
+	dialer := &net.Dialer{Timeout: requestTimeout, Control: refuseInternalAddresses}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Sender{
+		Client: &http.Client{Timeout: requestTimeout, Transport: transport},
+		Now:    time.Now,
+	}
+}
+
+// refuseInternalAddresses is the dialer's Control, it runs on the resolved address of every connection
+func refuseInternalAddresses(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || domain.IsInternalAddress(ip) {
+		return fmt.Errorf("%w: %s", ErrInternalAddress, host)
+	}
+	return nil
+}
+
+// Sign computes the hex HMAC-SHA256 of the timestamp and body with the subscription's secret.
+// Signing the timestamp lets receivers reject replayed deliveries
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a SignatureHeader value against the body, receivers use it to authenticate deliveries
+func Verify(secret string, header string, body []byte) bool {
+	var timestamp int64
+	var signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signature = value
+		}
+	}
+	expected := Sign(secret, timestamp, body)
+	return timestamp != 0 && hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// Deliver POSTs an event to a receiver, returning the response status code.
+// Anything but a 2xx response is an error
+func (s *Sender) Deliver(
+	ctx context.Context,
+	url string,
+	secret string,
+	eventUUID string,
+	eventType string,
+	body []byte,
+) (int, error) {
+	timestamp := s.Now().Unix()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("can't build webhook request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventTypeHeader, eventType)
+	request.Header.Set(EventIDHeader, eventUUID)
+	request.Header.Set(SignatureHeader, fmt.Sprintf("t=%d,v1=%s", timestamp, Sign(secret, timestamp, body)))
+
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("can't reach webhook receiver: %w", err)
+	}
+	defer response.Body.Close()
+	// drain the body so that the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook receiver responded with %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
@@ -0,0 +1,94 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/webhook"
+	"github.com/brianvoe/gofakeit/v6"
+)
+
+func TestSender_Deliver(t *testing.T) {
+	ctx := context.Background()
+	secret := gofakeit.Password(true, true, true, false, false, 32)
+	body := []byte(`{"type":"reservation.created"}`)
+
+	tests := []struct {
+		name       string
+		status     int
+		guarded    bool
+		wantStatus int
+		wantErr    bool
+	}{
+		{
+			name:       "Happy case: receiver acknowledges the event",
+			status:     http.StatusNoContent,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "Sad case: receiver fails",
+			status:     http.StatusInternalServerError,
+			wantStatus: http.StatusInternalServerError,
+			wantErr:    true,
+		},
+		{
+			name:    "Sad case: receiver on an internal address is refused",
+			status:  http.StatusNoContent,
+			guarded: true,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verified := false
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received, _ := io.ReadAll(r.Body)
+				verified = webhook.Verify(secret, r.Header.Get(webhook.SignatureHeader), received) &&
+					r.Header.Get(webhook.EventIDHeader) == "event"
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			sender := webhook.NewSender()
+			if !tt.guarded {
+				// the test receiver listens on the loopback address the sender refuses
+				sender.Client = receiver.Client()
+			}
+			status, err := sender.Deliver(ctx, receiver.URL, secret, "event", "reservation.created", body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Sender.Deliver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if status != tt.wantStatus {
+				t.Errorf("expected status %v but got %v", tt.wantStatus, status)
+			}
+			if tt.guarded {
+				if !errors.Is(err, webhook.ErrInternalAddress) || verified {
+					t.Errorf("expected the delivery to be refused before reaching the receiver but got %v", err)
+				}
+				return
+			}
+			if !verified {
+				t.Errorf("expected the receiver to verify the delivery's signature")
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"reservation.cancelled"}`)
+	signature := webhook.Sign("a-very-long-secret", 1685620800, body)
+
+	if !webhook.Verify("a-very-long-secret", "t=1685620800,v1="+signature, body) {
+		t.Errorf("expected a valid signature to verify")
+	}
+	if webhook.Verify("another-long-secret", "t=1685620800,v1="+signature, body) {
+		t.Errorf("expected a signature made with another secret not to verify")
+	}
+	if webhook.Verify("a-very-long-secret", "t=1685620801,v1="+signature, body) {
+		t.Errorf("expected a signature with a tampered timestamp not to verify")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/database"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | version"
+
+// runMigrate runs the migrate subcommand: up applies every pending migration, down reverts the last
+// steps migrations, one by default, and version prints the database's schema version
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	db, err := database.Connect(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(ctx, db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("invalid number of steps %q: %w", args[1], err)
+			}
+		}
+		reverted, err := database.MigrateDown(ctx, db, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %d migration(s)\n", reverted)
+	case "version":
+		current, err := database.SchemaVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		latest, err := database.LatestSchemaVersion()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("schema version %d, latest %d\n", current, latest)
+	default:
+		return errors.New(migrateUsage)
+	}
+	return nil
+}
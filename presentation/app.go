@@ -0,0 +1,119 @@
+package presentation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/presentation/health"
+)
+
+// Worker is a background job that runs until its context is cancelled
+type Worker struct {
+	Name string
+	Run  func(ctx context.Context)
+}
+
+// Closer releases a connection shared by the service, such as the database's pool
+type Closer struct {
+	Name  string
+	Close func() error
+}
+
+// App is the running service: its HTTP server, its background workers and the connections they share
+type App struct {
+	Server  *http.Server
+	Workers []Worker
+	// Closers are closed in order once the server and the workers have stopped
+	Closers []Closer
+	// ShutdownTimeout bounds how long in-flight requests and workers are waited for on shutdown
+	ShutdownTimeout time.Duration
+	// Health is drained as soon as shutdown starts so that the readiness probe fails
+	Health *health.Health
+	// DrainDelay is how long requests are still accepted once Health is drained, for the load balancer to
+	// observe the readiness probe failing
+	DrainDelay time.Duration
+}
+
+// Run serves HTTP requests and runs the background workers until ctx is cancelled or the server fails.
+// It then fails the readiness probe, keeps serving for DrainDelay, stops accepting requests, waits for the
+// in-flight ones and the workers up to ShutdownTimeout, and closes the shared connections
+func (a *App) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", a.Server.Addr)
+	if err != nil {
+		a.close()
+		return fmt.Errorf("can't listen on %s: %w", a.Server.Addr, err)
+	}
+
+	// workers get their own context so that they keep running while in-flight requests drain
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	for _, worker := range a.Workers {
+		workers.Add(1)
+		go func(worker Worker) {
+			defer workers.Done()
+			worker.Run(workerCtx)
+			log.Infof("%s stopped", worker.Name)
+		}(worker)
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- a.Server.Serve(listener)
+	}()
+	log.Infof("server up and running at %s", listener.Addr())
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Info("shutting down")
+	case err := <-serverErr:
+		runErr = fmt.Errorf("server stopped unexpectedly: %w", err)
+	}
+
+	if a.Health != nil {
+		a.Health.Drain()
+	}
+	if runErr == nil && a.DrainDelay > 0 {
+		log.Infof("draining for %s before refusing new requests", a.DrainDelay)
+		time.Sleep(a.DrainDelay)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.ShutdownTimeout)
+	defer cancel()
+	if err := a.Server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Errorf("can't drain in-flight requests: %v", err)
+		a.Server.Close()
+	}
+
+	stopWorkers()
+	stopped := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		log.Errorf("background workers didn't stop within %s", a.ShutdownTimeout)
+	}
+
+	a.close()
+	log.Info("server stopped")
+	return runErr
+}
+
+// close closes the shared connections in order
+func (a *App) close() {
+	for _, closer := range a.Closers {
+		if err := closer.Close(); err != nil {
+			log.Errorf("can't close %s: %v", closer.Name, err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package presentation_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/presentation"
+	"github.com/MelvinKim/Hotel-Reservation-System/presentation/health"
+)
+
+// freeAddr returns a local address nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestApp_Run(t *testing.T) {
+	addr := freeAddr(t)
+	requestStarted := make(chan struct{})
+	releaseRequest := make(chan struct{})
+	var order []string
+
+	app := &presentation.App{
+		Server: &http.Server{
+			Addr: addr,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(requestStarted)
+				<-releaseRequest
+				fmt.Fprint(w, "done")
+			}),
+		},
+		Workers: []presentation.Worker{
+			{Name: "worker", Run: func(ctx context.Context) {
+				<-ctx.Done()
+				order = append(order, "worker")
+			}},
+		},
+		Closers: []presentation.Closer{
+			{Name: "database", Close: func() error {
+				order = append(order, "database")
+				return nil
+			}},
+		},
+		ShutdownTimeout: 5 * time.Second,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- app.Run(ctx)
+	}()
+
+	response := make(chan string, 1)
+	go func() {
+		var resp *http.Response
+		var err error
+		// the server may not be listening yet
+		for attempt := 0; attempt < 50; attempt++ {
+			if resp, err = http.Get("http://" + addr); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			response <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		response <- string(body)
+	}()
+
+	<-requestStarted
+	cancel()
+	// the in-flight request is still served after shutdown has started
+	time.Sleep(50 * time.Millisecond)
+	close(releaseRequest)
+
+	if got := <-response; got != "done" {
+		t.Fatalf("in-flight request got %q, want it to complete", got)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("App.Run() error = %v", err)
+	}
+	if len(order) != 2 || order[0] != "worker" || order[1] != "database" {
+		t.Fatalf("shutdown order = %v, want the workers to stop before the database is closed", order)
+	}
+	if _, err := http.Get("http://" + addr); err == nil {
+		t.Fatalf("expected the server to stop accepting requests")
+	}
+}
+
+func TestApp_Run_drainDelay(t *testing.T) {
+	addr := freeAddr(t)
+	probes := health.NewHealth()
+	app := &presentation.App{
+		Server: &http.Server{
+			Addr: addr,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "done")
+			}),
+		},
+		ShutdownTimeout: 5 * time.Second,
+		Health:          probes,
+		DrainDelay:      500 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- app.Run(ctx)
+	}()
+	// the server may not be listening yet
+	for attempt := 0; attempt < 50; attempt++ {
+		resp, err := http.Get("http://" + addr)
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	for attempt := 0; attempt < 50 && probes.Check(context.Background()).Status == "ready"; attempt++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if status := probes.Check(context.Background()).Status; status == "ready" {
+		t.Fatalf("readiness = %q once shutdown started, want it to fail", status)
+	}
+	// the load balancer still sends requests until it notices
+	resp, err := http.Get("http://" + addr)
+	if err != nil {
+		t.Fatalf("request during the drain delay failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if err := <-stopped; err != nil {
+		t.Fatalf("App.Run() error = %v", err)
+	}
+	if _, err := http.Get("http://" + addr); err == nil {
+		t.Fatalf("expected the server to stop accepting requests after the drain delay")
+	}
+}
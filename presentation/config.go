@@ -5,79 +5,255 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
 	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/database"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/events"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/metrics"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/notification"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/payment"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/ratelimit"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/tracing"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/webhook"
+	"github.com/MelvinKim/Hotel-Reservation-System/presentation/health"
 	"github.com/MelvinKim/Hotel-Reservation-System/presentation/interactor"
 	"github.com/MelvinKim/Hotel-Reservation-System/presentation/rest"
 	"github.com/MelvinKim/Hotel-Reservation-System/usecase"
+	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 const (
 	serverTimeoutSeconds = 120
+	// holdReaperInterval is how often expired reservation holds are released
+	holdReaperInterval = time.Minute
+	// eventRelayInterval is how often reservation events are published from the outbox, and how often
+	// each consumer consumes them
+	eventRelayInterval = 5 * time.Second
+	// webhookDispatchInterval is how often due webhook deliveries are attempted
+	webhookDispatchInterval = 5 * time.Second
+	// arrivalReminderInterval is how often upcoming stays are checked for arrival reminders
+	arrivalReminderInterval = time.Hour
+	// blockReleaseInterval is how often group blocks past their release date are given back
+	blockReleaseInterval = 10 * time.Minute
+	// paymentSettleInterval is how often refunds owed on cancelled reservations are retried
+	paymentSettleInterval = time.Minute
+	// tracingFlushTimeout bounds how long the last spans are exported for on shutdown
+	tracingFlushTimeout = 5 * time.Second
+	// readinessCheckTimeout bounds each dependency check of the readiness probe
+	readinessCheckTimeout = 2 * time.Second
 )
 
 var allowedHeaders = []string{
 	"Authorization", "Accept", "Accept-Charset", "Accept-Language",
 	"Accept-Encoding", "Origin", "Host", "User-Agent", "Content-Length",
 	"Content-Type", " X-Authorization", " Access-Control-Allow-Origin", "Access-Control-Allow-Methods", "Access-Control-Allow-Headers",
+	"traceparent", "tracestate", logging.RequestIDHeader,
 }
 
-// Router sets up the gorilla Mux router
-func Router(ctx context.Context) (*mux.Router, error) {
-	create := database.NewPostgresDB()
-	get := database.NewPostgresDB()
-	update := database.NewPostgresDB()
-	hotel := usecase.NewUseCase(create, get, update)
-
-	// Initialize the interactor
-	i, err := interactor.NewHotelInteractor(hotel)
-	if err != nil {
-		return nil, fmt.Errorf("can't instantiate service: %w", err)
-	}
+// Router sets up the gorilla Mux router, apiMiddlewares only wrap the versioned API
+func Router(h rest.PresentationHandlers, probes *health.Health, apiMiddlewares ...mux.MiddlewareFunc) *mux.Router {
+	r := mux.NewRouter()
 
-	h := rest.NewPresentationHandlers(i)
+	r.Use(metrics.Middleware)
 
-	r := mux.NewRouter()
+	// the probes and metrics sit outside the versioned API
+	r.Path("/metrics").Methods(http.MethodGet).Handler(metrics.Handler())
+	r.Path("/healthz").Methods(http.MethodGet).HandlerFunc(probes.Liveness())
+	r.Path("/readyz").Methods(http.MethodGet).HandlerFunc(probes.Readiness())
 
 	hotelRoutes := r.PathPrefix("/api/v1").Subrouter()
+	hotelRoutes.Use(apiMiddlewares...)
 	hotelRoutes.Path("/guest").Methods(http.MethodPost).HandlerFunc(h.CreateGuest())
 	hotelRoutes.Path("/reservation").Methods(http.MethodPost).HandlerFunc(h.CreateReservation())
+	hotelRoutes.Path("/reservations/lookup").Methods(http.MethodGet).HandlerFunc(h.LookupReservation())
+	hotelRoutes.Path("/reservations/{uuid}").Methods(http.MethodPatch).HandlerFunc(h.ModifyReservation())
+	hotelRoutes.Path("/reservation/hold").Methods(http.MethodPost).HandlerFunc(h.HoldReservation())
+	hotelRoutes.Path("/reservation/confirm").Methods(http.MethodPost).HandlerFunc(h.ConfirmReservation())
+	hotelRoutes.Path("/check-in").Methods(http.MethodPost).HandlerFunc(h.CheckIn())
+	hotelRoutes.Path("/check-out").Methods(http.MethodPost).HandlerFunc(h.CheckOut())
+	hotelRoutes.Path("/waitlist").Methods(http.MethodPost).HandlerFunc(h.JoinWaitlist())
+	hotelRoutes.Path("/loyalty/balance").Methods(http.MethodGet).HandlerFunc(h.GetLoyaltyBalance())
+	hotelRoutes.Path("/loyalty/history").Methods(http.MethodGet).HandlerFunc(h.GetLoyaltyHistory())
+	hotelRoutes.Path("/bookings").Methods(http.MethodPost).HandlerFunc(h.CreateBooking())
+	hotelRoutes.Path("/bookings").Methods(http.MethodGet).HandlerFunc(h.GetBooking())
+	hotelRoutes.Path("/bookings/cancel-line").Methods(http.MethodPost).HandlerFunc(h.CancelBookingLine())
+	hotelRoutes.Path("/bookings/confirm").Methods(http.MethodPost).HandlerFunc(h.ConfirmBookingBlock())
+	hotelRoutes.Path("/overbooking").Methods(http.MethodPost).HandlerFunc(h.SetOverbookingLimits())
+	hotelRoutes.Path("/reports/walk-outs").Methods(http.MethodGet).HandlerFunc(h.GetWalkOutReport())
+	hotelRoutes.Path("/webhooks").Methods(http.MethodPost).HandlerFunc(h.CreateWebhookSubscription())
+	hotelRoutes.Path("/webhooks/deliveries").Methods(http.MethodGet).HandlerFunc(h.GetWebhookDeliveries())
+	hotelRoutes.Path("/capture-payment").Methods(http.MethodPost).HandlerFunc(h.CapturePayment())
 	hotelRoutes.Path("/cancel-reservation").Methods(http.MethodPost).HandlerFunc(h.CancelReservation())
+	hotelRoutes.Path("/rates/bulk").Methods(http.MethodPost).HandlerFunc(h.BulkUpsertRates())
+	hotelRoutes.Path("/rates/upload").Methods(http.MethodPost).HandlerFunc(h.UploadRateCalendar())
+	hotelRoutes.Path("/rate-plans").Methods(http.MethodPost).HandlerFunc(h.CreateRatePlan())
+	hotelRoutes.Path("/rate-plans/rates").Methods(http.MethodPost).HandlerFunc(h.SetRatePlanRates())
+	hotelRoutes.Path("/availability").Methods(http.MethodGet).HandlerFunc(h.SearchAvailability())
+	hotelRoutes.Path("/pricing-rules").Methods(http.MethodPost).HandlerFunc(h.CreatePricingRule())
+	hotelRoutes.Path("/pricing-rules").Methods(http.MethodGet).HandlerFunc(h.GetPricingRules())
+	hotelRoutes.Path("/pricing-rules/preview").Methods(http.MethodPost).HandlerFunc(h.PreviewPricing())
+	hotelRoutes.Path("/promotions").Methods(http.MethodPost).HandlerFunc(h.CreatePromotion())
 
-	return r, nil
+	return r
 }
 
-// PrepareServer starts up a server
+// newMailer sends guest emails through the SMTP server when one is configured, writes them to the mail
+// directory when that is configured instead, and otherwise logs them
+func newMailer(cfg config.MailConfig) notification.Mailer {
+	if cfg.SMTP.Host != "" {
+		return notification.NewSMTPMailer(
+			cfg.SMTP.Host,
+			cfg.SMTP.Port,
+			cfg.SMTP.Username,
+			cfg.SMTP.Password,
+			cfg.SMTP.From,
+		)
+	}
+	if cfg.Dir != "" {
+		return notification.NewFileMailer(cfg.Dir)
+	}
+	return notification.LogMailer{}
+}
+
+// newRateLimitStore counts requests in redis when the replicas are to share their counts, and in memory
+// otherwise. The redis client is returned so that it can be checked and closed, it is nil for the memory
+// store
+func newRateLimitStore(cfg config.RateLimitConfig) (ratelimit.Store, *redis.Client) {
+	if cfg.Store == config.RateLimitStoreRedis {
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		return ratelimit.NewRedisStore(client), client
+	}
+	return ratelimit.NewMemoryStore(), nil
+}
+
+// PrepareServer wires the service up: its database, use cases, background workers and HTTP server.
+// Nothing runs until App.Run is called, and nothing is left open when it fails
 func PrepareServer(
 	ctx context.Context,
-	port int,
-) *http.Server {
-	// start up  the router
-	r, err := Router(ctx)
+	cfg *config.Config,
+) (*App, error) {
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return nil, fmt.Errorf("can't set up tracing: %w", err)
+	}
+	tracingCloser := Closer{Name: "tracing", Close: func() error {
+		// exports the spans still buffered
+		ctx, cancel := context.WithTimeout(context.Background(), tracingFlushTimeout)
+		defer cancel()
+		return shutdownTracing(ctx)
+	}}
+	// closers are the connections opened so far, tracing is closed after them so that it exports their spans
+	var closers []Closer
+	fail := func(err error) (*App, error) {
+		(&App{Closers: append(closers, tracingCloser)}).close()
+		return nil, err
+	}
+
+	// one connection pool is shared by every repository
+	db, err := database.NewPostgresDB(ctx, cfg.Database)
 	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Error("Server startup error")
+		return fail(fmt.Errorf("can't connect to the database: %w", err))
+	}
+	closers = append(closers, Closer{Name: "database", Close: db.Close})
+	sqlDB, err := db.SQLDB()
+	if err != nil {
+		return fail(fmt.Errorf("can't get the database pool: %w", err))
+	}
+	if err := metrics.RegisterDBStats(sqlDB, cfg.Database.Name); err != nil {
+		return fail(fmt.Errorf("can't expose the database pool statistics: %w", err))
+	}
+	// no payment provider has been integrated yet, the fake gateway approves every card
+	// except payment.DeclinedPaymentMethod
+	payments := payment.NewFakeGateway()
+	hotel, err := usecase.NewUseCase(db, db, db, payments, metrics.Reservations{})
+	if err != nil {
+		return fail(fmt.Errorf("can't instantiate service: %w", err))
+	}
+	templates, err := notification.NewTemplates()
+	if err != nil {
+		return fail(fmt.Errorf("can't load email templates: %w", err))
+	}
+	// every consumer gets each event on its own, renaming one delivers it every event again
+	consumers := []events.Consumer{
+		{Name: "log", Publisher: events.LogPublisher{}},
+		{Name: "webhooks", Publisher: events.PublisherFunc(hotel.EnqueueWebhookDeliveries)},
+		{Name: "guest_emails", Publisher: hotel.GuestNotifier(newMailer(cfg.Mail), templates)},
+		{Name: "waitlist_offers", Publisher: events.PublisherFunc(hotel.OfferWaitlistedRooms)},
+		{Name: "loyalty_ledger", Publisher: events.PublisherFunc(hotel.UpdateLoyaltyLedger)},
+	}
+	sender := webhook.NewSender()
+	workers := []Worker{
+		{Name: "hold reaper", Run: func(ctx context.Context) { hotel.RunHoldReaper(ctx, holdReaperInterval) }},
+		{Name: "event relay", Run: func(ctx context.Context) { hotel.RunEventRelay(ctx, consumers, eventRelayInterval) }},
+		{Name: "webhook dispatcher", Run: func(ctx context.Context) { hotel.RunWebhookDispatcher(ctx, sender, webhookDispatchInterval) }},
+		{Name: "arrival reminders", Run: func(ctx context.Context) { hotel.RunArrivalReminders(ctx, arrivalReminderInterval) }},
+		{Name: "block releaser", Run: func(ctx context.Context) { hotel.RunBlockReleaser(ctx, blockReleaseInterval) }},
+		{Name: "payment settler", Run: func(ctx context.Context) { hotel.RunPaymentSettler(ctx, paymentSettleInterval) }},
+	}
+	for _, consumer := range consumers {
+		consumer := consumer
+		workers = append(workers, Worker{Name: "event consumer " + consumer.Name, Run: func(ctx context.Context) {
+			hotel.RunEventConsumer(ctx, consumer, eventRelayInterval)
+		}})
 	}
 
+	// Initialize the interactor
+	i, err := interactor.NewHotelInteractor(hotel)
+	if err != nil {
+		return fail(fmt.Errorf("can't instantiate service: %w", err))
+	}
+
+	checks := []health.Check{{Name: "postgres", Timeout: readinessCheckTimeout, Check: db.Ping}}
+	var apiMiddlewares []mux.MiddlewareFunc
+	if cfg.RateLimit.Enabled {
+		store, client := newRateLimitStore(cfg.RateLimit)
+		if client != nil {
+			closers = append(closers, Closer{Name: "rate limit store", Close: client.Close})
+			// every API request goes through the store
+			checks = append(checks, health.Check{Name: "redis", Timeout: readinessCheckTimeout, Check: func(ctx context.Context) error {
+				return client.Ping(ctx).Err()
+			}})
+		}
+		limiter, err := ratelimit.NewLimiter(store, cfg.RateLimit)
+		if err != nil {
+			return fail(fmt.Errorf("can't set up rate limiting: %w", err))
+		}
+		apiMiddlewares = append(apiMiddlewares, limiter.Middleware)
+	}
+	closers = append(closers, tracingCloser)
+	probes := health.NewHealth(checks...)
+
+	r := Router(rest.NewPresentationHandlers(i), probes, apiMiddlewares...)
+	// spans are named after the route template and continue the caller's W3C trace
+	r.Use(otelmux.Middleware(cfg.Tracing.ServiceName))
+	r.Use(logging.RouteMiddleware)
+
 	// start the server
-	addr := fmt.Sprintf(":%d", port)
+	addr := fmt.Sprintf(":%d", cfg.Server.Port)
 	h := handlers.CompressHandlerLevel(r, gzip.BestCompression)
 
 	h = handlers.CORS(
 		handlers.AllowedHeaders(allowedHeaders),
 		handlers.AllowCredentials(),
-		handlers.AllowedMethods([]string{"OPTIONS", "GET", "POST"}),
+		handlers.AllowedMethods([]string{"OPTIONS", "GET", "POST", "PATCH"}),
+		handlers.ExposedHeaders([]string{logging.RequestIDHeader, "Retry-After"}),
 	)(h)
-	h = handlers.CombinedLoggingHandler(os.Stdout, h)
+	// every request is logged with its id, including those that don't match a route
+	h = logging.Middleware(h)
 	h = handlers.ContentTypeHandler(
 		h,
 		"application/json",
 		"application/x-www-form-urlencoded",
+		"multipart/form-data",
 	)
 	srv := &http.Server{
 		Handler:      h,
@@ -85,7 +261,12 @@ func PrepareServer(
 		WriteTimeout: serverTimeoutSeconds * time.Second,
 		ReadTimeout:  serverTimeoutSeconds * time.Second,
 	}
-	log.Infof("Server running at port %v", addr)
-	return srv
-
+	return &App{
+		Server:          srv,
+		Workers:         workers,
+		Closers:         closers,
+		ShutdownTimeout: cfg.Server.ShutdownTimeout,
+		Health:          probes,
+		DrainDelay:      cfg.Server.DrainDelay,
+	}, nil
 }
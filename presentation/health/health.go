@@ -0,0 +1,131 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultCheckTimeout bounds a check that doesn't set its own timeout
+const defaultCheckTimeout = 2 * time.Second
+
+// Check reports whether a dependency of the service can be used
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Check   func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of a dependency check
+type CheckResult struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the body of a readiness probe
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Health serves the liveness and readiness probes of the service
+type Health struct {
+	checks   []Check
+	draining atomic.Bool
+}
+
+// NewHealth creates the probes, readiness depends on every check passing
+func NewHealth(checks ...Check) *Health {
+	return &Health{checks: checks}
+}
+
+// Drain makes the service report that it isn't ready, so that no new traffic is sent its way while it
+// shuts down
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Liveness reports that the process is up, it doesn't check any dependency
+func (h *Health) Liveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	}
+}
+
+// Readiness reports whether the service can serve requests: it isn't draining and every dependency
+// check passes within its timeout. The checks run concurrently
+func (h *Health) Readiness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context())
+		status := http.StatusOK
+		if report.Status != "ready" {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// Check runs the dependency checks
+func (h *Health) Check(ctx context.Context) Report {
+	report := Report{Status: "ready", Checks: make(map[string]CheckResult, len(h.checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := run(ctx, check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status != "up" {
+				report.Status = "not_ready"
+			}
+		}(check)
+	}
+	wg.Wait()
+	if h.draining.Load() {
+		report.Status = "draining"
+	}
+	return report
+}
+
+// run runs a single check within its timeout
+func run(ctx context.Context, check Check) CheckResult {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Check(ctx)
+	}()
+	// a check that doesn't honour its context still can't hold the probe up
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	result := CheckResult{Status: "up", LatencyMS: time.Since(started).Milliseconds()}
+	if err != nil {
+		result.Status = "down"
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/presentation/health"
+)
+
+func TestHealth_Readiness(t *testing.T) {
+	up := health.Check{Name: "postgres", Check: func(ctx context.Context) error { return nil }}
+	down := health.Check{Name: "redis", Check: func(ctx context.Context) error { return errors.New("connection refused") }}
+	slow := health.Check{Name: "postgres", Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}}
+
+	tests := []struct {
+		name       string
+		checks     []health.Check
+		drain      bool
+		wantCode   int
+		wantStatus string
+		wantDown   string
+	}{
+		{name: "every dependency is up", checks: []health.Check{up}, wantCode: http.StatusOK, wantStatus: "ready"},
+		{name: "a dependency is down", checks: []health.Check{up, down}, wantCode: http.StatusServiceUnavailable, wantStatus: "not_ready", wantDown: "redis"},
+		{name: "a check times out", checks: []health.Check{slow}, wantCode: http.StatusServiceUnavailable, wantStatus: "not_ready", wantDown: "postgres"},
+		{name: "draining", checks: []health.Check{up}, drain: true, wantCode: http.StatusServiceUnavailable, wantStatus: "draining"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probes := health.NewHealth(tt.checks...)
+			if tt.drain {
+				probes.Drain()
+			}
+			w := httptest.NewRecorder()
+			started := time.Now()
+			probes.Readiness()(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+				t.Fatalf("readiness probe took %s, want the check timeout to bound it", elapsed)
+			}
+
+			if w.Code != tt.wantCode {
+				t.Errorf("Readiness() code = %d, want %d", w.Code, tt.wantCode)
+			}
+			var report health.Report
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatalf("can't decode readiness report: %v", err)
+			}
+			if report.Status != tt.wantStatus {
+				t.Errorf("Readiness() status = %s, want %s", report.Status, tt.wantStatus)
+			}
+			if tt.wantDown != "" && report.Checks[tt.wantDown].Status != "down" {
+				t.Errorf("expected %s to be reported down, got %+v", tt.wantDown, report.Checks)
+			}
+		})
+	}
+}
+
+func TestHealth_Liveness(t *testing.T) {
+	probes := health.NewHealth(health.Check{Name: "postgres", Check: func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}})
+	w := httptest.NewRecorder()
+	probes.Liveness()(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Liveness() code = %d, want %d whatever the state of the dependencies", w.Code, http.StatusOK)
+	}
+}
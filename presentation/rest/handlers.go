@@ -1,23 +1,58 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/MelvinKim/Hotel-Reservation-System/application/common/dto"
 	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
 	"github.com/MelvinKim/Hotel-Reservation-System/presentation/interactor"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
 )
 
 // PresentationHandlers represents all the REST API logic
 type PresentationHandlers interface {
 	CreateGuest() http.HandlerFunc
 	CreateReservation() http.HandlerFunc
+	HoldReservation() http.HandlerFunc
+	ConfirmReservation() http.HandlerFunc
+	ModifyReservation() http.HandlerFunc
+	LookupReservation() http.HandlerFunc
+	CapturePayment() http.HandlerFunc
+	CheckIn() http.HandlerFunc
+	CheckOut() http.HandlerFunc
+	CreateWebhookSubscription() http.HandlerFunc
+	GetWebhookDeliveries() http.HandlerFunc
+	JoinWaitlist() http.HandlerFunc
+	GetLoyaltyBalance() http.HandlerFunc
+	GetLoyaltyHistory() http.HandlerFunc
+	CreateBooking() http.HandlerFunc
+	GetBooking() http.HandlerFunc
+	CancelBookingLine() http.HandlerFunc
+	ConfirmBookingBlock() http.HandlerFunc
+	SetOverbookingLimits() http.HandlerFunc
+	GetWalkOutReport() http.HandlerFunc
 	CancelReservation() http.HandlerFunc
+	BulkUpsertRates() http.HandlerFunc
+	UploadRateCalendar() http.HandlerFunc
+	CreateRatePlan() http.HandlerFunc
+	SetRatePlanRates() http.HandlerFunc
+	SearchAvailability() http.HandlerFunc
+	CreatePricingRule() http.HandlerFunc
+	GetPricingRules() http.HandlerFunc
+	PreviewPricing() http.HandlerFunc
+	CreatePromotion() http.HandlerFunc
 }
 
+// maxRateCalendarUploadBytes caps the size of an uploaded rate calendar
+const maxRateCalendarUploadBytes = 10 << 20
+
 // PresentationHandlersImpl represents the usecase implementation object
 type PresentationHandlersImpl struct {
 	interactor *interactor.Interactor
@@ -38,7 +73,7 @@ func (p PresentationHandlersImpl) CreateGuest() http.HandlerFunc {
 		err := json.NewDecoder(r.Body).Decode(payload)
 		if err != nil {
 			msg := fmt.Sprintf("error unmarshalling request boy to struct: %v", err)
-			http.Error(w, msg, http.StatusBadRequest)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
 			return
 		}
 
@@ -51,7 +86,7 @@ func (p PresentationHandlersImpl) CreateGuest() http.HandlerFunc {
 		createdGuest, err := p.interactor.Hotel.CreateGuest(ctx, &guest)
 		if err != nil {
 			msg := fmt.Sprintf("error creating guest: %v", err)
-			http.Error(w, msg, http.StatusBadRequest)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
 			return
 		}
 
@@ -68,22 +103,37 @@ func (p PresentationHandlersImpl) CreateReservation() http.HandlerFunc {
 		err := json.NewDecoder(r.Body).Decode(payload)
 		if err != nil {
 			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
-			http.Error(w, msg, http.StatusBadRequest)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
 			return
 		}
 
-		reservation := &domain.Reservation{
-			GuestUUID:    payload.GuestUUID,
-			HotelUUID:    payload.HotelUUID,
-			RoomTypeUUID: payload.RoomTypeUUID,
-			StartDate:    time.Now(),
-			EndDate:      time.Now().Add(time.Hour * 72),
-			Status:       string(domain.RESERVED),
+		logSubject(ctx, payload.GuestUUID, payload.HotelUUID)
+		reservation, err := reservationFromPayload(payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 		createdReservation, err := p.interactor.Hotel.CreateReservation(ctx, reservation)
+		if errors.Is(err, domain.ErrSoldOut) && payload.JoinWaitlist {
+			entry, err := p.interactor.Hotel.JoinWaitlist(ctx, &domain.WaitlistEntry{
+				GuestUUID:    reservation.GuestUUID,
+				HotelUUID:    reservation.HotelUUID,
+				RoomTypeUUID: reservation.RoomTypeUUID,
+				StartDate:    reservation.StartDate,
+				EndDate:      reservation.EndDate,
+			})
+			if err != nil {
+				msg := fmt.Sprintf("error joining waitlist: %v", err)
+				http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(entry)
+			return
+		}
 		if err != nil {
 			msg := fmt.Sprintf("error creating reservation: %v", err)
-			http.Error(w, msg, http.StatusBadRequest)
+			http.Error(w, msg, reservationErrorStatus(err))
 			return
 		}
 
@@ -92,6 +142,179 @@ func (p PresentationHandlersImpl) CreateReservation() http.HandlerFunc {
 	}
 }
 
+// HoldReservation claims a Reservation's rooms for a few minutes while the guest pays
+func (p PresentationHandlersImpl) HoldReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.ReservationPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		logSubject(ctx, payload.GuestUUID, payload.HotelUUID)
+		reservation, err := reservationFromPayload(payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		heldReservation, err := p.interactor.Hotel.HoldReservation(ctx, reservation)
+		if err != nil {
+			msg := fmt.Sprintf("error holding reservation: %v", err)
+			http.Error(w, msg, reservationErrorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(heldReservation)
+	}
+}
+
+// ModifyReservation changes the dates and/or room type of the reservation given by the uuid path variable
+// e.g PATCH /api/v1/reservations/{uuid}
+func (p PresentationHandlersImpl) ModifyReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.ReservationModificationPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		reservation, err := p.interactor.Hotel.ModifyReservation(ctx, mux.Vars(r)["uuid"], payload)
+		if err != nil {
+			msg := fmt.Sprintf("error modifying reservation: %v", err)
+			http.Error(w, msg, reservationErrorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+// LookupReservation finds a reservation by confirmation code and guest last name for "manage my booking"
+// e.g GET /api/v1/reservations/lookup?confirmation_code=7KQ2M9XA&last_name=Kimani
+func (p PresentationHandlersImpl) LookupReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+		reservation, err := p.interactor.Hotel.LookupReservation(ctx, query.Get("confirmation_code"), query.Get("last_name"))
+		if err != nil {
+			msg := fmt.Sprintf("error looking up reservation: %v", err)
+			status := errorStatus(err, http.StatusInternalServerError)
+			if errors.Is(err, domain.ErrReservationNotFound) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, msg, status)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+// ConfirmReservation pays for a held Reservation
+func (p PresentationHandlersImpl) ConfirmReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.ConfirmReservationPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		confirmedReservation, err := p.interactor.Hotel.ConfirmReservation(ctx, payload.ReservationUUID, payload.PaymentMethod)
+		if err != nil {
+			msg := fmt.Sprintf("error confirming reservation: %v", err)
+			http.Error(w, msg, reservationErrorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(confirmedReservation)
+	}
+}
+
+// CheckIn records a guest arriving for their Reservation
+func (p PresentationHandlersImpl) CheckIn() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.ReservationStatusPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		reservation, err := p.interactor.Hotel.CheckIn(ctx, payload.ReservationUUID)
+		if err != nil {
+			msg := fmt.Sprintf("error checking in: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+// CheckOut records a guest leaving at the end of their Reservation
+func (p PresentationHandlersImpl) CheckOut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.ReservationStatusPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		reservation, err := p.interactor.Hotel.CheckOut(ctx, payload.ReservationUUID)
+		if err != nil {
+			msg := fmt.Sprintf("error checking out: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+// CapturePayment collects the authorized payment of a Reservation
+func (p PresentationHandlersImpl) CapturePayment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.CapturePaymentPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		capturedPayment, err := p.interactor.Hotel.CapturePayment(ctx, payload.ReservationUUID)
+		if err != nil {
+			msg := fmt.Sprintf("error capturing payment: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(capturedPayment)
+	}
+}
+
 // CancelReservation cancels an existing Reservation
 func (p PresentationHandlersImpl) CancelReservation() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -100,14 +323,15 @@ func (p PresentationHandlersImpl) CancelReservation() http.HandlerFunc {
 		err := json.NewDecoder(r.Body).Decode(payload)
 		if err != nil {
 			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
-			http.Error(w, msg, http.StatusBadRequest)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
 			return
 		}
 
+		logSubject(ctx, payload.GuestUUID, "")
 		cancelledReservation, err := p.interactor.Hotel.CancelReservation(ctx, payload.GuestUUID, payload.RoomTypeUUID)
 		if err != nil {
 			msg := fmt.Sprintf("error cancelling reservation: %v", err)
-			http.Error(w, msg, http.StatusBadRequest)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
 			return
 		}
 
@@ -115,3 +339,679 @@ func (p PresentationHandlersImpl) CancelReservation() http.HandlerFunc {
 		json.NewEncoder(w).Encode(cancelledReservation)
 	}
 }
+
+// BulkUpsertRates sets a room type's nightly rates for one or more date ranges
+func (p PresentationHandlersImpl) BulkUpsertRates() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.BulkRatePayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		summary, err := p.interactor.Hotel.BulkUpsertRates(ctx, payload)
+		if err != nil {
+			msg := fmt.Sprintf("error setting rates: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(summary)
+	}
+}
+
+// UploadRateCalendar sets a room type's nightly rates from an uploaded CSV file
+// The multipart form carries the hotel_uuid, roomtype_uuid and the CSV document as `file`
+func (p PresentationHandlersImpl) UploadRateCalendar() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		r.Body = http.MaxBytesReader(w, r.Body, maxRateCalendarUploadBytes)
+		if err := r.ParseMultipartForm(maxRateCalendarUploadBytes); err != nil {
+			msg := fmt.Sprintf("error parsing multipart form: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			msg := fmt.Sprintf("error reading uploaded rate calendar: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+		defer file.Close()
+
+		summary, err := p.interactor.Hotel.UploadRateCalendar(
+			ctx,
+			r.FormValue("hotel_uuid"),
+			r.FormValue("roomtype_uuid"),
+			file,
+		)
+		if err != nil {
+			msg := fmt.Sprintf("error setting rates: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(summary)
+	}
+}
+
+// CreateRatePlan creates a new rate plan for a room type
+func (p PresentationHandlersImpl) CreateRatePlan() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.RatePlanPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		logSubject(ctx, "", payload.HotelUUID)
+		ratePlan := &domain.RatePlan{
+			HotelUUID:         payload.HotelUUID,
+			RoomTypeUUID:      payload.RoomTypeUUID,
+			Name:              payload.Name,
+			Description:       payload.Description,
+			Refundable:        payload.Refundable == nil || *payload.Refundable,
+			BreakfastIncluded: payload.BreakfastIncluded,
+			MinLengthOfStay:   payload.MinLengthOfStay,
+			MaxLengthOfStay:   payload.MaxLengthOfStay,
+			MinAdvanceDays:    payload.MinAdvanceDays,
+			MaxAdvanceDays:    payload.MaxAdvanceDays,
+		}
+		createdRatePlan, err := p.interactor.Hotel.CreateRatePlan(ctx, ratePlan)
+		if err != nil {
+			msg := fmt.Sprintf("error creating rate plan: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(createdRatePlan)
+	}
+}
+
+// SetRatePlanRates sets a rate plan's nightly price and restrictions over a date range
+func (p PresentationHandlersImpl) SetRatePlanRates() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.RatePlanRatesPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		rates, err := p.interactor.Hotel.SetRatePlanRates(ctx, payload)
+		if err != nil {
+			msg := fmt.Sprintf("error setting rate plan rates: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rates)
+	}
+}
+
+// SearchAvailability lists the room types and rate plans of a hotel that can be booked for a stay
+// e.g GET /api/v1/availability?hotel_uuid=...&start_date=2023-06-01&end_date=2023-06-03
+func (p PresentationHandlersImpl) SearchAvailability() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+		startDate, endDate, err := parseStay(query.Get("start_date"), query.Get("end_date"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		availability, err := p.interactor.Hotel.SearchAvailability(ctx, query.Get("hotel_uuid"), startDate, endDate)
+		if err != nil {
+			msg := fmt.Sprintf("error searching availability: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(availability)
+	}
+}
+
+// CreatePricingRule creates a new dynamic pricing rule for a hotel
+func (p PresentationHandlersImpl) CreatePricingRule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.PricingRulePayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		rule := pricingRuleFromPayload(*payload)
+		createdRule, err := p.interactor.Hotel.CreatePricingRule(ctx, &rule)
+		if err != nil {
+			msg := fmt.Sprintf("error creating pricing rule: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(createdRule)
+	}
+}
+
+// GetPricingRules lists the pricing rules of the hotel given by the hotel_uuid query parameter
+func (p PresentationHandlersImpl) GetPricingRules() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		rules, err := p.interactor.Hotel.GetPricingRules(ctx, r.URL.Query().Get("hotel_uuid"))
+		if err != nil {
+			msg := fmt.Sprintf("error getting pricing rules: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(rules)
+	}
+}
+
+// PreviewPricing is a dry run of the dynamic pricing of a room type over a date range
+func (p PresentationHandlersImpl) PreviewPricing() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.PricingPreviewPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+		startDate, endDate, err := parseStay(payload.StartDate, payload.EndDate)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// nil rules preview the hotel's stored rules
+		var rules []domain.PricingRule
+		if payload.Rules != nil {
+			rules = []domain.PricingRule{}
+			for _, rule := range payload.Rules {
+				rules = append(rules, pricingRuleFromPayload(rule))
+			}
+		}
+		logSubject(ctx, "", payload.HotelUUID)
+		calendar, err := p.interactor.Hotel.PreviewPricing(
+			ctx,
+			payload.HotelUUID,
+			payload.RoomTypeUUID,
+			startDate,
+			endDate,
+			rules,
+		)
+		if err != nil {
+			msg := fmt.Sprintf("error previewing pricing: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(calendar)
+	}
+}
+
+// pricingRuleFromPayload maps a pricing rule payload to a pricing rule
+func pricingRuleFromPayload(payload dto.PricingRulePayload) domain.PricingRule {
+	return domain.PricingRule{
+		HotelUUID:    payload.HotelUUID,
+		RoomTypeUUID: payload.RoomTypeUUID,
+		Name:         payload.Name,
+		Type:         domain.PricingRuleType(payload.Type),
+		Multiplier:   payload.Multiplier,
+		Priority:     payload.Priority,
+		MinOccupancy: payload.MinOccupancy,
+		MaxOccupancy: payload.MaxOccupancy,
+		Weekday:      payload.Weekday,
+		MinLeadDays:  payload.MinLeadDays,
+		MaxLeadDays:  payload.MaxLeadDays,
+	}
+}
+
+// CreatePromotion creates a new promo code
+func (p PresentationHandlersImpl) CreatePromotion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.PromotionPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		promotion := &domain.Promotion{
+			Code:            payload.Code,
+			Description:     payload.Description,
+			DiscountType:    domain.DiscountType(payload.DiscountType),
+			Value:           payload.Value,
+			HotelUUIDs:      payload.HotelUUIDs,
+			RoomTypeUUIDs:   payload.RoomTypeUUIDs,
+			MaxUses:         payload.MaxUses,
+			MaxUsesPerGuest: payload.MaxUsesPerGuest,
+			ValidFrom:       time.Now(),
+		}
+		if payload.ValidFrom != "" {
+			if promotion.ValidFrom, err = time.Parse(time.RFC3339, payload.ValidFrom); err != nil {
+				http.Error(w, fmt.Sprintf("invalid valid_from %q: %v", payload.ValidFrom, err), http.StatusBadRequest)
+				return
+			}
+		}
+		if payload.ValidUntil != "" {
+			if promotion.ValidUntil, err = time.Parse(time.RFC3339, payload.ValidUntil); err != nil {
+				http.Error(w, fmt.Sprintf("invalid valid_until %q: %v", payload.ValidUntil, err), http.StatusBadRequest)
+				return
+			}
+		}
+		createdPromotion, err := p.interactor.Hotel.CreatePromotion(ctx, promotion)
+		if err != nil {
+			msg := fmt.Sprintf("error creating promotion: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(createdPromotion)
+	}
+}
+
+// parseStay parses a stay's check-in and check-out dates
+func parseStay(startDate, endDate string) (time.Time, time.Time, error) {
+	start, err := domain.ParseDate(startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date %q, expected a date like 2023-06-01", startDate)
+	}
+	end, err := domain.ParseDate(endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date %q, expected a date like 2023-06-01", endDate)
+	}
+	return start, end, nil
+}
+
+// CreateWebhookSubscription subscribes a partner's endpoint to a hotel's reservation events
+func (p PresentationHandlersImpl) CreateWebhookSubscription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.WebhookSubscriptionPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		logSubject(ctx, "", payload.HotelUUID)
+		subscription := &domain.WebhookSubscription{
+			HotelUUID: payload.HotelUUID,
+			URL:       payload.URL,
+			Secret:    payload.Secret,
+		}
+		for _, eventType := range payload.EventTypes {
+			subscription.EventTypes = append(subscription.EventTypes, domain.EventType(eventType))
+		}
+		createdSubscription, err := p.interactor.Hotel.CreateWebhookSubscription(ctx, subscription)
+		if err != nil {
+			msg := fmt.Sprintf("error creating webhook subscription: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(createdSubscription)
+	}
+}
+
+// GetWebhookDeliveries lists the deliveries made to a webhook subscription
+func (p PresentationHandlersImpl) GetWebhookDeliveries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		deliveries, err := p.interactor.Hotel.GetWebhookDeliveries(ctx, r.URL.Query().Get("subscription_uuid"))
+		if err != nil {
+			msg := fmt.Sprintf("error getting webhook deliveries: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(deliveries)
+	}
+}
+
+// JoinWaitlist puts a guest on the waitlist of a sold out room type
+func (p PresentationHandlersImpl) JoinWaitlist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.WaitlistPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+		startDate, endDate, err := parseStay(payload.StartDate, payload.EndDate)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logSubject(ctx, payload.GuestUUID, payload.HotelUUID)
+		entry, err := p.interactor.Hotel.JoinWaitlist(ctx, &domain.WaitlistEntry{
+			GuestUUID:    payload.GuestUUID,
+			HotelUUID:    payload.HotelUUID,
+			RoomTypeUUID: payload.RoomTypeUUID,
+			StartDate:    startDate,
+			EndDate:      endDate,
+		})
+		if err != nil {
+			msg := fmt.Sprintf("error joining waitlist: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(entry)
+	}
+}
+
+// GetLoyaltyBalance gets the loyalty points of the guest given by the guest_uuid query parameter
+func (p PresentationHandlersImpl) GetLoyaltyBalance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		balance, err := p.interactor.Hotel.GetLoyaltyBalance(ctx, r.URL.Query().Get("guest_uuid"))
+		if err != nil {
+			msg := fmt.Sprintf("error getting loyalty balance: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(balance)
+	}
+}
+
+// GetLoyaltyHistory lists the loyalty ledger of the guest given by the guest_uuid query parameter
+func (p PresentationHandlersImpl) GetLoyaltyHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		entries, err := p.interactor.Hotel.GetLoyaltyHistory(ctx, r.URL.Query().Get("guest_uuid"))
+		if err != nil {
+			msg := fmt.Sprintf("error getting loyalty history: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+// CreateBooking books several rooms, possibly of different room types, in one go
+func (p PresentationHandlersImpl) CreateBooking() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.BookingPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		logSubject(ctx, payload.GuestUUID, payload.HotelUUID)
+		booking, err := bookingFromPayload(payload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		createdBooking, err := p.interactor.Hotel.CreateBooking(ctx, booking)
+		if err != nil {
+			msg := fmt.Sprintf("error creating booking: %v", err)
+			http.Error(w, msg, reservationErrorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(createdBooking)
+	}
+}
+
+// GetBooking gets the booking given by the booking_uuid query parameter
+func (p PresentationHandlersImpl) GetBooking() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		booking, err := p.interactor.Hotel.GetBooking(ctx, r.URL.Query().Get("booking_uuid"))
+		if err != nil {
+			msg := fmt.Sprintf("error getting booking: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusNotFound))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(booking)
+	}
+}
+
+// CancelBookingLine cancels the rooms of one line of a booking
+func (p PresentationHandlersImpl) CancelBookingLine() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.BookingLineCancellationPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		booking, err := p.interactor.Hotel.CancelBookingLine(ctx, payload.BookingUUID, payload.LineUUID)
+		if err != nil {
+			msg := fmt.Sprintf("error cancelling booking line: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(booking)
+	}
+}
+
+// ConfirmBookingBlock books the rooms of a group block before they are released
+func (p PresentationHandlersImpl) ConfirmBookingBlock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.ConfirmBookingPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		booking, err := p.interactor.Hotel.ConfirmBookingBlock(ctx, payload.BookingUUID, payload.PaymentMethod)
+		if err != nil {
+			msg := fmt.Sprintf("error confirming booking: %v", err)
+			http.Error(w, msg, reservationErrorStatus(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(booking)
+	}
+}
+
+// SetOverbookingLimits overrides a room type's overbooking limit for a date range
+func (p PresentationHandlersImpl) SetOverbookingLimits() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		payload := &dto.OverbookingPayload{}
+		err := json.NewDecoder(r.Body).Decode(payload)
+		if err != nil {
+			msg := fmt.Sprintf("error unmarshalling request body to struct: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		inventories, err := p.interactor.Hotel.SetOverbookingLimits(ctx, payload)
+		if err != nil {
+			msg := fmt.Sprintf("error setting overbooking limits: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(inventories)
+	}
+}
+
+// GetWalkOutReport lists the nights a hotel is oversold on and how many guests would have to be walked
+// e.g GET /api/v1/reports/walk-outs?hotel_uuid=...&start_date=2023-06-01&end_date=2023-06-30
+func (p PresentationHandlersImpl) GetWalkOutReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		query := r.URL.Query()
+		startDate, endDate, err := parseStay(query.Get("start_date"), query.Get("end_date"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := p.interactor.Hotel.GetWalkOutReport(ctx, query.Get("hotel_uuid"), startDate, endDate)
+		if err != nil {
+			msg := fmt.Sprintf("error getting walk-out report: %v", err)
+			http.Error(w, msg, errorStatus(err, http.StatusBadRequest))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// bookingFromPayload builds the Booking described by a payload
+func bookingFromPayload(payload *dto.BookingPayload) (*domain.Booking, error) {
+	startDate, endDate, err := parseStay(payload.StartDate, payload.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	booking := &domain.Booking{
+		GuestUUID:     payload.GuestUUID,
+		HotelUUID:     payload.HotelUUID,
+		GroupName:     payload.GroupName,
+		StartDate:     startDate,
+		EndDate:       endDate,
+		PaymentMethod: payload.PaymentMethod,
+	}
+	if payload.ReleaseDate != "" {
+		releaseDate, err := domain.ParseDate(payload.ReleaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid release date %q, expected a date like 2023-06-01", payload.ReleaseDate)
+		}
+		booking.ReleaseDate = &releaseDate
+	}
+	for _, line := range payload.Lines {
+		bookingLine := domain.BookingLine{
+			RoomTypeUUID: line.RoomTypeUUID,
+			Quantity:     line.Quantity,
+			Occupants:    line.Occupants,
+		}
+		if line.RatePlanUUID != "" {
+			ratePlanUUID := line.RatePlanUUID
+			bookingLine.RatePlanUUID = &ratePlanUUID
+		}
+		booking.Lines = append(booking.Lines, bookingLine)
+	}
+	return booking, nil
+}
+
+// reservationFromPayload builds the Reservation described by a payload.
+// Stays without dates default to three nights from now
+func reservationFromPayload(payload *dto.ReservationPayload) (*domain.Reservation, error) {
+	reservation := &domain.Reservation{
+		GuestUUID:      payload.GuestUUID,
+		HotelUUID:      payload.HotelUUID,
+		RoomTypeUUID:   payload.RoomTypeUUID,
+		StartDate:      time.Now(),
+		EndDate:        time.Now().Add(time.Hour * 72),
+		PromoCode:      payload.PromoCode,
+		PaymentMethod:  payload.PaymentMethod,
+		RedeemedPoints: payload.RedeemPoints,
+	}
+	if payload.RatePlanUUID != "" {
+		reservation.RatePlanUUID = &payload.RatePlanUUID
+	}
+	if payload.StartDate != "" || payload.EndDate != "" {
+		var err error
+		reservation.StartDate, reservation.EndDate, err = parseStay(payload.StartDate, payload.EndDate)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reservation, nil
+}
+
+// logSubject adds the guest and hotel a request is about, when it names them, to its log lines
+func logSubject(ctx context.Context, guestUUID, hotelUUID string) {
+	fields := log.Fields{}
+	if guestUUID != "" {
+		fields["guest_uuid"] = guestUUID
+	}
+	if hotelUUID != "" {
+		fields["hotel_uuid"] = hotelUUID
+	}
+	logging.AddFields(ctx, fields)
+}
+
+// errorStatus is the HTTP status code of a failed request: 504 when a query ran out of time, 503 when it was
+// abandoned because the request was cancelled, and fallback otherwise
+func errorStatus(err error, fallback int) int {
+	var timeout *domain.TimeoutError
+	if !errors.As(err, &timeout) {
+		return fallback
+	}
+	if errors.Is(timeout, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusServiceUnavailable
+}
+
+// reservationErrorStatus maps the errors of booking flows to a HTTP status code
+func reservationErrorStatus(err error) int {
+	var timeout *domain.TimeoutError
+	switch {
+	case errors.As(err, &timeout):
+		return errorStatus(err, http.StatusBadRequest)
+	case errors.Is(err, domain.ErrSoldOut):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, domain.ErrHoldExpired), errors.Is(err, domain.ErrBlockReleased):
+		return http.StatusGone
+	case errors.Is(err, domain.ErrStayRestricted),
+		errors.Is(err, domain.ErrPromotionNotApplicable),
+		errors.Is(err, domain.ErrPromotionExhausted),
+		errors.Is(err, domain.ErrInsufficientPoints):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -2,6 +2,7 @@ package mock
 
 import (
 	"context"
+	"time"
 
 	"github.com/MelvinKim/Hotel-Reservation-System/domain"
 	"github.com/brianvoe/gofakeit/v6"
@@ -17,6 +18,10 @@ type MockCreateRepository struct {
 		ctx context.Context,
 		rate *domain.Rate,
 	) (*domain.Rate, error)
+	MockUpsertRates func(
+		ctx context.Context,
+		rates []*domain.Rate,
+	) (int64, error)
 	MockCreateGuest func(
 		ctx context.Context,
 		guest *domain.Guest,
@@ -33,6 +38,43 @@ type MockCreateRepository struct {
 		ctx context.Context,
 		hotel *domain.Room,
 	) (*domain.Room, error)
+	MockCreateRatePlan func(
+		ctx context.Context,
+		ratePlan *domain.RatePlan,
+	) (*domain.RatePlan, error)
+	MockUpsertRatePlanRates func(
+		ctx context.Context,
+		rates []*domain.RatePlanRate,
+	) error
+	MockCreatePricingRule func(
+		ctx context.Context,
+		rule *domain.PricingRule,
+	) (*domain.PricingRule, error)
+	MockCreatePromotion func(
+		ctx context.Context,
+		promotion *domain.Promotion,
+	) (*domain.Promotion, error)
+	MockCreateWebhookSubscription func(
+		ctx context.Context,
+		subscription *domain.WebhookSubscription,
+	) (*domain.WebhookSubscription, error)
+	MockCreateWebhookDeliveries func(
+		ctx context.Context,
+		deliveries []*domain.WebhookDelivery,
+	) error
+	MockCreateWaitlistEntry func(
+		ctx context.Context,
+		entry *domain.WaitlistEntry,
+	) (*domain.WaitlistEntry, error)
+	MockCreateBooking func(
+		ctx context.Context,
+		booking *domain.Booking,
+		payment *domain.Payment,
+	) (*domain.Booking, error)
+	MockCreateLoyaltyEntry func(
+		ctx context.Context,
+		entry *domain.LoyaltyEntry,
+	) error
 }
 
 // NewMockCreateRepository initializes
@@ -44,6 +86,9 @@ func NewMockCreateRepository() *MockCreateRepository {
 		MockCreateRate: func(ctx context.Context, rate *domain.Rate) (*domain.Rate, error) {
 			return &domain.Rate{}, nil
 		},
+		MockUpsertRates: func(ctx context.Context, rates []*domain.Rate) (int64, error) {
+			return int64(len(rates)), nil
+		},
 		MockCreateGuest: func(ctx context.Context, guest *domain.Guest) (*domain.Guest, error) {
 			return &domain.Guest{}, nil
 		},
@@ -56,6 +101,33 @@ func NewMockCreateRepository() *MockCreateRepository {
 		MockCreateRoom: func(ctx context.Context, hotel *domain.Room) (*domain.Room, error) {
 			return &domain.Room{}, nil
 		},
+		MockCreateRatePlan: func(ctx context.Context, ratePlan *domain.RatePlan) (*domain.RatePlan, error) {
+			return ratePlan, nil
+		},
+		MockUpsertRatePlanRates: func(ctx context.Context, rates []*domain.RatePlanRate) error {
+			return nil
+		},
+		MockCreatePricingRule: func(ctx context.Context, rule *domain.PricingRule) (*domain.PricingRule, error) {
+			return rule, nil
+		},
+		MockCreatePromotion: func(ctx context.Context, promotion *domain.Promotion) (*domain.Promotion, error) {
+			return promotion, nil
+		},
+		MockCreateWebhookSubscription: func(ctx context.Context, subscription *domain.WebhookSubscription) (*domain.WebhookSubscription, error) {
+			return subscription, nil
+		},
+		MockCreateWebhookDeliveries: func(ctx context.Context, deliveries []*domain.WebhookDelivery) error {
+			return nil
+		},
+		MockCreateWaitlistEntry: func(ctx context.Context, entry *domain.WaitlistEntry) (*domain.WaitlistEntry, error) {
+			return entry, nil
+		},
+		MockCreateBooking: func(ctx context.Context, booking *domain.Booking, payment *domain.Payment) (*domain.Booking, error) {
+			return booking, nil
+		},
+		MockCreateLoyaltyEntry: func(ctx context.Context, entry *domain.LoyaltyEntry) error {
+			return nil
+		},
 	}
 }
 
@@ -75,6 +147,14 @@ func (c *MockCreateRepository) CreateRate(
 	return c.MockCreateRate(ctx, rate)
 }
 
+// UpsertRates mocks UpsertRates
+func (c *MockCreateRepository) UpsertRates(
+	ctx context.Context,
+	rates []*domain.Rate,
+) (int64, error) {
+	return c.MockUpsertRates(ctx, rates)
+}
+
 // CreateGuest mocks CreateGuest
 func (c *MockCreateRepository) CreateGuest(
 	ctx context.Context,
@@ -107,6 +187,79 @@ func (c *MockCreateRepository) CreateRoom(
 	return c.MockCreateRoom(ctx, room)
 }
 
+// CreateRatePlan mocks CreateRatePlan
+func (c *MockCreateRepository) CreateRatePlan(
+	ctx context.Context,
+	ratePlan *domain.RatePlan,
+) (*domain.RatePlan, error) {
+	return c.MockCreateRatePlan(ctx, ratePlan)
+}
+
+// UpsertRatePlanRates mocks UpsertRatePlanRates
+func (c *MockCreateRepository) UpsertRatePlanRates(
+	ctx context.Context,
+	rates []*domain.RatePlanRate,
+) error {
+	return c.MockUpsertRatePlanRates(ctx, rates)
+}
+
+// CreatePricingRule mocks CreatePricingRule
+func (c *MockCreateRepository) CreatePricingRule(
+	ctx context.Context,
+	rule *domain.PricingRule,
+) (*domain.PricingRule, error) {
+	return c.MockCreatePricingRule(ctx, rule)
+}
+
+// CreatePromotion mocks CreatePromotion
+func (c *MockCreateRepository) CreatePromotion(
+	ctx context.Context,
+	promotion *domain.Promotion,
+) (*domain.Promotion, error) {
+	return c.MockCreatePromotion(ctx, promotion)
+}
+
+// CreateWebhookSubscription mocks CreateWebhookSubscription
+func (c *MockCreateRepository) CreateWebhookSubscription(
+	ctx context.Context,
+	subscription *domain.WebhookSubscription,
+) (*domain.WebhookSubscription, error) {
+	return c.MockCreateWebhookSubscription(ctx, subscription)
+}
+
+// CreateWebhookDeliveries mocks CreateWebhookDeliveries
+func (c *MockCreateRepository) CreateWebhookDeliveries(
+	ctx context.Context,
+	deliveries []*domain.WebhookDelivery,
+) error {
+	return c.MockCreateWebhookDeliveries(ctx, deliveries)
+}
+
+// CreateWaitlistEntry mocks CreateWaitlistEntry
+func (c *MockCreateRepository) CreateWaitlistEntry(
+	ctx context.Context,
+	entry *domain.WaitlistEntry,
+) (*domain.WaitlistEntry, error) {
+	return c.MockCreateWaitlistEntry(ctx, entry)
+}
+
+// CreateBooking mocks CreateBooking
+func (c *MockCreateRepository) CreateBooking(
+	ctx context.Context,
+	booking *domain.Booking,
+	payment *domain.Payment,
+) (*domain.Booking, error) {
+	return c.MockCreateBooking(ctx, booking, payment)
+}
+
+// CreateLoyaltyEntry mocks CreateLoyaltyEntry
+func (c *MockCreateRepository) CreateLoyaltyEntry(
+	ctx context.Context,
+	entry *domain.LoyaltyEntry,
+) error {
+	return c.MockCreateLoyaltyEntry(ctx, entry)
+}
+
 // MockGetRepository mocks the database's get repository
 type MockGetRepository struct {
 	MockGetReservations func(
@@ -124,6 +277,10 @@ type MockGetRepository struct {
 	MockGetRoomTypes func(
 		ctx context.Context,
 	) ([]domain.RoomType, error)
+	MockGetRoomType func(
+		ctx context.Context,
+		RoomTypeUUID string,
+	) (*domain.RoomType, error)
 	MockGetRates func(
 		ctx context.Context,
 	) ([]domain.Rate, error)
@@ -137,6 +294,100 @@ type MockGetRepository struct {
 		RoomTypeUUID string,
 		HotelUUID string,
 	) (*domain.Room, error)
+	MockGetRatePlan func(
+		ctx context.Context,
+		RatePlanUUID string,
+	) (*domain.RatePlan, error)
+	MockGetRatePlans func(
+		ctx context.Context,
+		HotelUUID string,
+	) ([]domain.RatePlan, error)
+	MockGetRatePlanRates func(
+		ctx context.Context,
+		RatePlanUUIDs []string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.RatePlanRate, error)
+	MockGetHotelRoomTypes func(
+		ctx context.Context,
+		HotelUUID string,
+	) ([]domain.RoomType, error)
+	MockGetRoomTypeInventories func(
+		ctx context.Context,
+		HotelUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.RoomTypeInventory, error)
+	MockGetRatesInRange func(
+		ctx context.Context,
+		RoomTypeUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.Rate, error)
+	MockGetPricingRules func(
+		ctx context.Context,
+		HotelUUID string,
+	) ([]domain.PricingRule, error)
+	MockGetPromotionByCode func(
+		ctx context.Context,
+		Code string,
+	) (*domain.Promotion, error)
+	MockGetReservation func(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Reservation, error)
+	MockGetPayment func(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Payment, error)
+	MockGetBookingPayment func(
+		ctx context.Context,
+		BookingUUID string,
+	) (*domain.Payment, error)
+	MockGetWebhookSubscriptions func(
+		ctx context.Context,
+		HotelUUID string,
+	) ([]domain.WebhookSubscription, error)
+	MockGetWebhookDeliveries func(
+		ctx context.Context,
+		SubscriptionUUID string,
+	) ([]domain.WebhookDelivery, error)
+	MockGetGuest func(
+		ctx context.Context,
+		GuestUUID string,
+	) (*domain.Guest, error)
+	MockGetHotel func(
+		ctx context.Context,
+		HotelUUID string,
+	) (*domain.Hotel, error)
+	MockGetWaitlist func(
+		ctx context.Context,
+		RoomTypeUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.WaitlistEntry, error)
+	MockGetOversoldNights func(
+		ctx context.Context,
+		HotelUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.OversoldNight, error)
+	MockGetBooking func(
+		ctx context.Context,
+		BookingUUID string,
+	) (*domain.Booking, error)
+	MockGetReservationByConfirmationCode func(
+		ctx context.Context,
+		ConfirmationCode string,
+	) (*domain.Reservation, error)
+	MockGetLoyaltyBalance func(
+		ctx context.Context,
+		GuestUUID string,
+	) (int, error)
+	MockGetLoyaltyEntries func(
+		ctx context.Context,
+		GuestUUID string,
+	) ([]domain.LoyaltyEntry, error)
 }
 
 // NewMockGetRepository initializes a new mock Get Repository
@@ -147,6 +398,18 @@ func NewMockGetRepository() *MockGetRepository {
 		Rate:         30,
 		Date:         gofakeit.Date(),
 	}
+	roomType := domain.RoomType{
+		HotelUUID: gofakeit.UUID(),
+		Inventory: 40,
+		Reserved:  20,
+	}
+	ratePlan := domain.RatePlan{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID(), Active: true},
+		HotelUUID:    roomType.HotelUUID,
+		RoomTypeUUID: gofakeit.UUID(),
+		Name:         gofakeit.Word(),
+		Refundable:   true,
+	}
 	room := domain.Room{
 		RoomTypeUUID: gofakeit.UUID(),
 		HotelUUID:    gofakeit.UUID(),
@@ -168,6 +431,9 @@ func NewMockGetRepository() *MockGetRepository {
 		MockGetRoomTypes: func(ctx context.Context) ([]domain.RoomType, error) {
 			return []domain.RoomType{}, nil
 		},
+		MockGetRoomType: func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+			return &roomType, nil
+		},
 		MockGetRates: func(ctx context.Context) ([]domain.Rate, error) {
 			return []domain.Rate{}, nil
 		},
@@ -177,6 +443,69 @@ func NewMockGetRepository() *MockGetRepository {
 		MockGetRoom: func(ctx context.Context, RoomTypeUUID, HotelUUID string) (*domain.Room, error) {
 			return &room, nil
 		},
+		MockGetRatePlan: func(ctx context.Context, RatePlanUUID string) (*domain.RatePlan, error) {
+			return &ratePlan, nil
+		},
+		MockGetRatePlans: func(ctx context.Context, HotelUUID string) ([]domain.RatePlan, error) {
+			return []domain.RatePlan{ratePlan}, nil
+		},
+		MockGetRatePlanRates: func(ctx context.Context, RatePlanUUIDs []string, StartDate time.Time, EndDate time.Time) ([]domain.RatePlanRate, error) {
+			return []domain.RatePlanRate{}, nil
+		},
+		MockGetHotelRoomTypes: func(ctx context.Context, HotelUUID string) ([]domain.RoomType, error) {
+			return []domain.RoomType{roomType}, nil
+		},
+		MockGetRoomTypeInventories: func(ctx context.Context, HotelUUID string, StartDate time.Time, EndDate time.Time) ([]domain.RoomTypeInventory, error) {
+			return []domain.RoomTypeInventory{}, nil
+		},
+		MockGetRatesInRange: func(ctx context.Context, RoomTypeUUID string, StartDate time.Time, EndDate time.Time) ([]domain.Rate, error) {
+			return []domain.Rate{}, nil
+		},
+		MockGetPricingRules: func(ctx context.Context, HotelUUID string) ([]domain.PricingRule, error) {
+			return []domain.PricingRule{}, nil
+		},
+		MockGetPromotionByCode: func(ctx context.Context, Code string) (*domain.Promotion, error) {
+			return nil, nil
+		},
+		MockGetReservation: func(ctx context.Context, ReservationUUID string) (*domain.Reservation, error) {
+			return &domain.Reservation{}, nil
+		},
+		MockGetPayment: func(ctx context.Context, ReservationUUID string) (*domain.Payment, error) {
+			return nil, nil
+		},
+		MockGetBookingPayment: func(ctx context.Context, BookingUUID string) (*domain.Payment, error) {
+			return nil, nil
+		},
+		MockGetWebhookSubscriptions: func(ctx context.Context, HotelUUID string) ([]domain.WebhookSubscription, error) {
+			return []domain.WebhookSubscription{}, nil
+		},
+		MockGetWebhookDeliveries: func(ctx context.Context, SubscriptionUUID string) ([]domain.WebhookDelivery, error) {
+			return []domain.WebhookDelivery{}, nil
+		},
+		MockGetGuest: func(ctx context.Context, GuestUUID string) (*domain.Guest, error) {
+			return &domain.Guest{}, nil
+		},
+		MockGetHotel: func(ctx context.Context, HotelUUID string) (*domain.Hotel, error) {
+			return &domain.Hotel{}, nil
+		},
+		MockGetWaitlist: func(ctx context.Context, RoomTypeUUID string, StartDate time.Time, EndDate time.Time) ([]domain.WaitlistEntry, error) {
+			return []domain.WaitlistEntry{}, nil
+		},
+		MockGetOversoldNights: func(ctx context.Context, HotelUUID string, StartDate time.Time, EndDate time.Time) ([]domain.OversoldNight, error) {
+			return []domain.OversoldNight{}, nil
+		},
+		MockGetBooking: func(ctx context.Context, BookingUUID string) (*domain.Booking, error) {
+			return &domain.Booking{AbstractBase: domain.AbstractBase{UUID: BookingUUID}}, nil
+		},
+		MockGetReservationByConfirmationCode: func(ctx context.Context, ConfirmationCode string) (*domain.Reservation, error) {
+			return nil, nil
+		},
+		MockGetLoyaltyBalance: func(ctx context.Context, GuestUUID string) (int, error) {
+			return 0, nil
+		},
+		MockGetLoyaltyEntries: func(ctx context.Context, GuestUUID string) ([]domain.LoyaltyEntry, error) {
+			return []domain.LoyaltyEntry{}, nil
+		},
 	}
 }
 
@@ -215,6 +544,14 @@ func (g *MockGetRepository) GetRoomTypes(
 	return g.MockGetRoomTypes(ctx)
 }
 
+// GetRoomType mocks GetRoomType
+func (g *MockGetRepository) GetRoomType(
+	ctx context.Context,
+	RoomTypeUUID string,
+) (*domain.RoomType, error) {
+	return g.MockGetRoomType(ctx, RoomTypeUUID)
+}
+
 // GetRates mocks GetRates
 func (g *MockGetRepository) GetRates(
 	ctx context.Context,
@@ -240,6 +577,184 @@ func (g *MockGetRepository) GetRoom(
 	return g.MockGetRoom(ctx, RoomTypeUUID, HotelUUID)
 }
 
+// GetRatePlan mocks GetRatePlan
+func (g *MockGetRepository) GetRatePlan(
+	ctx context.Context,
+	RatePlanUUID string,
+) (*domain.RatePlan, error) {
+	return g.MockGetRatePlan(ctx, RatePlanUUID)
+}
+
+// GetRatePlans mocks GetRatePlans
+func (g *MockGetRepository) GetRatePlans(
+	ctx context.Context,
+	HotelUUID string,
+) ([]domain.RatePlan, error) {
+	return g.MockGetRatePlans(ctx, HotelUUID)
+}
+
+// GetRatePlanRates mocks GetRatePlanRates
+func (g *MockGetRepository) GetRatePlanRates(
+	ctx context.Context,
+	RatePlanUUIDs []string,
+	StartDate time.Time,
+	EndDate time.Time,
+) ([]domain.RatePlanRate, error) {
+	return g.MockGetRatePlanRates(ctx, RatePlanUUIDs, StartDate, EndDate)
+}
+
+// GetHotelRoomTypes mocks GetHotelRoomTypes
+func (g *MockGetRepository) GetHotelRoomTypes(
+	ctx context.Context,
+	HotelUUID string,
+) ([]domain.RoomType, error) {
+	return g.MockGetHotelRoomTypes(ctx, HotelUUID)
+}
+
+// GetRoomTypeInventories mocks GetRoomTypeInventories
+func (g *MockGetRepository) GetRoomTypeInventories(
+	ctx context.Context,
+	HotelUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+) ([]domain.RoomTypeInventory, error) {
+	return g.MockGetRoomTypeInventories(ctx, HotelUUID, StartDate, EndDate)
+}
+
+// GetRatesInRange mocks GetRatesInRange
+func (g *MockGetRepository) GetRatesInRange(
+	ctx context.Context,
+	RoomTypeUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+) ([]domain.Rate, error) {
+	return g.MockGetRatesInRange(ctx, RoomTypeUUID, StartDate, EndDate)
+}
+
+// GetPricingRules mocks GetPricingRules
+func (g *MockGetRepository) GetPricingRules(
+	ctx context.Context,
+	HotelUUID string,
+) ([]domain.PricingRule, error) {
+	return g.MockGetPricingRules(ctx, HotelUUID)
+}
+
+// GetPromotionByCode mocks GetPromotionByCode
+func (g *MockGetRepository) GetPromotionByCode(
+	ctx context.Context,
+	Code string,
+) (*domain.Promotion, error) {
+	return g.MockGetPromotionByCode(ctx, Code)
+}
+
+// GetReservation mocks GetReservation
+func (g *MockGetRepository) GetReservation(
+	ctx context.Context,
+	ReservationUUID string,
+) (*domain.Reservation, error) {
+	return g.MockGetReservation(ctx, ReservationUUID)
+}
+
+// GetPayment mocks GetPayment
+func (g *MockGetRepository) GetPayment(
+	ctx context.Context,
+	ReservationUUID string,
+) (*domain.Payment, error) {
+	return g.MockGetPayment(ctx, ReservationUUID)
+}
+
+// GetBookingPayment mocks GetBookingPayment
+func (g *MockGetRepository) GetBookingPayment(
+	ctx context.Context,
+	BookingUUID string,
+) (*domain.Payment, error) {
+	return g.MockGetBookingPayment(ctx, BookingUUID)
+}
+
+// GetWebhookSubscriptions mocks GetWebhookSubscriptions
+func (g *MockGetRepository) GetWebhookSubscriptions(
+	ctx context.Context,
+	HotelUUID string,
+) ([]domain.WebhookSubscription, error) {
+	return g.MockGetWebhookSubscriptions(ctx, HotelUUID)
+}
+
+// GetWebhookDeliveries mocks GetWebhookDeliveries
+func (g *MockGetRepository) GetWebhookDeliveries(
+	ctx context.Context,
+	SubscriptionUUID string,
+) ([]domain.WebhookDelivery, error) {
+	return g.MockGetWebhookDeliveries(ctx, SubscriptionUUID)
+}
+
+// GetGuest mocks GetGuest
+func (g *MockGetRepository) GetGuest(
+	ctx context.Context,
+	GuestUUID string,
+) (*domain.Guest, error) {
+	return g.MockGetGuest(ctx, GuestUUID)
+}
+
+// GetHotel mocks GetHotel
+func (g *MockGetRepository) GetHotel(
+	ctx context.Context,
+	HotelUUID string,
+) (*domain.Hotel, error) {
+	return g.MockGetHotel(ctx, HotelUUID)
+}
+
+// GetWaitlist mocks GetWaitlist
+func (g *MockGetRepository) GetWaitlist(
+	ctx context.Context,
+	RoomTypeUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+) ([]domain.WaitlistEntry, error) {
+	return g.MockGetWaitlist(ctx, RoomTypeUUID, StartDate, EndDate)
+}
+
+// GetOversoldNights mocks GetOversoldNights
+func (g *MockGetRepository) GetOversoldNights(
+	ctx context.Context,
+	HotelUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+) ([]domain.OversoldNight, error) {
+	return g.MockGetOversoldNights(ctx, HotelUUID, StartDate, EndDate)
+}
+
+// GetBooking mocks GetBooking
+func (g *MockGetRepository) GetBooking(
+	ctx context.Context,
+	BookingUUID string,
+) (*domain.Booking, error) {
+	return g.MockGetBooking(ctx, BookingUUID)
+}
+
+// GetReservationByConfirmationCode mocks GetReservationByConfirmationCode
+func (g *MockGetRepository) GetReservationByConfirmationCode(
+	ctx context.Context,
+	ConfirmationCode string,
+) (*domain.Reservation, error) {
+	return g.MockGetReservationByConfirmationCode(ctx, ConfirmationCode)
+}
+
+// GetLoyaltyBalance mocks GetLoyaltyBalance
+func (g *MockGetRepository) GetLoyaltyBalance(
+	ctx context.Context,
+	GuestUUID string,
+) (int, error) {
+	return g.MockGetLoyaltyBalance(ctx, GuestUUID)
+}
+
+// GetLoyaltyEntries mocks GetLoyaltyEntries
+func (g *MockGetRepository) GetLoyaltyEntries(
+	ctx context.Context,
+	GuestUUID string,
+) ([]domain.LoyaltyEntry, error) {
+	return g.MockGetLoyaltyEntries(ctx, GuestUUID)
+}
+
 // MockUpdateRepository mocks the database's Update repository
 type MockUpdateRepository struct {
 	MockCancelReservation func(
@@ -247,6 +762,107 @@ type MockUpdateRepository struct {
 		GuestUUID string,
 		RoomTypeUUID string,
 	) (*domain.Reservation, error)
+	MockConfirmReservationPayment func(
+		ctx context.Context,
+		payment *domain.Payment,
+	) (*domain.Reservation, error)
+	MockFailReservationPayment func(
+		ctx context.Context,
+		payment *domain.Payment,
+	) (*domain.Reservation, error)
+	MockUpdatePayment func(
+		ctx context.Context,
+		payment *domain.Payment,
+	) (*domain.Payment, error)
+	MockClaimDuePayments func(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) ([]domain.Payment, error)
+	MockConfirmHold func(
+		ctx context.Context,
+		ReservationUUID string,
+		now time.Time,
+	) (*domain.Reservation, error)
+	MockReleaseExpiredHolds func(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) (int, error)
+	MockCheckInReservation func(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Reservation, error)
+	MockCheckOutReservation func(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Reservation, error)
+	MockRelayOutboxEvents func(
+		ctx context.Context,
+		limit int,
+		consumers []string,
+	) (int, error)
+	MockClaimEventDeliveries func(
+		ctx context.Context,
+		consumer string,
+		now time.Time,
+		limit int,
+	) ([]domain.EventDelivery, error)
+	MockRecordEventDelivery func(
+		ctx context.Context,
+		delivery *domain.EventDelivery,
+	) error
+	MockClaimWebhookDeliveries func(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) ([]domain.WebhookDelivery, error)
+	MockRecordWebhookDelivery func(
+		ctx context.Context,
+		delivery *domain.WebhookDelivery,
+	) error
+	MockRecordArrivalReminders func(
+		ctx context.Context,
+		arrivalDate time.Time,
+		limit int,
+	) (int, error)
+	MockOfferWaitlistEntry func(
+		ctx context.Context,
+		entry *domain.WaitlistEntry,
+		hold *domain.Reservation,
+	) (*domain.Reservation, error)
+	MockSetOverbookingLimits func(
+		ctx context.Context,
+		RoomTypeUUID string,
+		nights []time.Time,
+		kind domain.OverbookingType,
+		value int,
+	) ([]domain.RoomTypeInventory, error)
+	MockCancelBookingLine func(
+		ctx context.Context,
+		BookingUUID string,
+		LineUUID string,
+	) (*domain.Booking, error)
+	MockConfirmBookingBlock func(
+		ctx context.Context,
+		BookingUUID string,
+		payment *domain.Payment,
+		now time.Time,
+	) (*domain.Booking, error)
+	MockReleaseExpiredBlocks func(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) (int, error)
+	MockModifyReservation func(
+		ctx context.Context,
+		modified *domain.Reservation,
+		authorization string,
+	) (*domain.Reservation, string, error)
+	MockReverseLoyaltyEntries func(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.LoyaltyEntry, error)
 }
 
 // NewMockUpdateRepository initializes a new MockUpdate Repository
@@ -255,6 +871,73 @@ func NewMockUpdateRepository() *MockUpdateRepository {
 		MockCancelReservation: func(ctx context.Context, GuestUUID, RoomTypeUUID string) (*domain.Reservation, error) {
 			return &domain.Reservation{}, nil
 		},
+		MockConfirmReservationPayment: func(ctx context.Context, payment *domain.Payment) (*domain.Reservation, error) {
+			return &domain.Reservation{Status: string(domain.RESERVED)}, nil
+		},
+		MockFailReservationPayment: func(ctx context.Context, payment *domain.Payment) (*domain.Reservation, error) {
+			return &domain.Reservation{Status: string(domain.PAYMENT_FAILED)}, nil
+		},
+		MockUpdatePayment: func(ctx context.Context, payment *domain.Payment) (*domain.Payment, error) {
+			return payment, nil
+		},
+		MockClaimDuePayments: func(ctx context.Context, now time.Time, limit int) ([]domain.Payment, error) {
+			return nil, nil
+		},
+		MockConfirmHold: func(ctx context.Context, ReservationUUID string, now time.Time) (*domain.Reservation, error) {
+			return &domain.Reservation{Status: string(domain.PENDING)}, nil
+		},
+		MockReleaseExpiredHolds: func(ctx context.Context, now time.Time, limit int) (int, error) {
+			return 0, nil
+		},
+		MockCheckInReservation: func(ctx context.Context, ReservationUUID string) (*domain.Reservation, error) {
+			return &domain.Reservation{Status: string(domain.CHECKED_IN)}, nil
+		},
+		MockCheckOutReservation: func(ctx context.Context, ReservationUUID string) (*domain.Reservation, error) {
+			return &domain.Reservation{Status: string(domain.CHECKED_OUT)}, nil
+		},
+		MockRelayOutboxEvents: func(ctx context.Context, limit int, consumers []string) (int, error) {
+			return 0, nil
+		},
+		MockClaimEventDeliveries: func(ctx context.Context, consumer string, now time.Time, limit int) ([]domain.EventDelivery, error) {
+			return nil, nil
+		},
+		MockRecordEventDelivery: func(ctx context.Context, delivery *domain.EventDelivery) error {
+			return nil
+		},
+		MockClaimWebhookDeliveries: func(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+			return nil, nil
+		},
+		MockRecordWebhookDelivery: func(ctx context.Context, delivery *domain.WebhookDelivery) error {
+			return nil
+		},
+		MockRecordArrivalReminders: func(ctx context.Context, arrivalDate time.Time, limit int) (int, error) {
+			return 0, nil
+		},
+		MockOfferWaitlistEntry: func(ctx context.Context, entry *domain.WaitlistEntry, hold *domain.Reservation) (*domain.Reservation, error) {
+			return hold, nil
+		},
+		MockSetOverbookingLimits: func(ctx context.Context, RoomTypeUUID string, nights []time.Time, kind domain.OverbookingType, value int) ([]domain.RoomTypeInventory, error) {
+			inventories := make([]domain.RoomTypeInventory, 0, len(nights))
+			for _, night := range nights {
+				inventories = append(inventories, domain.RoomTypeInventory{RoomTypeUUID: RoomTypeUUID, Date: night, OverbookingLimit: int64(value)})
+			}
+			return inventories, nil
+		},
+		MockCancelBookingLine: func(ctx context.Context, BookingUUID string, LineUUID string) (*domain.Booking, error) {
+			return &domain.Booking{AbstractBase: domain.AbstractBase{UUID: BookingUUID}}, nil
+		},
+		MockConfirmBookingBlock: func(ctx context.Context, BookingUUID string, payment *domain.Payment, now time.Time) (*domain.Booking, error) {
+			return &domain.Booking{AbstractBase: domain.AbstractBase{UUID: BookingUUID}, Status: domain.BOOKING_CONFIRMED}, nil
+		},
+		MockReleaseExpiredBlocks: func(ctx context.Context, now time.Time, limit int) (int, error) {
+			return 0, nil
+		},
+		MockModifyReservation: func(ctx context.Context, modified *domain.Reservation, authorization string) (*domain.Reservation, string, error) {
+			return modified, "", nil
+		},
+		MockReverseLoyaltyEntries: func(ctx context.Context, ReservationUUID string) (*domain.LoyaltyEntry, error) {
+			return nil, nil
+		},
 	}
 }
 
@@ -266,3 +949,188 @@ func (u *MockUpdateRepository) CancelReservation(
 ) (*domain.Reservation, error) {
 	return u.MockCancelReservation(ctx, GuestUUID, RoomTypeUUID)
 }
+
+// ConfirmReservationPayment mocks ConfirmReservationPayment
+func (u *MockUpdateRepository) ConfirmReservationPayment(
+	ctx context.Context,
+	payment *domain.Payment,
+) (*domain.Reservation, error) {
+	return u.MockConfirmReservationPayment(ctx, payment)
+}
+
+// FailReservationPayment mocks FailReservationPayment
+func (u *MockUpdateRepository) FailReservationPayment(
+	ctx context.Context,
+	payment *domain.Payment,
+) (*domain.Reservation, error) {
+	return u.MockFailReservationPayment(ctx, payment)
+}
+
+// UpdatePayment mocks UpdatePayment
+func (u *MockUpdateRepository) UpdatePayment(
+	ctx context.Context,
+	payment *domain.Payment,
+) (*domain.Payment, error) {
+	return u.MockUpdatePayment(ctx, payment)
+}
+
+// ClaimDuePayments mocks ClaimDuePayments
+func (u *MockUpdateRepository) ClaimDuePayments(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]domain.Payment, error) {
+	return u.MockClaimDuePayments(ctx, now, limit)
+}
+
+// ConfirmHold mocks ConfirmHold
+func (u *MockUpdateRepository) ConfirmHold(
+	ctx context.Context,
+	ReservationUUID string,
+	now time.Time,
+) (*domain.Reservation, error) {
+	return u.MockConfirmHold(ctx, ReservationUUID, now)
+}
+
+// ReleaseExpiredHolds mocks ReleaseExpiredHolds
+func (u *MockUpdateRepository) ReleaseExpiredHolds(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) (int, error) {
+	return u.MockReleaseExpiredHolds(ctx, now, limit)
+}
+
+// CheckInReservation mocks CheckInReservation
+func (u *MockUpdateRepository) CheckInReservation(
+	ctx context.Context,
+	ReservationUUID string,
+) (*domain.Reservation, error) {
+	return u.MockCheckInReservation(ctx, ReservationUUID)
+}
+
+// CheckOutReservation mocks CheckOutReservation
+func (u *MockUpdateRepository) CheckOutReservation(
+	ctx context.Context,
+	ReservationUUID string,
+) (*domain.Reservation, error) {
+	return u.MockCheckOutReservation(ctx, ReservationUUID)
+}
+
+// RelayOutboxEvents mocks RelayOutboxEvents
+func (u *MockUpdateRepository) RelayOutboxEvents(
+	ctx context.Context,
+	limit int,
+	consumers []string,
+) (int, error) {
+	return u.MockRelayOutboxEvents(ctx, limit, consumers)
+}
+
+// ClaimEventDeliveries mocks ClaimEventDeliveries
+func (u *MockUpdateRepository) ClaimEventDeliveries(
+	ctx context.Context,
+	consumer string,
+	now time.Time,
+	limit int,
+) ([]domain.EventDelivery, error) {
+	return u.MockClaimEventDeliveries(ctx, consumer, now, limit)
+}
+
+// RecordEventDelivery mocks RecordEventDelivery
+func (u *MockUpdateRepository) RecordEventDelivery(
+	ctx context.Context,
+	delivery *domain.EventDelivery,
+) error {
+	return u.MockRecordEventDelivery(ctx, delivery)
+}
+
+// ClaimWebhookDeliveries mocks ClaimWebhookDeliveries
+func (u *MockUpdateRepository) ClaimWebhookDeliveries(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]domain.WebhookDelivery, error) {
+	return u.MockClaimWebhookDeliveries(ctx, now, limit)
+}
+
+// RecordWebhookDelivery mocks RecordWebhookDelivery
+func (u *MockUpdateRepository) RecordWebhookDelivery(
+	ctx context.Context,
+	delivery *domain.WebhookDelivery,
+) error {
+	return u.MockRecordWebhookDelivery(ctx, delivery)
+}
+
+// RecordArrivalReminders mocks RecordArrivalReminders
+func (u *MockUpdateRepository) RecordArrivalReminders(
+	ctx context.Context,
+	arrivalDate time.Time,
+	limit int,
+) (int, error) {
+	return u.MockRecordArrivalReminders(ctx, arrivalDate, limit)
+}
+
+// OfferWaitlistEntry mocks OfferWaitlistEntry
+func (u *MockUpdateRepository) OfferWaitlistEntry(
+	ctx context.Context,
+	entry *domain.WaitlistEntry,
+	hold *domain.Reservation,
+) (*domain.Reservation, error) {
+	return u.MockOfferWaitlistEntry(ctx, entry, hold)
+}
+
+// SetOverbookingLimits mocks SetOverbookingLimits
+func (u *MockUpdateRepository) SetOverbookingLimits(
+	ctx context.Context,
+	RoomTypeUUID string,
+	nights []time.Time,
+	kind domain.OverbookingType,
+	value int,
+) ([]domain.RoomTypeInventory, error) {
+	return u.MockSetOverbookingLimits(ctx, RoomTypeUUID, nights, kind, value)
+}
+
+// CancelBookingLine mocks CancelBookingLine
+func (u *MockUpdateRepository) CancelBookingLine(
+	ctx context.Context,
+	BookingUUID string,
+	LineUUID string,
+) (*domain.Booking, error) {
+	return u.MockCancelBookingLine(ctx, BookingUUID, LineUUID)
+}
+
+// ConfirmBookingBlock mocks ConfirmBookingBlock
+func (u *MockUpdateRepository) ConfirmBookingBlock(
+	ctx context.Context,
+	BookingUUID string,
+	payment *domain.Payment,
+	now time.Time,
+) (*domain.Booking, error) {
+	return u.MockConfirmBookingBlock(ctx, BookingUUID, payment, now)
+}
+
+// ReleaseExpiredBlocks mocks ReleaseExpiredBlocks
+func (u *MockUpdateRepository) ReleaseExpiredBlocks(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) (int, error) {
+	return u.MockReleaseExpiredBlocks(ctx, now, limit)
+}
+
+// ModifyReservation mocks ModifyReservation
+func (u *MockUpdateRepository) ModifyReservation(
+	ctx context.Context,
+	modified *domain.Reservation,
+	authorization string,
+) (*domain.Reservation, string, error) {
+	return u.MockModifyReservation(ctx, modified, authorization)
+}
+
+// ReverseLoyaltyEntries mocks ReverseLoyaltyEntries
+func (u *MockUpdateRepository) ReverseLoyaltyEntries(
+	ctx context.Context,
+	ReservationUUID string,
+) (*domain.LoyaltyEntry, error) {
+	return u.MockReverseLoyaltyEntries(ctx, ReservationUUID)
+}
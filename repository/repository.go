@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/MelvinKim/Hotel-Reservation-System/domain"
 )
@@ -16,6 +17,10 @@ type CreateRepository interface {
 		ctx context.Context,
 		rate *domain.Rate,
 	) (*domain.Rate, error)
+	UpsertRates(
+		ctx context.Context,
+		rates []*domain.Rate,
+	) (int64, error)
 	CreateGuest(
 		ctx context.Context,
 		guest *domain.Guest,
@@ -32,6 +37,43 @@ type CreateRepository interface {
 		ctx context.Context,
 		room *domain.Room,
 	) (*domain.Room, error)
+	CreateRatePlan(
+		ctx context.Context,
+		ratePlan *domain.RatePlan,
+	) (*domain.RatePlan, error)
+	UpsertRatePlanRates(
+		ctx context.Context,
+		rates []*domain.RatePlanRate,
+	) error
+	CreatePricingRule(
+		ctx context.Context,
+		rule *domain.PricingRule,
+	) (*domain.PricingRule, error)
+	CreatePromotion(
+		ctx context.Context,
+		promotion *domain.Promotion,
+	) (*domain.Promotion, error)
+	CreateWebhookSubscription(
+		ctx context.Context,
+		subscription *domain.WebhookSubscription,
+	) (*domain.WebhookSubscription, error)
+	CreateWebhookDeliveries(
+		ctx context.Context,
+		deliveries []*domain.WebhookDelivery,
+	) error
+	CreateWaitlistEntry(
+		ctx context.Context,
+		entry *domain.WaitlistEntry,
+	) (*domain.WaitlistEntry, error)
+	CreateBooking(
+		ctx context.Context,
+		booking *domain.Booking,
+		payment *domain.Payment,
+	) (*domain.Booking, error)
+	CreateLoyaltyEntry(
+		ctx context.Context,
+		entry *domain.LoyaltyEntry,
+	) error
 }
 
 // GetRepository defines get/fetch contract
@@ -51,6 +93,10 @@ type GetRepository interface {
 	GetRoomTypes(
 		ctx context.Context,
 	) ([]domain.RoomType, error)
+	GetRoomType(
+		ctx context.Context,
+		RoomTypeUUID string,
+	) (*domain.RoomType, error)
 	GetRates(
 		ctx context.Context,
 	) ([]domain.Rate, error)
@@ -64,6 +110,100 @@ type GetRepository interface {
 		RoomTypeUUID string,
 		HotelUUID string,
 	) (*domain.Room, error)
+	GetRatePlan(
+		ctx context.Context,
+		RatePlanUUID string,
+	) (*domain.RatePlan, error)
+	GetRatePlans(
+		ctx context.Context,
+		HotelUUID string,
+	) ([]domain.RatePlan, error)
+	GetRatePlanRates(
+		ctx context.Context,
+		RatePlanUUIDs []string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.RatePlanRate, error)
+	GetHotelRoomTypes(
+		ctx context.Context,
+		HotelUUID string,
+	) ([]domain.RoomType, error)
+	GetRoomTypeInventories(
+		ctx context.Context,
+		HotelUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.RoomTypeInventory, error)
+	GetRatesInRange(
+		ctx context.Context,
+		RoomTypeUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.Rate, error)
+	GetPricingRules(
+		ctx context.Context,
+		HotelUUID string,
+	) ([]domain.PricingRule, error)
+	GetPromotionByCode(
+		ctx context.Context,
+		Code string,
+	) (*domain.Promotion, error)
+	GetReservation(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Reservation, error)
+	GetPayment(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Payment, error)
+	GetBookingPayment(
+		ctx context.Context,
+		BookingUUID string,
+	) (*domain.Payment, error)
+	GetWebhookSubscriptions(
+		ctx context.Context,
+		HotelUUID string,
+	) ([]domain.WebhookSubscription, error)
+	GetWebhookDeliveries(
+		ctx context.Context,
+		SubscriptionUUID string,
+	) ([]domain.WebhookDelivery, error)
+	GetGuest(
+		ctx context.Context,
+		GuestUUID string,
+	) (*domain.Guest, error)
+	GetHotel(
+		ctx context.Context,
+		HotelUUID string,
+	) (*domain.Hotel, error)
+	GetWaitlist(
+		ctx context.Context,
+		RoomTypeUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.WaitlistEntry, error)
+	GetOversoldNights(
+		ctx context.Context,
+		HotelUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.OversoldNight, error)
+	GetBooking(
+		ctx context.Context,
+		BookingUUID string,
+	) (*domain.Booking, error)
+	GetReservationByConfirmationCode(
+		ctx context.Context,
+		ConfirmationCode string,
+	) (*domain.Reservation, error)
+	GetLoyaltyBalance(
+		ctx context.Context,
+		GuestUUID string,
+	) (int, error)
+	GetLoyaltyEntries(
+		ctx context.Context,
+		GuestUUID string,
+	) ([]domain.LoyaltyEntry, error)
 }
 
 // UpdateRepository defined update/change contract
@@ -73,6 +213,107 @@ type UpdateRepository interface {
 		GuestUUID string,
 		RoomTypeUUID string,
 	) (*domain.Reservation, error)
+	ConfirmReservationPayment(
+		ctx context.Context,
+		payment *domain.Payment,
+	) (*domain.Reservation, error)
+	FailReservationPayment(
+		ctx context.Context,
+		payment *domain.Payment,
+	) (*domain.Reservation, error)
+	UpdatePayment(
+		ctx context.Context,
+		payment *domain.Payment,
+	) (*domain.Payment, error)
+	ClaimDuePayments(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) ([]domain.Payment, error)
+	ConfirmHold(
+		ctx context.Context,
+		ReservationUUID string,
+		now time.Time,
+	) (*domain.Reservation, error)
+	ReleaseExpiredHolds(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) (int, error)
+	CheckInReservation(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Reservation, error)
+	CheckOutReservation(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Reservation, error)
+	RelayOutboxEvents(
+		ctx context.Context,
+		limit int,
+		consumers []string,
+	) (int, error)
+	ClaimEventDeliveries(
+		ctx context.Context,
+		consumer string,
+		now time.Time,
+		limit int,
+	) ([]domain.EventDelivery, error)
+	RecordEventDelivery(
+		ctx context.Context,
+		delivery *domain.EventDelivery,
+	) error
+	ClaimWebhookDeliveries(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) ([]domain.WebhookDelivery, error)
+	RecordWebhookDelivery(
+		ctx context.Context,
+		delivery *domain.WebhookDelivery,
+	) error
+	RecordArrivalReminders(
+		ctx context.Context,
+		arrivalDate time.Time,
+		limit int,
+	) (int, error)
+	OfferWaitlistEntry(
+		ctx context.Context,
+		entry *domain.WaitlistEntry,
+		hold *domain.Reservation,
+	) (*domain.Reservation, error)
+	SetOverbookingLimits(
+		ctx context.Context,
+		RoomTypeUUID string,
+		nights []time.Time,
+		kind domain.OverbookingType,
+		value int,
+	) ([]domain.RoomTypeInventory, error)
+	CancelBookingLine(
+		ctx context.Context,
+		BookingUUID string,
+		LineUUID string,
+	) (*domain.Booking, error)
+	ConfirmBookingBlock(
+		ctx context.Context,
+		BookingUUID string,
+		payment *domain.Payment,
+		now time.Time,
+	) (*domain.Booking, error)
+	ReleaseExpiredBlocks(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+	) (int, error)
+	ModifyReservation(
+		ctx context.Context,
+		modified *domain.Reservation,
+		authorization string,
+	) (*domain.Reservation, string, error)
+	ReverseLoyaltyEntries(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.LoyaltyEntry, error)
 }
 
 // DeleteRepository defines deletion/inactivation contract
@@ -2,23 +2,42 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
 	"github.com/MelvinKim/Hotel-Reservation-System/presentation"
 )
 
-const PORT = 9000
-
 func main() {
-	ctx := context.Background()
+	// SIGTERM is what docker sends on stop, the server then drains before the SIGKILL that follows
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	srv := presentation.PrepareServer(ctx, PORT)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("can't load configuration: %v", err)
+	}
+	if err := logging.Setup(cfg.Log); err != nil {
+		log.Fatalf("can't set up logging: %v", err)
+	}
 
-	if err := srv.ListenAndServe(); err != nil {
-		log.Errorf("server start up error: %v", err)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(ctx, cfg, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
 		return
 	}
 
-	log.Infof("server up and running on port %d", PORT)
+	app, err := presentation.PrepareServer(ctx, cfg)
+	if err != nil {
+		log.Fatalf("server start up error: %v", err)
+	}
+	if err := app.Run(ctx); err != nil {
+		log.Fatalf("server error: %v", err)
+	}
 }
@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+)
+
+// blockReleaserBatchSize caps how many group blocks a single releaser transaction gives back
+const blockReleaserBatchSize = 100
+
+// CreateBooking quotes every line of a multi-room booking, authorizes its price and books all of its
+// rooms at once. Bookings with a release date are group blocks whose rooms are held until then unless
+// confirmed, they are paid for when they are confirmed
+func (u *Usecase) CreateBooking(
+	ctx context.Context,
+	booking *domain.Booking,
+) (_ *domain.Booking, err error) {
+	ctx, endSpan := startSpan(ctx, "CreateBooking")
+	defer endSpan(&err)
+	if err := booking.Validate(); err != nil {
+		return nil, err
+	}
+	guest, err := u.Get.GetGuest(ctx, booking.GuestUUID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get guest: %w", err)
+	}
+	if guest == nil {
+		return nil, fmt.Errorf("guest %s doesn't exist", booking.GuestUUID)
+	}
+	now := time.Now()
+	status := domain.BOOKING_CONFIRMED
+	if booking.ReleaseDate != nil {
+		if !booking.ReleaseDate.After(now) {
+			return nil, errors.New("a group block's release date must be in the future")
+		}
+		status = domain.BOOKING_BLOCKED
+	}
+	for i := range booking.Lines {
+		line := &booking.Lines[i]
+		unitPrice, err := u.quoteReservation(ctx, &domain.Reservation{
+			HotelUUID:    booking.HotelUUID,
+			RoomTypeUUID: line.RoomTypeUUID,
+			RatePlanUUID: line.RatePlanUUID,
+			StartDate:    booking.StartDate,
+			EndDate:      booking.EndDate,
+		}, now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i, err)
+		}
+		line.UnitPrice = unitPrice
+		line.TotalPrice = unitPrice * int(line.Quantity)
+		line.Status = status
+	}
+	booking.Status = status
+	booking.RecalculateTotal()
+	var charge *domain.Payment
+	if status == domain.BOOKING_CONFIRMED {
+		if charge, err = u.authorizeBookingPayment(ctx, booking); err != nil {
+			return nil, err
+		}
+	}
+	created, err := u.Create.CreateBooking(ctx, booking, charge)
+	if err != nil {
+		return nil, u.voidUnrecordedPayment(ctx, charge, err)
+	}
+	return created, nil
+}
+
+// GetBooking gets a booking and its lines
+func (u *Usecase) GetBooking(
+	ctx context.Context,
+	BookingUUID string,
+) (_ *domain.Booking, err error) {
+	ctx, endSpan := startSpan(ctx, "GetBooking")
+	defer endSpan(&err)
+	booking, err := u.Get.GetBooking(ctx, BookingUUID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get booking: %w", err)
+	}
+	if booking == nil {
+		return nil, fmt.Errorf("booking %s doesn't exist", BookingUUID)
+	}
+	return booking, nil
+}
+
+// CancelBookingLine gives back the rooms of one line of a booking, keeping the rest of the booking, and
+// refunds the line's price according to its rate plan's cancellation terms
+func (u *Usecase) CancelBookingLine(
+	ctx context.Context,
+	BookingUUID string,
+	LineUUID string,
+) (_ *domain.Booking, err error) {
+	ctx, endSpan := startSpan(ctx, "CancelBookingLine")
+	defer endSpan(&err)
+	booking, err := u.Update.CancelBookingLine(ctx, BookingUUID, LineUUID)
+	if err != nil {
+		return nil, err
+	}
+	// the refund owed was recorded with the cancellation, the payment settler retries it when it fails here
+	charge, getErr := u.Get.GetBookingPayment(ctx, BookingUUID)
+	if getErr != nil {
+		logging.FromContext(ctx).Errorf("can't get the payment of booking %s: %v", BookingUUID, getErr)
+		return booking, nil
+	}
+	if charge != nil && charge.SettleAt != nil {
+		if settleErr := u.settlePayment(ctx, charge); settleErr != nil {
+			logging.FromContext(ctx).Errorf("can't settle the payment of booking %s: %v", BookingUUID, settleErr)
+		}
+	}
+	return booking, nil
+}
+
+// ConfirmBookingBlock authorizes the price of a group block and books its rooms before its release date
+func (u *Usecase) ConfirmBookingBlock(
+	ctx context.Context,
+	BookingUUID string,
+	PaymentMethod string,
+) (_ *domain.Booking, err error) {
+	ctx, endSpan := startSpan(ctx, "ConfirmBookingBlock")
+	defer endSpan(&err)
+	booking, err := u.Get.GetBooking(ctx, BookingUUID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get booking: %w", err)
+	}
+	if booking == nil {
+		return nil, fmt.Errorf("booking %s doesn't exist", BookingUUID)
+	}
+	switch booking.Status {
+	case domain.BOOKING_BLOCKED:
+	case domain.BOOKING_RELEASED:
+		return nil, domain.ErrBlockReleased
+	default:
+		return nil, fmt.Errorf("can't confirm a %s booking", booking.Status)
+	}
+	booking.PaymentMethod = PaymentMethod
+	charge, err := u.authorizeBookingPayment(ctx, booking)
+	if err != nil {
+		return nil, err
+	}
+	confirmed, err := u.Update.ConfirmBookingBlock(ctx, BookingUUID, charge, time.Now())
+	if err != nil {
+		return nil, u.voidUnrecordedPayment(ctx, charge, err)
+	}
+	return confirmed, nil
+}
+
+// ReleaseExpiredBlocks gives back the rooms of every group block that passed its release date unconfirmed
+func (u *Usecase) ReleaseExpiredBlocks(ctx context.Context) (int, error) {
+	released := 0
+	for {
+		count, err := u.Update.ReleaseExpiredBlocks(ctx, time.Now(), blockReleaserBatchSize)
+		released += count
+		if err != nil || count < blockReleaserBatchSize {
+			return released, err
+		}
+	}
+}
+
+// RunBlockReleaser releases expired group blocks every interval until the context is cancelled
+func (u *Usecase) RunBlockReleaser(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := u.ReleaseExpiredBlocks(ctx)
+			if err != nil {
+				logging.FromContext(ctx).Errorf("can't release expired group blocks: %v", err)
+			}
+			if released > 0 {
+				logging.FromContext(ctx).Infof("released %d expired group blocks", released)
+			}
+		}
+	}
+}
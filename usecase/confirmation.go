@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+// LookupReservation finds a reservation by its confirmation code and the guest's last name, so that
+// guests can manage their booking without an account. Every mismatch returns domain.ErrReservationNotFound
+// so that the lookup doesn't reveal which codes exist
+func (u *Usecase) LookupReservation(
+	ctx context.Context,
+	ConfirmationCode string,
+	LastName string,
+) (_ *domain.Reservation, err error) {
+	ctx, endSpan := startSpan(ctx, "LookupReservation")
+	defer endSpan(&err)
+	code := domain.NormalizeConfirmationCode(ConfirmationCode)
+	if !domain.ValidConfirmationCode(code) {
+		return nil, domain.ErrReservationNotFound
+	}
+	reservation, err := u.Get.GetReservationByConfirmationCode(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("can't look up reservation: %w", err)
+	}
+	if reservation == nil || !strings.EqualFold(strings.TrimSpace(LastName), strings.TrimSpace(reservation.Guest.LastName)) {
+		return nil, domain.ErrReservationNotFound
+	}
+	return reservation, nil
+}
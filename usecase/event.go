@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/events"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+)
+
+const (
+	// eventRelayBatchSize caps how many outbox events a single relay transaction publishes
+	eventRelayBatchSize = 100
+	// eventConsumeBatchSize caps how many events a consumer claims at a time
+	eventConsumeBatchSize = 50
+)
+
+// CheckIn records a guest arriving for a RESERVED stay
+func (u *Usecase) CheckIn(
+	ctx context.Context,
+	ReservationUUID string,
+) (_ *domain.Reservation, err error) {
+	ctx, endSpan := startSpan(ctx, "CheckIn")
+	defer endSpan(&err)
+	return u.Update.CheckInReservation(ctx, ReservationUUID)
+}
+
+// CheckOut records a guest leaving at the end of their stay
+func (u *Usecase) CheckOut(
+	ctx context.Context,
+	ReservationUUID string,
+) (_ *domain.Reservation, err error) {
+	ctx, endSpan := startSpan(ctx, "CheckOut")
+	defer endSpan(&err)
+	return u.Update.CheckOutReservation(ctx, ReservationUUID)
+}
+
+// RelayEvents publishes every outbox event that hasn't been published yet to the consumers
+func (u *Usecase) RelayEvents(
+	ctx context.Context,
+	consumers []events.Consumer,
+) (int, error) {
+	names := make([]string, 0, len(consumers))
+	for _, consumer := range consumers {
+		names = append(names, consumer.Name)
+	}
+	relayed := 0
+	for {
+		count, err := u.Update.RelayOutboxEvents(ctx, eventRelayBatchSize, names)
+		relayed += count
+		if err != nil || count < eventRelayBatchSize {
+			return relayed, err
+		}
+	}
+}
+
+// ConsumeEvents hands every event due to the consumer to it, outside of any transaction, and records
+// the outcome of each delivery. It returns how many events it attempted
+func (u *Usecase) ConsumeEvents(
+	ctx context.Context,
+	consumer events.Consumer,
+) (int, error) {
+	attempted := 0
+	for {
+		deliveries, err := u.Update.ClaimEventDeliveries(ctx, consumer.Name, time.Now(), eventConsumeBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+		for i := range deliveries {
+			delivery := &deliveries[i]
+			var consumeErr error
+			if delivery.Event == nil {
+				consumeErr = errors.New("the delivery's event no longer exists")
+			} else {
+				consumeErr = consumer.Publisher.Publish(ctx, delivery.Event)
+			}
+			if consumeErr != nil {
+				logging.FromContext(ctx).Warnf("%s can't consume event %s, it will be retried: %v", consumer.Name, delivery.EventUUID, consumeErr)
+			}
+			delivery.RecordAttempt(time.Now(), consumeErr)
+			if err := u.Update.RecordEventDelivery(ctx, delivery); err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+		if len(deliveries) < eventConsumeBatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// RunEventRelay publishes outbox events to the consumers every interval until the context is cancelled
+func (u *Usecase) RunEventRelay(
+	ctx context.Context,
+	consumers []events.Consumer,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := u.RelayEvents(ctx, consumers); err != nil {
+				logging.FromContext(ctx).Errorf("can't relay outbox events: %v", err)
+			}
+		}
+	}
+}
+
+// RunEventConsumer hands the consumer its events every interval until the context is cancelled.
+// Every consumer runs on its own, so a slow or failing consumer doesn't delay the others
+func (u *Usecase) RunEventConsumer(
+	ctx context.Context,
+	consumer events.Consumer,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := u.ConsumeEvents(ctx, consumer); err != nil {
+				logging.FromContext(ctx).Errorf("can't consume events with %s: %v", consumer.Name, err)
+			}
+		}
+	}
+}
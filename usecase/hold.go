@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+)
+
+const (
+	// ReservationHoldDuration is how long a guest has to confirm a hold before its inventory is released
+	ReservationHoldDuration = 15 * time.Minute
+	// holdReaperBatchSize caps how many reservations a single reaper transaction releases
+	holdReaperBatchSize = 100
+)
+
+// HoldReservation quotes a reservation and claims its inventory for ReservationHoldDuration,
+// without taking payment. The hold is booked by confirming it before it expires
+func (u *Usecase) HoldReservation(
+	ctx context.Context,
+	reservation *domain.Reservation,
+) (_ *domain.Reservation, err error) {
+	ctx, endSpan := startSpan(ctx, "HoldReservation")
+	defer endSpan(&err)
+	now := time.Now()
+	if err := u.priceReservation(ctx, reservation, now); err != nil {
+		return nil, err
+	}
+	expiresAt := now.Add(ReservationHoldDuration)
+	reservation.Status = string(domain.HELD)
+	reservation.ExpiresAt = &expiresAt
+	held, err := u.Create.CreateReservation(ctx, reservation)
+	if err != nil {
+		u.countSoldOut(reservation, err)
+		return nil, err
+	}
+	return held, nil
+}
+
+// ConfirmReservation books a hold that hasn't expired by authorizing its payment. The payment is
+// authorized before the hold is confirmed, so a declined payment method leaves the hold HELD for the guest
+// to try another one until it expires
+func (u *Usecase) ConfirmReservation(
+	ctx context.Context,
+	ReservationUUID string,
+	PaymentMethod string,
+) (_ *domain.Reservation, err error) {
+	ctx, endSpan := startSpan(ctx, "ConfirmReservation")
+	defer endSpan(&err)
+	held, err := u.Get.GetReservation(ctx, ReservationUUID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get reservation: %w", err)
+	}
+	now := time.Now()
+	if held == nil || held.Status != string(domain.HELD) || held.ExpiresAt == nil || !held.ExpiresAt.After(now) {
+		return nil, domain.ErrHoldExpired
+	}
+	charge := &domain.Payment{
+		ReservationUUID: &held.UUID,
+		Amount:          held.TotalPrice,
+		Currency:        domain.DefaultCurrency,
+	}
+	if err := u.authorizeCharge(ctx, charge, PaymentMethod); err != nil {
+		return nil, err
+	}
+	// the hold may have lapsed or been confirmed by another request since it was read
+	if _, err := u.Update.ConfirmHold(ctx, ReservationUUID, now); err != nil {
+		return nil, u.voidUnrecordedPayment(ctx, charge, err)
+	}
+	confirmed, err := u.Update.ConfirmReservationPayment(ctx, charge)
+	if err != nil {
+		return nil, u.voidUnrecordedPayment(ctx, charge, err)
+	}
+	u.Metrics.ReservationCreated(confirmed.HotelUUID)
+	return confirmed, nil
+}
+
+// ReleaseExpiredHolds releases the inventory of every hold that has expired, and of every PENDING
+// reservation whose payment wasn't authorized within domain.PendingPaymentTimeout
+func (u *Usecase) ReleaseExpiredHolds(ctx context.Context) (int, error) {
+	released := 0
+	for {
+		count, err := u.Update.ReleaseExpiredHolds(ctx, time.Now(), holdReaperBatchSize)
+		released += count
+		if err != nil || count < holdReaperBatchSize {
+			return released, err
+		}
+	}
+}
+
+// RunHoldReaper releases expired holds and pending reservations every interval until the context is
+// cancelled
+func (u *Usecase) RunHoldReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := u.ReleaseExpiredHolds(ctx)
+			if err != nil {
+				logging.FromContext(ctx).Errorf("can't release expired reservations: %v", err)
+			}
+			if released > 0 {
+				logging.FromContext(ctx).Infof("released %d expired reservations", released)
+			}
+		}
+	}
+}
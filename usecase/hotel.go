@@ -2,9 +2,14 @@ package usecase
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"time"
 
+	"github.com/MelvinKim/Hotel-Reservation-System/application/common/dto"
 	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
 	"github.com/MelvinKim/Hotel-Reservation-System/repository"
 )
 
@@ -33,6 +38,50 @@ type UsecasesContract interface {
 		ctx context.Context,
 		rate *domain.Rate,
 	) (*domain.Rate, error)
+	BulkUpsertRates(
+		ctx context.Context,
+		payload *dto.BulkRatePayload,
+	) (*dto.BulkRateSummary, error)
+	UploadRateCalendar(
+		ctx context.Context,
+		HotelUUID string,
+		RoomTypeUUID string,
+		document io.Reader,
+	) (*dto.BulkRateSummary, error)
+	CreateRatePlan(
+		ctx context.Context,
+		ratePlan *domain.RatePlan,
+	) (*domain.RatePlan, error)
+	SetRatePlanRates(
+		ctx context.Context,
+		payload *dto.RatePlanRatesPayload,
+	) ([]*domain.RatePlanRate, error)
+	SearchAvailability(
+		ctx context.Context,
+		HotelUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]dto.RoomTypeAvailability, error)
+	CreatePricingRule(
+		ctx context.Context,
+		rule *domain.PricingRule,
+	) (*domain.PricingRule, error)
+	GetPricingRules(
+		ctx context.Context,
+		HotelUUID string,
+	) ([]domain.PricingRule, error)
+	PreviewPricing(
+		ctx context.Context,
+		HotelUUID string,
+		RoomTypeUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+		rules []domain.PricingRule,
+	) ([]dto.PricedNight, error)
+	CreatePromotion(
+		ctx context.Context,
+		promotion *domain.Promotion,
+	) (*domain.Promotion, error)
 	GetReservations(
 		ctx context.Context,
 	) ([]domain.Reservation, error)
@@ -52,6 +101,85 @@ type UsecasesContract interface {
 		GuestUUID string,
 		RoomTypeUUID string,
 	) (*domain.Reservation, error)
+	LookupReservation(
+		ctx context.Context,
+		ConfirmationCode string,
+		LastName string,
+	) (*domain.Reservation, error)
+	ModifyReservation(
+		ctx context.Context,
+		ReservationUUID string,
+		payload *dto.ReservationModificationPayload,
+	) (*domain.Reservation, error)
+	CapturePayment(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Payment, error)
+	HoldReservation(
+		ctx context.Context,
+		reservation *domain.Reservation,
+	) (*domain.Reservation, error)
+	ConfirmReservation(
+		ctx context.Context,
+		ReservationUUID string,
+		PaymentMethod string,
+	) (*domain.Reservation, error)
+	CheckIn(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Reservation, error)
+	CheckOut(
+		ctx context.Context,
+		ReservationUUID string,
+	) (*domain.Reservation, error)
+	CreateWebhookSubscription(
+		ctx context.Context,
+		subscription *domain.WebhookSubscription,
+	) (*domain.WebhookSubscription, error)
+	GetWebhookDeliveries(
+		ctx context.Context,
+		SubscriptionUUID string,
+	) ([]domain.WebhookDelivery, error)
+	JoinWaitlist(
+		ctx context.Context,
+		entry *domain.WaitlistEntry,
+	) (*domain.WaitlistEntry, error)
+	GetLoyaltyBalance(
+		ctx context.Context,
+		GuestUUID string,
+	) (*dto.LoyaltyBalance, error)
+	GetLoyaltyHistory(
+		ctx context.Context,
+		GuestUUID string,
+	) ([]domain.LoyaltyEntry, error)
+	CreateBooking(
+		ctx context.Context,
+		booking *domain.Booking,
+	) (*domain.Booking, error)
+	GetBooking(
+		ctx context.Context,
+		BookingUUID string,
+	) (*domain.Booking, error)
+	CancelBookingLine(
+		ctx context.Context,
+		BookingUUID string,
+		LineUUID string,
+	) (*domain.Booking, error)
+	ConfirmBookingBlock(
+		ctx context.Context,
+		BookingUUID string,
+		PaymentMethod string,
+	) (*domain.Booking, error)
+	SetOverbookingLimits(
+		ctx context.Context,
+		payload *dto.OverbookingPayload,
+	) ([]domain.RoomTypeInventory, error)
+	GetWalkOutReport(
+		ctx context.Context,
+		HotelUUID string,
+		StartDate time.Time,
+		EndDate time.Time,
+	) ([]domain.OversoldNight, error)
 }
 
 // Usecase represents the Application's business logic
@@ -59,19 +187,40 @@ type Usecase struct {
 	Create repository.CreateRepository
 	Get    repository.GetRepository
 	Update repository.UpdateRepository
+	// Payments authorizes, captures and refunds the money paid for reservations
+	Payments domain.PaymentGateway
+	// Metrics records how reservations turn out, for monitoring
+	Metrics ReservationMetrics
+}
+
+// ReservationMetrics is the contract the monitoring of reservations must satisfy
+type ReservationMetrics interface {
+	// ReservationCreated counts a reservation whose payment was authorized
+	ReservationCreated(HotelUUID string)
+	// ReservationCancelled counts a cancelled reservation
+	ReservationCancelled(HotelUUID string)
+	// ReservationSoldOut counts a reservation or hold rejected because its room type was sold out
+	ReservationSoldOut(HotelUUID string)
 }
 
-// Checkpreconditions asserts all pre-conditions are met
-func (u *Usecase) Checkpreconditions() {
+// Checkpreconditions returns an error unless all pre-conditions are met
+func (u *Usecase) Checkpreconditions() error {
 	if u.Create == nil {
-		log.Panicf("hotel usecase has not initialized a create repository")
+		return errors.New("hotel usecase has not initialized a create repository")
 	}
 	if u.Get == nil {
-		log.Panicf("hotel usecase has not initialized a get repository")
+		return errors.New("hotel usecase has not initialized a get repository")
 	}
 	if u.Update == nil {
-		log.Panicf("hotel  usecase has not initialized a delete repository")
+		return errors.New("hotel usecase has not initialized an update repository")
+	}
+	if u.Payments == nil {
+		return errors.New("hotel usecase has not initialized a payment gateway")
+	}
+	if u.Metrics == nil {
+		return errors.New("hotel usecase has not initialized reservation metrics")
 	}
+	return nil
 }
 
 // NewUseCase initializes  a new hotel usecase
@@ -79,37 +228,86 @@ func NewUseCase(
 	create repository.CreateRepository,
 	get repository.GetRepository,
 	update repository.UpdateRepository,
-) *Usecase {
+	payments domain.PaymentGateway,
+	metrics ReservationMetrics,
+) (*Usecase, error) {
 	uc := &Usecase{
-		Create: create,
-		Get:    get,
-		Update: update,
+		Create:   create,
+		Get:      get,
+		Update:   update,
+		Payments: payments,
+		Metrics:  metrics,
 	}
-	uc.Checkpreconditions()
-	return uc
+	if err := uc.Checkpreconditions(); err != nil {
+		return nil, err
+	}
+	return uc, nil
 }
 
 // CreateGuest creates a new guest
 func (u *Usecase) CreateGuest(
 	ctx context.Context,
 	guest *domain.Guest,
-) (*domain.Guest, error) {
+) (_ *domain.Guest, err error) {
+	ctx, endSpan := startSpan(ctx, "CreateGuest")
+	defer endSpan(&err)
 	return u.Create.CreateGuest(ctx, guest)
 }
 
-// CreateReservation creates a new reservation
+// CreateReservation quotes, discounts and creates a new reservation, claiming the room type's inventory
+// for every night. The reservation stays PENDING until its payment is authorized, or for
+// domain.PendingPaymentTimeout when it never is
 func (u *Usecase) CreateReservation(
 	ctx context.Context,
 	reservation *domain.Reservation,
-) (*domain.Reservation, error) {
-	return u.Create.CreateReservation(ctx, reservation)
+) (_ *domain.Reservation, err error) {
+	ctx, endSpan := startSpan(ctx, "CreateReservation")
+	defer endSpan(&err)
+	now := time.Now()
+	if err := u.priceReservation(ctx, reservation, now); err != nil {
+		return nil, err
+	}
+	expiresAt := now.Add(domain.PendingPaymentTimeout)
+	reservation.Status = string(domain.PENDING)
+	reservation.ExpiresAt = &expiresAt
+	created, err := u.Create.CreateReservation(ctx, reservation)
+	if err != nil {
+		u.countSoldOut(reservation, err)
+		return nil, err
+	}
+	return u.authorizePayment(ctx, created)
+}
+
+// priceReservation quotes a reservation and applies its promo code and loyalty points
+func (u *Usecase) priceReservation(
+	ctx context.Context,
+	reservation *domain.Reservation,
+	bookedOn time.Time,
+) error {
+	if err := domain.CheckStayLength(reservation.StartDate, reservation.EndDate); err != nil {
+		return err
+	}
+	if len(domain.StayNights(reservation.StartDate, reservation.EndDate)) == 0 {
+		return errors.New("a reservation must be at least one night long")
+	}
+	price, err := u.quoteReservation(ctx, reservation, bookedOn)
+	if err != nil {
+		return err
+	}
+	reservation.TotalPrice = price
+	if err := u.applyPromotion(ctx, reservation, bookedOn); err != nil {
+		return err
+	}
+	return u.applyLoyaltyPoints(ctx, reservation)
 }
 
 // CreateHotel creates a new Hotel
 func (u *Usecase) CreateHotel(
 	ctx context.Context,
 	hotel *domain.Hotel,
-) (*domain.Hotel, error) {
+) (_ *domain.Hotel, err error) {
+	ctx, endSpan := startSpan(ctx, "CreateHotel")
+	defer endSpan(&err)
 	return u.Create.CreateHotel(ctx, hotel)
 }
 
@@ -117,7 +315,12 @@ func (u *Usecase) CreateHotel(
 func (u *Usecase) CreateRoomType(
 	ctx context.Context,
 	roomType *domain.RoomType,
-) (*domain.RoomType, error) {
+) (_ *domain.RoomType, err error) {
+	ctx, endSpan := startSpan(ctx, "CreateRoomType")
+	defer endSpan(&err)
+	if err := domain.ValidateOverbooking(roomType.OverbookingType, roomType.OverbookingValue); err != nil {
+		return nil, err
+	}
 	return u.Create.CreateRoomType(ctx, roomType)
 }
 
@@ -125,7 +328,9 @@ func (u *Usecase) CreateRoomType(
 func (u *Usecase) CreateRoom(
 	ctx context.Context,
 	room *domain.Room,
-) (*domain.Room, error) {
+) (_ *domain.Room, err error) {
+	ctx, endSpan := startSpan(ctx, "CreateRoom")
+	defer endSpan(&err)
 	return u.Create.CreateRoom(ctx, room)
 }
 
@@ -133,14 +338,18 @@ func (u *Usecase) CreateRoom(
 func (u *Usecase) CreateRate(
 	ctx context.Context,
 	rate *domain.Rate,
-) (*domain.Rate, error) {
+) (_ *domain.Rate, err error) {
+	ctx, endSpan := startSpan(ctx, "CreateRate")
+	defer endSpan(&err)
 	return u.Create.CreateRate(ctx, rate)
 }
 
 // GetReservations gets all reservations
 func (u *Usecase) GetReservations(
 	ctx context.Context,
-) ([]domain.Reservation, error) {
+) (_ []domain.Reservation, err error) {
+	ctx, endSpan := startSpan(ctx, "GetReservations")
+	defer endSpan(&err)
 	return u.Get.GetReservations(ctx)
 }
 
@@ -149,29 +358,77 @@ func (u *Usecase) GetRoom(
 	ctx context.Context,
 	RoomTypeUUID string,
 	HotelUUID string,
-) (*domain.Room, error) {
+) (_ *domain.Room, err error) {
+	ctx, endSpan := startSpan(ctx, "GetRoom")
+	defer endSpan(&err)
 	return u.Get.GetRoom(ctx, RoomTypeUUID, HotelUUID)
 }
 
 // GetGuests gets all Guests who have had a reservation with the Hotel
 func (u *Usecase) GetGuests(
 	ctx context.Context,
-) ([]domain.Guest, error) {
+) (_ []domain.Guest, err error) {
+	ctx, endSpan := startSpan(ctx, "GetGuests")
+	defer endSpan(&err)
 	return u.Get.GetGuests(ctx)
 }
 
 // GetRoomTypes gets all RoomTypes
 func (u *Usecase) GetRoomTypes(
 	ctx context.Context,
-) ([]domain.RoomType, error) {
+) (_ []domain.RoomType, err error) {
+	ctx, endSpan := startSpan(ctx, "GetRoomTypes")
+	defer endSpan(&err)
 	return u.Get.GetRoomTypes(ctx)
 }
 
-// CancelReservation cancels a reservation made earlier
+// CancelReservation cancels a reservation made earlier, refunding its payment according to the
+// rate plan's cancellation terms
 func (u *Usecase) CancelReservation(
 	ctx context.Context,
 	GuestUUID string,
 	RoomTypeUUID string,
-) (*domain.Reservation, error) {
-	return u.Update.CancelReservation(ctx, GuestUUID, RoomTypeUUID)
+) (_ *domain.Reservation, err error) {
+	ctx, endSpan := startSpan(ctx, "CancelReservation")
+	defer endSpan(&err)
+	reservation, err := u.Update.CancelReservation(ctx, GuestUUID, RoomTypeUUID)
+	if err != nil {
+		return nil, err
+	}
+	u.Metrics.ReservationCancelled(reservation.HotelUUID)
+	// the refund owed was recorded with the cancellation, the payment settler retries it when it fails here
+	charge, getErr := u.Get.GetPayment(ctx, reservation.UUID)
+	if getErr != nil {
+		logging.FromContext(ctx).Errorf("can't get the payment of cancelled reservation %s: %v", reservation.UUID, getErr)
+		return reservation, nil
+	}
+	if charge != nil && charge.SettleAt != nil {
+		if settleErr := u.settlePayment(ctx, charge); settleErr != nil {
+			logging.FromContext(ctx).Errorf("can't settle the payment of cancelled reservation %s: %v", reservation.UUID, settleErr)
+		}
+	}
+	return reservation, nil
+}
+
+// countSoldOut counts a reservation rejected because its room type was sold out
+func (u *Usecase) countSoldOut(reservation *domain.Reservation, err error) {
+	if errors.Is(err, domain.ErrSoldOut) {
+		u.Metrics.ReservationSoldOut(reservation.HotelUUID)
+	}
+}
+
+// getHotelRoomType fetches a room type, asserting it belongs to the given hotel
+func (u *Usecase) getHotelRoomType(
+	ctx context.Context,
+	HotelUUID string,
+	RoomTypeUUID string,
+) (*domain.RoomType, error) {
+	roomType, err := u.Get.GetRoomType(ctx, RoomTypeUUID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get room type: %w", err)
+	}
+	if roomType == nil || roomType.HotelUUID != HotelUUID {
+		return nil, fmt.Errorf("room type %s doesn't exist in hotel %s", RoomTypeUUID, HotelUUID)
+	}
+	return roomType, nil
 }
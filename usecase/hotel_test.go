@@ -2,14 +2,34 @@ package usecase_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/MelvinKim/Hotel-Reservation-System/application/common/dto"
+	"github.com/MelvinKim/Hotel-Reservation-System/application/config"
 	"github.com/MelvinKim/Hotel-Reservation-System/domain"
 	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/database"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/events"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/metrics"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/notification"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/payment"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/webhook"
+	"github.com/MelvinKim/Hotel-Reservation-System/repository"
 	"github.com/MelvinKim/Hotel-Reservation-System/repository/mock"
 	hotel "github.com/MelvinKim/Hotel-Reservation-System/usecase"
 	"github.com/brianvoe/gofakeit/v6"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -18,23 +38,67 @@ var (
 	mockUpdate = mock.NewMockUpdateRepository()
 )
 
-// newTestUseCase initializes a new test Usecase
-func newTestUseCase() *hotel.Usecase {
-	create := database.NewPostgresDB()
-	get := database.NewPostgresDB()
-	update := database.NewPostgresDB()
-	u := hotel.NewUseCase(create, get, update)
-	return u
+// testDatabase is the database the tests run against, configured like the service
+var testDatabase config.DatabaseConfig
+
+// errTestDatabase is why the test database couldn't be reached, the tests that need it are skipped
+var errTestDatabase error
+
+// TestMain brings the test database's schema up to date before the tests run. Tests that don't need
+// the database still run when it can't be reached
+func TestMain(m *testing.M) {
+	if cfg, err := config.Load(); err == nil {
+		testDatabase = cfg.Database
+	}
+	// the tests that need the database are skipped when it can't be reached, don't wait for it
+	testDatabase.ConnectRetries = 0
+	db, err := database.Connect(context.Background(), testDatabase)
+	if err != nil {
+		errTestDatabase = err
+	} else if _, err := database.MigrateUp(context.Background(), db); err != nil {
+		fmt.Fprintf(os.Stderr, "can't migrate the test database: %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestUseCase initializes a new test Usecase on the test database, skipping the test when it can't be reached
+func newTestUseCase(t *testing.T) *hotel.Usecase {
+	t.Helper()
+	if errTestDatabase != nil {
+		t.Skipf("can't connect to the test database: %v", errTestDatabase)
+	}
+	db, err := database.NewPostgresDB(context.Background(), testDatabase)
+	if err != nil {
+		t.Fatalf("can't connect to the test database: %v", err)
+	}
+	return newUseCase(t, db, db, db, payment.NewFakeGateway())
+}
+
+// newMockTestUseCase initializes a new test Usecase on the package's mock repositories
+func newMockTestUseCase(t *testing.T) *hotel.Usecase {
+	t.Helper()
+	return newUseCase(t, mockCreate, mockGet, mockUpdate, payment.NewFakeGateway())
 }
 
-// newMockTestUseCase
-func newMockTestUseCase() *hotel.Usecase {
-	mockUsecase := hotel.NewUseCase(mockCreate, mockGet, mockUpdate)
-	return mockUsecase
+// newUseCase initializes a Usecase on the given repositories and gateway
+func newUseCase(
+	t *testing.T,
+	create repository.CreateRepository,
+	get repository.GetRepository,
+	update repository.UpdateRepository,
+	payments domain.PaymentGateway,
+) *hotel.Usecase {
+	t.Helper()
+	u, err := hotel.NewUseCase(create, get, update, payments, metrics.Reservations{})
+	if err != nil {
+		t.Fatalf("can't instantiate the usecase: %v", err)
+	}
+	return u
 }
 
 func TestUsecase_CreateGuest(t *testing.T) {
-	u := newTestUseCase()
+	u := newTestUseCase(t)
 	ctx := context.Background()
 	guest := &domain.Guest{
 		FirstName: gofakeit.FirstName(),
@@ -84,7 +148,7 @@ func TestUsecase_CreateGuest(t *testing.T) {
 }
 
 func TestUsecase_CreateReservation(t *testing.T) {
-	u := newTestUseCase()
+	u := newTestUseCase(t)
 	ctx := context.Background()
 	guest := &domain.Guest{
 		FirstName: gofakeit.FirstName(),
@@ -164,7 +228,7 @@ func TestUsecase_CreateReservation(t *testing.T) {
 }
 
 func TestUsecase_CancelReservation(t *testing.T) {
-	u := newTestUseCase()
+	u := newTestUseCase(t)
 	ctx := context.Background()
 	guest := &domain.Guest{
 		FirstName: gofakeit.FirstName(),
@@ -248,3 +312,2010 @@ func TestUsecase_CancelReservation(t *testing.T) {
 		})
 	}
 }
+
+func TestUsecase_BulkUpsertRates(t *testing.T) {
+	ctx := context.Background()
+	hotelUUID := gofakeit.UUID()
+	roomTypeUUID := gofakeit.UUID()
+	get := mock.NewMockGetRepository()
+	get.MockGetRoomType = func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+		return &domain.RoomType{
+			AbstractBase: domain.AbstractBase{UUID: RoomTypeUUID},
+			HotelUUID:    hotelUUID,
+			Inventory:    40,
+		}, nil
+	}
+	u := newUseCase(t, mock.NewMockCreateRepository(), get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+
+	type args struct {
+		ctx     context.Context
+		payload *dto.BulkRatePayload
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantTotal int64
+		wantErr   bool
+	}{
+		{
+			name: "Happy case: weekend rates with a weekday default",
+			args: args{
+				ctx: ctx,
+				payload: &dto.BulkRatePayload{
+					HotelUUID:    hotelUUID,
+					RoomTypeUUID: roomTypeUUID,
+					Periods: []dto.RatePeriodPayload{
+						{
+							StartDate:    "2023-06-01",
+							EndDate:      "2023-06-30",
+							WeekdayRates: map[string]int{"saturday": 150, "Sunday": 140},
+							DefaultRate:  100,
+						},
+					},
+				},
+			},
+			wantTotal: 30,
+			wantErr:   false,
+		},
+		{
+			name: "Happy case: weekdays without a rate are skipped",
+			args: args{
+				ctx: ctx,
+				payload: &dto.BulkRatePayload{
+					HotelUUID:    hotelUUID,
+					RoomTypeUUID: roomTypeUUID,
+					Periods: []dto.RatePeriodPayload{
+						{
+							StartDate:    "2023-06-01",
+							EndDate:      "2023-06-30",
+							WeekdayRates: map[string]int{"saturday": 150},
+						},
+					},
+				},
+			},
+			wantTotal: 4,
+			wantErr:   false,
+		},
+		{
+			name: "Sad case: overlapping periods",
+			args: args{
+				ctx: ctx,
+				payload: &dto.BulkRatePayload{
+					HotelUUID:    hotelUUID,
+					RoomTypeUUID: roomTypeUUID,
+					Periods: []dto.RatePeriodPayload{
+						{StartDate: "2023-06-01", EndDate: "2023-06-15", DefaultRate: 100},
+						{StartDate: "2023-06-15", EndDate: "2023-06-30", DefaultRate: 120},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Sad case: period longer than an upload may set",
+			args: args{
+				ctx: ctx,
+				payload: &dto.BulkRatePayload{
+					HotelUUID:    hotelUUID,
+					RoomTypeUUID: roomTypeUUID,
+					Periods: []dto.RatePeriodPayload{
+						{StartDate: "0001-01-01", EndDate: "9999-12-31", DefaultRate: 100},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Sad case: unknown weekday",
+			args: args{
+				ctx: ctx,
+				payload: &dto.BulkRatePayload{
+					HotelUUID:    hotelUUID,
+					RoomTypeUUID: roomTypeUUID,
+					Periods: []dto.RatePeriodPayload{
+						{StartDate: "2023-06-01", EndDate: "2023-06-15", WeekdayRates: map[string]int{"funday": 100}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Sad case: room type belongs to another hotel",
+			args: args{
+				ctx: ctx,
+				payload: &dto.BulkRatePayload{
+					HotelUUID:    gofakeit.UUID(),
+					RoomTypeUUID: roomTypeUUID,
+					Periods: []dto.RatePeriodPayload{
+						{StartDate: "2023-06-01", EndDate: "2023-06-15", DefaultRate: 100},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := u.BulkUpsertRates(tt.args.ctx, tt.args.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Usecase.BulkUpsertRates() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && summary.Total != tt.wantTotal {
+				t.Fatalf("expected %v rates to be set, but got %v", tt.wantTotal, summary.Total)
+			}
+		})
+	}
+}
+
+func TestUsecase_UploadRateCalendar(t *testing.T) {
+	ctx := context.Background()
+	hotelUUID := gofakeit.UUID()
+	get := mock.NewMockGetRepository()
+	get.MockGetRoomType = func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+		return &domain.RoomType{HotelUUID: hotelUUID}, nil
+	}
+	u := newUseCase(t, mock.NewMockCreateRepository(), get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+
+	tests := []struct {
+		name      string
+		document  string
+		wantTotal int64
+		wantErr   bool
+	}{
+		{
+			name:      "Happy case",
+			document:  "date,rate\n2023-06-01,100\n2023-06-02,120\n",
+			wantTotal: 2,
+			wantErr:   false,
+		},
+		{
+			name:     "Sad case: duplicate date",
+			document: "date,rate\n2023-06-01,100\n2023-06-01,120\n",
+			wantErr:  true,
+		},
+		{
+			name:     "Sad case: missing header",
+			document: "2023-06-01,100\n",
+			wantErr:  true,
+		},
+		{
+			name:     "Sad case: invalid rate",
+			document: "date,rate\n2023-06-01,free\n",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := u.UploadRateCalendar(ctx, hotelUUID, gofakeit.UUID(), strings.NewReader(tt.document))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Usecase.UploadRateCalendar() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && summary.Total != tt.wantTotal {
+				t.Fatalf("expected %v rates to be set, but got %v", tt.wantTotal, summary.Total)
+			}
+		})
+	}
+}
+
+func TestUsecase_SearchAvailability(t *testing.T) {
+	ctx := context.Background()
+	hotelUUID := gofakeit.UUID()
+	roomType := domain.RoomType{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:    hotelUUID,
+		Inventory:    10,
+	}
+	ratePlan := domain.RatePlan{
+		AbstractBase:    domain.AbstractBase{UUID: gofakeit.UUID(), Active: true},
+		HotelUUID:       hotelUUID,
+		RoomTypeUUID:    roomType.UUID,
+		Name:            "Weekend saver",
+		MinLengthOfStay: 2,
+	}
+	checkIn := domain.TruncateToDate(time.Now().AddDate(0, 0, 30))
+	get := mock.NewMockGetRepository()
+	get.MockGetHotelRoomTypes = func(ctx context.Context, HotelUUID string) ([]domain.RoomType, error) {
+		return []domain.RoomType{roomType}, nil
+	}
+	get.MockGetRoomTypeInventories = func(ctx context.Context, HotelUUID string, StartDate, EndDate time.Time) ([]domain.RoomTypeInventory, error) {
+		return []domain.RoomTypeInventory{
+			{RoomTypeUUID: roomType.UUID, Date: checkIn, TotalInventory: 10, TotalReserved: 7},
+		}, nil
+	}
+	get.MockGetRatePlans = func(ctx context.Context, HotelUUID string) ([]domain.RatePlan, error) {
+		return []domain.RatePlan{ratePlan}, nil
+	}
+	get.MockGetRatePlanRates = func(ctx context.Context, RatePlanUUIDs []string, StartDate, EndDate time.Time) ([]domain.RatePlanRate, error) {
+		rates := []domain.RatePlanRate{}
+		for night := StartDate; !night.After(EndDate); night = night.AddDate(0, 0, 1) {
+			rates = append(rates, domain.RatePlanRate{RatePlanUUID: ratePlan.UUID, Date: night, Rate: 80})
+		}
+		return rates, nil
+	}
+	u := newUseCase(t, mock.NewMockCreateRepository(), get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+
+	tests := []struct {
+		name          string
+		checkOut      time.Time
+		wantAvailable int64
+		wantSellable  bool
+		wantPrice     int
+		wantErr       error
+	}{
+		{
+			name:          "Happy case: stay satisfies the plan",
+			checkOut:      checkIn.AddDate(0, 0, 2),
+			wantAvailable: 3,
+			wantSellable:  true,
+			wantPrice:     160,
+		},
+		{
+			name:          "Sad case: stay is shorter than the minimum stay",
+			checkOut:      checkIn.AddDate(0, 0, 1),
+			wantAvailable: 3,
+			wantSellable:  false,
+			wantPrice:     80,
+		},
+		{
+			name:     "Sad case: stay is longer than the longest stay",
+			checkOut: checkIn.AddDate(0, 0, domain.MaxStayNights+1),
+			wantErr:  domain.ErrStayTooLong,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			availability, err := u.SearchAvailability(ctx, hotelUUID, checkIn, tt.checkOut)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Usecase.SearchAvailability() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Usecase.SearchAvailability() unexpected error = %v", err)
+			}
+			if len(availability) != 1 || len(availability[0].Offers) != 1 {
+				t.Fatalf("expected one room type with one offer but got %v", availability)
+			}
+			if availability[0].Available != tt.wantAvailable {
+				t.Errorf("expected %v rooms to be available but got %v", tt.wantAvailable, availability[0].Available)
+			}
+			offer := availability[0].Offers[0]
+			if offer.Sellable != tt.wantSellable {
+				t.Errorf("expected sellable to be %v, restrictions: %v", tt.wantSellable, offer.Restrictions)
+			}
+			if offer.TotalPrice != tt.wantPrice {
+				t.Errorf("expected a total price of %v but got %v", tt.wantPrice, offer.TotalPrice)
+			}
+		})
+	}
+}
+
+func TestUsecase_SearchAvailability_Overbooking(t *testing.T) {
+	ctx := context.Background()
+	hotelUUID := gofakeit.UUID()
+	roomType := domain.RoomType{
+		AbstractBase:     domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:        hotelUUID,
+		Inventory:        10,
+		OverbookingType:  domain.OVERBOOKING_PERCENTAGE,
+		OverbookingValue: 10,
+	}
+	checkIn := domain.TruncateToDate(time.Now().AddDate(0, 0, 30))
+
+	tests := []struct {
+		name          string
+		inventories   []domain.RoomTypeInventory
+		wantAvailable int64
+	}{
+		{
+			name:          "Happy case: unbooked nights use the room type's allowance",
+			wantAvailable: 11,
+		},
+		{
+			name: "Happy case: a fully booked night can still be overbooked",
+			inventories: []domain.RoomTypeInventory{
+				{RoomTypeUUID: roomType.UUID, Date: checkIn, TotalInventory: 10, TotalReserved: 10, OverbookingLimit: 2},
+			},
+			wantAvailable: 2,
+		},
+		{
+			name: "Sad case: the overbooking limit has been reached",
+			inventories: []domain.RoomTypeInventory{
+				{RoomTypeUUID: roomType.UUID, Date: checkIn, TotalInventory: 10, TotalReserved: 12, OverbookingLimit: 2},
+			},
+			wantAvailable: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			get := mock.NewMockGetRepository()
+			get.MockGetHotelRoomTypes = func(ctx context.Context, HotelUUID string) ([]domain.RoomType, error) {
+				return []domain.RoomType{roomType}, nil
+			}
+			get.MockGetRoomTypeInventories = func(ctx context.Context, HotelUUID string, StartDate, EndDate time.Time) ([]domain.RoomTypeInventory, error) {
+				return tt.inventories, nil
+			}
+			get.MockGetRatePlans = func(ctx context.Context, HotelUUID string) ([]domain.RatePlan, error) {
+				return nil, nil
+			}
+			u := newUseCase(t, mock.NewMockCreateRepository(), get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+
+			availability, err := u.SearchAvailability(ctx, hotelUUID, checkIn, checkIn.AddDate(0, 0, 1))
+			if err != nil {
+				t.Fatalf("Usecase.SearchAvailability() unexpected error = %v", err)
+			}
+			if len(availability) != 1 {
+				t.Fatalf("expected one room type but got %v", availability)
+			}
+			if availability[0].Available != tt.wantAvailable {
+				t.Errorf("expected %v rooms to be available but got %v", tt.wantAvailable, availability[0].Available)
+			}
+		})
+	}
+}
+
+func TestUsecase_SetOverbookingLimits(t *testing.T) {
+	ctx := context.Background()
+	hotelUUID := gofakeit.UUID()
+	roomType := domain.RoomType{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:    hotelUUID,
+		Inventory:    10,
+	}
+	get := mock.NewMockGetRepository()
+	get.MockGetRoomType = func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+		return &roomType, nil
+	}
+	u := newUseCase(t, mock.NewMockCreateRepository(), get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+
+	tests := []struct {
+		name       string
+		payload    dto.OverbookingPayload
+		wantNights int
+		wantErr    bool
+	}{
+		{
+			name: "Happy case: both dates are included",
+			payload: dto.OverbookingPayload{
+				HotelUUID: hotelUUID, RoomTypeUUID: roomType.UUID,
+				StartDate: "2023-12-30", EndDate: "2024-01-01", Type: "ABSOLUTE", Value: 2,
+			},
+			wantNights: 3,
+		},
+		{
+			name: "Sad case: unknown overbooking type",
+			payload: dto.OverbookingPayload{
+				HotelUUID: hotelUUID, RoomTypeUUID: roomType.UUID,
+				StartDate: "2023-12-30", EndDate: "2024-01-01", Type: "ROOMS", Value: 2,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Sad case: room type of another hotel",
+			payload: dto.OverbookingPayload{
+				HotelUUID: gofakeit.UUID(), RoomTypeUUID: roomType.UUID,
+				StartDate: "2023-12-30", EndDate: "2024-01-01", Type: "PERCENTAGE", Value: 5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Sad case: range longer than a calendar",
+			payload: dto.OverbookingPayload{
+				HotelUUID: hotelUUID, RoomTypeUUID: roomType.UUID,
+				StartDate: "0001-01-01", EndDate: "9999-12-31", Type: "ABSOLUTE", Value: 2,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inventories, err := u.SetOverbookingLimits(ctx, &tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Usecase.SetOverbookingLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(inventories) != tt.wantNights {
+				t.Errorf("expected %v nights to be set but got %v", tt.wantNights, len(inventories))
+			}
+		})
+	}
+}
+
+func TestUsecase_PreviewPricing(t *testing.T) {
+	ctx := context.Background()
+	roomType := domain.RoomType{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:    gofakeit.UUID(),
+		Inventory:    10,
+	}
+	start := domain.TruncateToDate(time.Now().AddDate(0, 0, 30))
+	get := mock.NewMockGetRepository()
+	get.MockGetRoomType = func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+		return &roomType, nil
+	}
+	get.MockGetRatesInRange = func(ctx context.Context, RoomTypeUUID string, StartDate, EndDate time.Time) ([]domain.Rate, error) {
+		return []domain.Rate{
+			{RoomTypeUUID: roomType.UUID, Date: start, Rate: 100},
+			{RoomTypeUUID: roomType.UUID, Date: start.AddDate(0, 0, 1), Rate: 100},
+		}, nil
+	}
+	get.MockGetRoomTypeInventories = func(ctx context.Context, HotelUUID string, StartDate, EndDate time.Time) ([]domain.RoomTypeInventory, error) {
+		return []domain.RoomTypeInventory{
+			{RoomTypeUUID: roomType.UUID, Date: start, TotalInventory: 10, TotalReserved: 9},
+		}, nil
+	}
+	u := newUseCase(t, mock.NewMockCreateRepository(), get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+	rules := []domain.PricingRule{
+		{Name: "Busy night", Type: domain.OCCUPANCY, MinOccupancy: 80, MaxOccupancy: 100, Multiplier: 1.5},
+	}
+
+	calendar, err := u.PreviewPricing(ctx, roomType.HotelUUID, roomType.UUID, start, start.AddDate(0, 0, 2), rules)
+	if err != nil {
+		t.Fatalf("Usecase.PreviewPricing() unexpected error = %v", err)
+	}
+	if len(calendar) != 3 {
+		t.Fatalf("expected 3 nights to be previewed but got %v", len(calendar))
+	}
+	if calendar[0].Price != 150 || calendar[0].Occupancy != 90 {
+		t.Errorf("expected the busy night to cost 150 at 90%% occupancy but got %v", calendar[0])
+	}
+	if calendar[1].Price != 100 {
+		t.Errorf("expected the quiet night to cost the base rate but got %v", calendar[1])
+	}
+	if calendar[2].HasRate {
+		t.Errorf("expected the last night not to have a rate but got %v", calendar[2])
+	}
+
+	farFuture := time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+	if _, err := u.PreviewPricing(ctx, roomType.HotelUUID, roomType.UUID, start, farFuture, rules); err == nil {
+		t.Errorf("expected a preview of thousands of years to be rejected")
+	}
+}
+
+func TestUsecase_ReservationPayment(t *testing.T) {
+	ctx := context.Background()
+	roomType := domain.RoomType{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:    gofakeit.UUID(),
+		Inventory:    10,
+	}
+	checkIn := domain.TruncateToDate(time.Now().AddDate(0, 0, 30))
+	create := mock.NewMockCreateRepository()
+	create.MockCreateReservation = func(ctx context.Context, reservation *domain.Reservation) (*domain.Reservation, error) {
+		// a reservation whose payment never completes is released by the reaper
+		if reservation.ExpiresAt == nil || !reservation.ExpiresAt.After(time.Now()) {
+			t.Errorf("expected the pending reservation to expire but it expires at %v", reservation.ExpiresAt)
+		}
+		reservation.UUID = gofakeit.UUID()
+		return reservation, nil
+	}
+
+	tests := []struct {
+		name          string
+		paymentMethod string
+		rate          int
+		wantErr       error
+		wantStatus    domain.PaymentStatus
+		wantAmount    int
+	}{
+		{
+			name:          "Happy case: payment is authorized",
+			paymentMethod: "tok_visa",
+			rate:          100,
+			wantStatus:    domain.AUTHORIZED,
+			wantAmount:    200,
+		},
+		{
+			name:          "Happy case: stay without rates is booked unpriced",
+			paymentMethod: "tok_visa",
+			wantStatus:    domain.CAPTURED,
+		},
+		{
+			name:          "Sad case: payment is declined",
+			paymentMethod: payment.DeclinedPaymentMethod,
+			rate:          100,
+			wantErr:       domain.ErrPaymentDeclined,
+			wantStatus:    domain.FAILED,
+			wantAmount:    200,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			get := mock.NewMockGetRepository()
+			get.MockGetRoomType = func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+				return &roomType, nil
+			}
+			get.MockGetRatesInRange = func(ctx context.Context, RoomTypeUUID string, StartDate, EndDate time.Time) ([]domain.Rate, error) {
+				rates := []domain.Rate{}
+				for night := StartDate; tt.rate > 0 && !night.After(EndDate); night = night.AddDate(0, 0, 1) {
+					rates = append(rates, domain.Rate{RoomTypeUUID: roomType.UUID, Date: night, Rate: tt.rate})
+				}
+				return rates, nil
+			}
+			var recorded *domain.Payment
+			update := mock.NewMockUpdateRepository()
+			update.MockConfirmReservationPayment = func(ctx context.Context, charge *domain.Payment) (*domain.Reservation, error) {
+				recorded = charge
+				return &domain.Reservation{Status: string(domain.RESERVED)}, nil
+			}
+			update.MockFailReservationPayment = func(ctx context.Context, charge *domain.Payment) (*domain.Reservation, error) {
+				recorded = charge
+				return &domain.Reservation{Status: string(domain.PAYMENT_FAILED)}, nil
+			}
+			u := newUseCase(t, create, get, update, payment.NewFakeGateway())
+
+			_, err := u.CreateReservation(ctx, &domain.Reservation{
+				GuestUUID:     gofakeit.UUID(),
+				HotelUUID:     roomType.HotelUUID,
+				RoomTypeUUID:  roomType.UUID,
+				StartDate:     checkIn,
+				EndDate:       checkIn.AddDate(0, 0, 2),
+				PaymentMethod: tt.paymentMethod,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Usecase.CreateReservation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if recorded == nil || recorded.Status != tt.wantStatus {
+				t.Fatalf("expected a %v payment to be recorded but got %v", tt.wantStatus, recorded)
+			}
+			if recorded.Amount != tt.wantAmount {
+				t.Errorf("expected a payment of %v but got %v", tt.wantAmount, recorded.Amount)
+			}
+		})
+	}
+}
+
+// unavailableGateway fails to settle payments while down, the way a gateway outage would
+type unavailableGateway struct {
+	*payment.FakeGateway
+	down bool
+}
+
+func (g *unavailableGateway) Capture(ctx context.Context, reference string, amount int) error {
+	if g.down {
+		return errors.New("payment gateway unavailable")
+	}
+	return g.FakeGateway.Capture(ctx, reference, amount)
+}
+
+func (g *unavailableGateway) Refund(ctx context.Context, reference string, amount int) error {
+	if g.down {
+		return errors.New("payment gateway unavailable")
+	}
+	return g.FakeGateway.Refund(ctx, reference, amount)
+}
+
+func (g *unavailableGateway) Void(ctx context.Context, reference string) error {
+	if g.down {
+		return errors.New("payment gateway unavailable")
+	}
+	return g.FakeGateway.Void(ctx, reference)
+}
+
+func TestUsecase_CancelReservation_Refund(t *testing.T) {
+	ctx := context.Background()
+	tomorrow := domain.TruncateToDate(time.Now().AddDate(0, 0, 1))
+
+	tests := []struct {
+		name         string
+		startDate    time.Time
+		refundable   bool
+		gatewayDown  bool
+		wantStatus   domain.PaymentStatus
+		wantCaptured int
+		wantRefunded int
+	}{
+		{
+			name:       "Happy case: refundable stay cancelled before arrival is voided",
+			startDate:  tomorrow,
+			refundable: true,
+			wantStatus: domain.VOIDED,
+		},
+		{
+			name:         "Happy case: non refundable stay is captured",
+			startDate:    tomorrow,
+			refundable:   false,
+			wantStatus:   domain.CAPTURED,
+			wantCaptured: 300,
+		},
+		{
+			name:         "Happy case: stay cancelled on arrival is captured",
+			startDate:    domain.TruncateToDate(time.Now()),
+			refundable:   true,
+			wantStatus:   domain.CAPTURED,
+			wantCaptured: 300,
+		},
+		{
+			name:        "Sad case: refund the gateway fails to make is left to the settler",
+			startDate:   tomorrow,
+			refundable:  true,
+			gatewayDown: true,
+			wantStatus:  domain.AUTHORIZED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := &unavailableGateway{FakeGateway: payment.NewFakeGateway(), down: tt.gatewayDown}
+			reservationUUID := gofakeit.UUID()
+			reference, err := gateway.Authorize(ctx, domain.AuthorizeRequest{Amount: 300, IdempotencyKey: reservationUUID})
+			if err != nil {
+				t.Fatalf("can't authorize payment: %v", err)
+			}
+			reservation := &domain.Reservation{
+				AbstractBase: domain.AbstractBase{UUID: reservationUUID},
+				StartDate:    tt.startDate,
+				EndDate:      tt.startDate.AddDate(0, 0, 3),
+				Status:       string(domain.CANCELLED),
+			}
+			// the refund owed is recorded with the cancellation
+			charge := &domain.Payment{
+				ReservationUUID:  &reservationUUID,
+				Amount:           300,
+				Status:           domain.AUTHORIZED,
+				GatewayReference: reference,
+			}
+			refund := domain.RefundAmount(reservation, &domain.RatePlan{Refundable: tt.refundable}, charge, time.Now())
+			charge.OweRefund(refund, time.Now())
+
+			get := mock.NewMockGetRepository()
+			get.MockGetPayment = func(ctx context.Context, ReservationUUID string) (*domain.Payment, error) {
+				return charge, nil
+			}
+			var updated *domain.Payment
+			update := mock.NewMockUpdateRepository()
+			update.MockCancelReservation = func(ctx context.Context, GuestUUID, RoomTypeUUID string) (*domain.Reservation, error) {
+				return reservation, nil
+			}
+			update.MockUpdatePayment = func(ctx context.Context, charge *domain.Payment) (*domain.Payment, error) {
+				updated = charge
+				return charge, nil
+			}
+			u := newUseCase(t, mock.NewMockCreateRepository(), get, update, gateway)
+
+			if _, err := u.CancelReservation(ctx, gofakeit.UUID(), gofakeit.UUID()); err != nil {
+				t.Fatalf("Usecase.CancelReservation() unexpected error = %v", err)
+			}
+			if updated == nil || updated.Status != tt.wantStatus {
+				t.Fatalf("expected the payment to be %v but got %v", tt.wantStatus, updated)
+			}
+			if settled := updated.SettleAt == nil; settled == tt.gatewayDown {
+				t.Fatalf("expected the payment to be settled to be %v but it was due at %v", !tt.gatewayDown, updated.SettleAt)
+			}
+			captured, refunded, _ := gateway.Balance(reference)
+			if captured != tt.wantCaptured || refunded != tt.wantRefunded {
+				t.Errorf("expected %v captured and %v refunded but got %v and %v", tt.wantCaptured, tt.wantRefunded, captured, refunded)
+			}
+		})
+	}
+}
+
+func TestUsecase_SettleDuePayments(t *testing.T) {
+	ctx := context.Background()
+	gateway := &unavailableGateway{FakeGateway: payment.NewFakeGateway()}
+	reference, err := gateway.Authorize(ctx, domain.AuthorizeRequest{Amount: 300, IdempotencyKey: gofakeit.UUID()})
+	if err != nil {
+		t.Fatalf("can't authorize payment: %v", err)
+	}
+	// a partial refund captures the authorization before giving the refund back
+	due := domain.Payment{
+		AbstractBase:     domain.AbstractBase{UUID: gofakeit.UUID()},
+		Amount:           300,
+		Status:           domain.AUTHORIZED,
+		GatewayReference: reference,
+	}
+	due.OweRefund(100, time.Now())
+
+	var saved []domain.Payment
+	update := mock.NewMockUpdateRepository()
+	update.MockClaimDuePayments = func(ctx context.Context, now time.Time, limit int) ([]domain.Payment, error) {
+		return []domain.Payment{due}, nil
+	}
+	update.MockUpdatePayment = func(ctx context.Context, charge *domain.Payment) (*domain.Payment, error) {
+		saved = append(saved, *charge)
+		return charge, nil
+	}
+	u := newUseCase(t, mock.NewMockCreateRepository(), mock.NewMockGetRepository(), update, gateway)
+
+	attempted, err := u.SettleDuePayments(ctx)
+	if err != nil || attempted != 1 {
+		t.Fatalf("Usecase.SettleDuePayments() = %v, %v, want 1 attempted", attempted, err)
+	}
+	if len(saved) != 2 || saved[0].Status != domain.CAPTURED || saved[0].SettleAt == nil {
+		t.Fatalf("expected the capture to be saved before the refund but got %+v", saved)
+	}
+	settled := saved[1]
+	if settled.Status != domain.PARTIALLY_REFUNDED || settled.RefundDue != 0 || settled.SettleAt != nil {
+		t.Errorf("expected the payment to be settled but got %+v", settled)
+	}
+	captured, refunded, _ := gateway.Balance(reference)
+	if captured != 300 || refunded != 100 {
+		t.Errorf("expected 300 captured and 100 refunded but got %v and %v", captured, refunded)
+	}
+}
+
+func TestUsecase_HoldReservation(t *testing.T) {
+	ctx := context.Background()
+	roomType := domain.RoomType{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:    gofakeit.UUID(),
+		Inventory:    10,
+	}
+	checkIn := domain.TruncateToDate(time.Now().AddDate(0, 0, 30))
+	get := mock.NewMockGetRepository()
+	get.MockGetRoomType = func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+		return &roomType, nil
+	}
+	get.MockGetRatesInRange = func(ctx context.Context, RoomTypeUUID string, StartDate, EndDate time.Time) ([]domain.Rate, error) {
+		return []domain.Rate{{RoomTypeUUID: roomType.UUID, Date: checkIn, Rate: 100}}, nil
+	}
+	create := mock.NewMockCreateRepository()
+	create.MockCreateReservation = func(ctx context.Context, reservation *domain.Reservation) (*domain.Reservation, error) {
+		return reservation, nil
+	}
+	u := newUseCase(t, create, get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+
+	held, err := u.HoldReservation(ctx, &domain.Reservation{
+		GuestUUID:    gofakeit.UUID(),
+		HotelUUID:    roomType.HotelUUID,
+		RoomTypeUUID: roomType.UUID,
+		StartDate:    checkIn,
+		EndDate:      checkIn.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		t.Fatalf("Usecase.HoldReservation() unexpected error = %v", err)
+	}
+	if held.Status != string(domain.HELD) || held.ExpiresAt == nil {
+		t.Fatalf("expected a HELD reservation with an expiry but got %v, %v", held.Status, held.ExpiresAt)
+	}
+	if remaining := time.Until(*held.ExpiresAt); remaining <= 0 || remaining > hotel.ReservationHoldDuration {
+		t.Errorf("expected the hold to expire within %v but it expires in %v", hotel.ReservationHoldDuration, remaining)
+	}
+}
+
+// recordingGateway remembers its last authorization, for payments that are never recorded
+type recordingGateway struct {
+	*payment.FakeGateway
+	authorized string
+}
+
+func (g *recordingGateway) Authorize(ctx context.Context, request domain.AuthorizeRequest) (string, error) {
+	reference, err := g.FakeGateway.Authorize(ctx, request)
+	if err == nil {
+		g.authorized = reference
+	}
+	return reference, err
+}
+
+func TestUsecase_ConfirmReservation(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		expiresIn     time.Duration
+		paymentMethod string
+		holdErr       error
+		wantErr       error
+		wantStatus    string
+		wantConfirmed bool
+		wantVoided    bool
+	}{
+		{
+			name:          "Happy case: hold is confirmed and paid",
+			expiresIn:     time.Minute,
+			paymentMethod: "tok_visa",
+			wantStatus:    string(domain.RESERVED),
+			wantConfirmed: true,
+		},
+		{
+			name:          "Sad case: hold has expired",
+			expiresIn:     -time.Minute,
+			paymentMethod: "tok_visa",
+			wantErr:       domain.ErrHoldExpired,
+		},
+		{
+			name:          "Sad case: declined payment keeps the hold",
+			expiresIn:     time.Minute,
+			paymentMethod: payment.DeclinedPaymentMethod,
+			wantErr:       domain.ErrPaymentDeclined,
+		},
+		{
+			name:          "Sad case: hold lapses while its payment is authorized",
+			expiresIn:     time.Minute,
+			paymentMethod: "tok_visa",
+			holdErr:       domain.ErrHoldExpired,
+			wantErr:       domain.ErrHoldExpired,
+			wantVoided:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiresAt := time.Now().Add(tt.expiresIn)
+			held := &domain.Reservation{
+				AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+				TotalPrice:   150,
+				Status:       string(domain.HELD),
+				ExpiresAt:    &expiresAt,
+			}
+			get := mock.NewMockGetRepository()
+			get.MockGetReservation = func(ctx context.Context, ReservationUUID string) (*domain.Reservation, error) {
+				return held, nil
+			}
+			confirmedHold := false
+			update := mock.NewMockUpdateRepository()
+			update.MockConfirmHold = func(ctx context.Context, ReservationUUID string, now time.Time) (*domain.Reservation, error) {
+				if tt.holdErr != nil {
+					return nil, tt.holdErr
+				}
+				confirmedHold = true
+				return &domain.Reservation{AbstractBase: held.AbstractBase, Status: string(domain.PENDING)}, nil
+			}
+			var charge *domain.Payment
+			update.MockConfirmReservationPayment = func(ctx context.Context, payment *domain.Payment) (*domain.Reservation, error) {
+				charge = payment
+				return &domain.Reservation{AbstractBase: held.AbstractBase, Status: string(domain.RESERVED)}, nil
+			}
+			update.MockFailReservationPayment = func(ctx context.Context, payment *domain.Payment) (*domain.Reservation, error) {
+				t.Fatalf("expected the hold to be kept but its payment was failed")
+				return nil, nil
+			}
+			gateway := &recordingGateway{FakeGateway: payment.NewFakeGateway()}
+			u := newUseCase(t, mock.NewMockCreateRepository(), get, update, gateway)
+
+			confirmed, err := u.ConfirmReservation(ctx, held.UUID, tt.paymentMethod)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Usecase.ConfirmReservation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && confirmed.Status != tt.wantStatus {
+				t.Errorf("expected a %v reservation but got %v", tt.wantStatus, confirmed.Status)
+			}
+			if confirmedHold != tt.wantConfirmed {
+				t.Errorf("expected the hold to be confirmed: %v, got %v", tt.wantConfirmed, confirmedHold)
+			}
+			if charge != nil && (charge.Status != domain.AUTHORIZED || *charge.ReservationUUID != held.UUID) {
+				t.Errorf("expected an authorized payment of the hold but got %+v", charge)
+			}
+			if tt.wantVoided {
+				if _, _, voided := gateway.Balance(gateway.authorized); gateway.authorized == "" || !voided {
+					t.Errorf("expected the authorization of the lapsed hold to be voided")
+				}
+			}
+		})
+	}
+}
+
+func TestUsecase_ReleaseExpiredHolds(t *testing.T) {
+	ctx := context.Background()
+	// two full batches followed by a partial one
+	batches := []int{100, 100, 7}
+	calls := 0
+	update := mock.NewMockUpdateRepository()
+	update.MockReleaseExpiredHolds = func(ctx context.Context, now time.Time, limit int) (int, error) {
+		count := batches[calls]
+		calls++
+		return count, nil
+	}
+	u := newUseCase(t, mock.NewMockCreateRepository(), mock.NewMockGetRepository(), update, payment.NewFakeGateway())
+
+	released, err := u.ReleaseExpiredHolds(ctx)
+	if err != nil {
+		t.Fatalf("Usecase.ReleaseExpiredHolds() unexpected error = %v", err)
+	}
+	if released != 207 || calls != 3 {
+		t.Errorf("expected 207 holds released in 3 batches but got %v in %v", released, calls)
+	}
+}
+
+func TestUsecase_RelayEvents(t *testing.T) {
+	ctx := context.Background()
+	var relayedTo []string
+	update := mock.NewMockUpdateRepository()
+	update.MockRelayOutboxEvents = func(ctx context.Context, limit int, consumers []string) (int, error) {
+		relayedTo = consumers
+		return 2, nil
+	}
+	u := newUseCase(t, mock.NewMockCreateRepository(), mock.NewMockGetRepository(), update, payment.NewFakeGateway())
+
+	relayed, err := u.RelayEvents(ctx, []events.Consumer{
+		{Name: "log", Publisher: events.LogPublisher{}},
+		{Name: "memory", Publisher: events.NewMemoryPublisher()},
+	})
+	if err != nil {
+		t.Fatalf("Usecase.RelayEvents() unexpected error = %v", err)
+	}
+	if relayed != 2 || len(relayedTo) != 2 || relayedTo[0] != "log" || relayedTo[1] != "memory" {
+		t.Errorf("expected 2 events to be relayed to log and memory but got %v to %v", relayed, relayedTo)
+	}
+}
+
+func TestUsecase_ConsumeEvents(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		publisher  events.EventPublisher
+		wantStatus domain.DeliveryStatus
+	}{
+		{
+			name:       "Happy case: every event is consumed",
+			publisher:  events.NewMemoryPublisher(),
+			wantStatus: domain.DELIVERED,
+		},
+		{
+			name:       "Sad case: consumer is failing",
+			publisher:  failingPublisher{},
+			wantStatus: domain.DELIVERY_PENDING,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deliveries := []domain.EventDelivery{
+				{EventUUID: "created", Status: domain.DELIVERY_PENDING, Event: &domain.OutboxEvent{Type: domain.RESERVATION_CREATED}},
+				{EventUUID: "cancelled", Status: domain.DELIVERY_PENDING, Event: &domain.OutboxEvent{Type: domain.RESERVATION_CANCELLED}},
+			}
+			var recorded []domain.EventDelivery
+			update := mock.NewMockUpdateRepository()
+			update.MockClaimEventDeliveries = func(ctx context.Context, consumer string, now time.Time, limit int) ([]domain.EventDelivery, error) {
+				if consumer != "test" {
+					t.Errorf("expected the deliveries of test to be claimed but got the ones of %v", consumer)
+				}
+				return deliveries, nil
+			}
+			update.MockRecordEventDelivery = func(ctx context.Context, delivery *domain.EventDelivery) error {
+				recorded = append(recorded, *delivery)
+				return nil
+			}
+			u := newUseCase(t, mock.NewMockCreateRepository(), mock.NewMockGetRepository(), update, payment.NewFakeGateway())
+
+			attempted, err := u.ConsumeEvents(ctx, events.Consumer{Name: "test", Publisher: tt.publisher})
+			if err != nil {
+				t.Fatalf("Usecase.ConsumeEvents() unexpected error = %v", err)
+			}
+			if attempted != 2 || len(recorded) != 2 {
+				t.Fatalf("expected 2 deliveries to be attempted and recorded but got %v and %v", attempted, len(recorded))
+			}
+			for _, delivery := range recorded {
+				if delivery.Status != tt.wantStatus || delivery.Attempts != 1 {
+					t.Errorf("expected delivery %v to be %v after an attempt but got %v after %v", delivery.EventUUID, tt.wantStatus, delivery.Status, delivery.Attempts)
+				}
+			}
+			if memory, ok := tt.publisher.(*events.MemoryPublisher); ok && len(memory.Events()) != 2 {
+				t.Errorf("expected 2 events to be consumed but got %v", len(memory.Events()))
+			}
+		})
+	}
+}
+
+// failingPublisher is an EventPublisher whose downstream system is unavailable
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(ctx context.Context, event *domain.OutboxEvent) error {
+	return errors.New("downstream system is unavailable")
+}
+
+func TestUsecase_EnqueueWebhookDeliveries(t *testing.T) {
+	ctx := context.Background()
+	hotelUUID := gofakeit.UUID()
+	get := mock.NewMockGetRepository()
+	get.MockGetWebhookSubscriptions = func(ctx context.Context, HotelUUID string) ([]domain.WebhookSubscription, error) {
+		return []domain.WebhookSubscription{
+			{AbstractBase: domain.AbstractBase{UUID: "all", Active: true}, HotelUUID: hotelUUID},
+			{
+				AbstractBase: domain.AbstractBase{UUID: "cancellations", Active: true},
+				HotelUUID:    hotelUUID,
+				EventTypes:   []domain.EventType{domain.RESERVATION_CANCELLED},
+			},
+		}, nil
+	}
+	var queued []*domain.WebhookDelivery
+	create := mock.NewMockCreateRepository()
+	create.MockCreateWebhookDeliveries = func(ctx context.Context, deliveries []*domain.WebhookDelivery) error {
+		queued = deliveries
+		return nil
+	}
+	u := newUseCase(t, create, get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+
+	err := u.EnqueueWebhookDeliveries(ctx, &domain.OutboxEvent{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:    hotelUUID,
+		Type:         domain.RESERVATION_CREATED,
+	})
+	if err != nil {
+		t.Fatalf("Usecase.EnqueueWebhookDeliveries() unexpected error = %v", err)
+	}
+	if len(queued) != 1 || queued[0].SubscriptionUUID != "all" || queued[0].Status != domain.DELIVERY_PENDING {
+		t.Errorf("expected a single pending delivery to the unfiltered subscription but got %v", queued)
+	}
+}
+
+func TestUsecase_DispatchWebhooks(t *testing.T) {
+	ctx := context.Background()
+	secret := gofakeit.Password(true, true, true, false, false, 32)
+
+	tests := []struct {
+		name       string
+		status     int
+		attempts   int
+		wantStatus domain.DeliveryStatus
+	}{
+		{
+			name:       "Happy case: receiver acknowledges the event",
+			status:     http.StatusOK,
+			wantStatus: domain.DELIVERED,
+		},
+		{
+			name:       "Sad case: receiver fails and the delivery is retried",
+			status:     http.StatusServiceUnavailable,
+			wantStatus: domain.DELIVERY_PENDING,
+		},
+		{
+			name:       "Sad case: receiver fails for the last time and the delivery is dead lettered",
+			status:     http.StatusServiceUnavailable,
+			attempts:   domain.MaxWebhookAttempts - 1,
+			wantStatus: domain.DEAD_LETTERED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verified := false
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				verified = webhook.Verify(secret, r.Header.Get(webhook.SignatureHeader), body)
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			delivery := &domain.WebhookDelivery{
+				EventUUID: gofakeit.UUID(),
+				EventType: domain.RESERVATION_CREATED,
+				Status:    domain.DELIVERY_PENDING,
+				Attempts:  tt.attempts,
+				Subscription: &domain.WebhookSubscription{
+					URL:    receiver.URL,
+					Secret: secret,
+				},
+				Event: &domain.OutboxEvent{Payload: []byte(`{"type":"reservation.created"}`)},
+			}
+			update := mock.NewMockUpdateRepository()
+			update.MockClaimWebhookDeliveries = func(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+				return []domain.WebhookDelivery{*delivery}, nil
+			}
+			update.MockRecordWebhookDelivery = func(ctx context.Context, recorded *domain.WebhookDelivery) error {
+				*delivery = *recorded
+				return nil
+			}
+			u := newUseCase(t, mock.NewMockCreateRepository(), mock.NewMockGetRepository(), update, payment.NewFakeGateway())
+
+			// the test receiver listens on the loopback address the sender refuses
+			sender := webhook.NewSender()
+			sender.Client = receiver.Client()
+			if _, err := u.DispatchWebhooks(ctx, sender); err != nil {
+				t.Fatalf("Usecase.DispatchWebhooks() unexpected error = %v", err)
+			}
+			if !verified {
+				t.Errorf("expected the receiver to verify the delivery's signature")
+			}
+			if delivery.Status != tt.wantStatus || delivery.LastStatusCode != tt.status {
+				t.Errorf("expected a %v delivery with status code %v but got %v with %v", tt.wantStatus, tt.status, delivery.Status, delivery.LastStatusCode)
+			}
+		})
+	}
+}
+
+func TestUsecase_GuestNotifier(t *testing.T) {
+	ctx := context.Background()
+	templates, err := notification.NewTemplates()
+	if err != nil {
+		t.Fatalf("can't load email templates: %v", err)
+	}
+	get := mock.NewMockGetRepository()
+	get.MockGetGuest = func(ctx context.Context, GuestUUID string) (*domain.Guest, error) {
+		return &domain.Guest{FirstName: "Amina", Email: "amina@example.com"}, nil
+	}
+	get.MockGetHotel = func(ctx context.Context, HotelUUID string) (*domain.Hotel, error) {
+		return &domain.Hotel{Name: "Sea View", Email: "frontdesk@seaview.example.com"}, nil
+	}
+	u := newUseCase(t, mock.NewMockCreateRepository(), get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+
+	tests := []struct {
+		name        string
+		eventType   domain.EventType
+		wantSubject string
+	}{
+		{
+			name:        "Happy case: confirmation is sent when a reservation is created",
+			eventType:   domain.RESERVATION_CREATED,
+			wantSubject: "Your reservation at Sea View is confirmed",
+		},
+		{
+			name:        "Happy case: cancellation is sent when a reservation is cancelled",
+			eventType:   domain.RESERVATION_CANCELLED,
+			wantSubject: "Your reservation at Sea View has been cancelled",
+		},
+		{
+			name:      "Happy case: no email is sent on check in",
+			eventType: domain.RESERVATION_CHECKED_IN,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mailer := &recordingMailer{}
+			event, err := domain.NewReservationEvent(tt.eventType, &domain.Reservation{
+				AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+				GuestUUID:    gofakeit.UUID(),
+				HotelUUID:    gofakeit.UUID(),
+			}, time.Now())
+			if err != nil {
+				t.Fatalf("can't build event: %v", err)
+			}
+
+			if err := u.GuestNotifier(mailer, templates).Publish(ctx, event); err != nil {
+				t.Fatalf("GuestNotifier.Publish() unexpected error = %v", err)
+			}
+			if tt.wantSubject == "" {
+				if len(mailer.sent) != 0 {
+					t.Errorf("expected no email but got %v", mailer.sent)
+				}
+				return
+			}
+			if len(mailer.sent) != 1 {
+				t.Fatalf("expected one email but got %v", len(mailer.sent))
+			}
+			sent := mailer.sent[0]
+			if sent.To != "amina@example.com" || sent.ReplyTo != "frontdesk@seaview.example.com" || sent.Subject != tt.wantSubject {
+				t.Errorf("unexpected email %+v", sent)
+			}
+		})
+	}
+}
+
+// recordingMailer is a Mailer that keeps the emails it is asked to send
+type recordingMailer struct {
+	sent []notification.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, message notification.Message) error {
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+func TestUsecase_OfferWaitlistedRooms(t *testing.T) {
+	ctx := context.Background()
+	roomType := domain.RoomType{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:    gofakeit.UUID(),
+		Inventory:    1,
+	}
+	checkIn := domain.TruncateToDate(time.Now().AddDate(0, 0, 30))
+	waitlist := []domain.WaitlistEntry{
+		{AbstractBase: domain.AbstractBase{UUID: "longer-stay"}, GuestUUID: "first", HotelUUID: roomType.HotelUUID, RoomTypeUUID: roomType.UUID, StartDate: checkIn, EndDate: checkIn.AddDate(0, 0, 5)},
+		{AbstractBase: domain.AbstractBase{UUID: "same-stay"}, GuestUUID: "second", HotelUUID: roomType.HotelUUID, RoomTypeUUID: roomType.UUID, StartDate: checkIn, EndDate: checkIn.AddDate(0, 0, 2)},
+	}
+	get := mock.NewMockGetRepository()
+	get.MockGetRoomType = func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+		return &roomType, nil
+	}
+	get.MockGetRatesInRange = func(ctx context.Context, RoomTypeUUID string, StartDate, EndDate time.Time) ([]domain.Rate, error) {
+		rates := []domain.Rate{}
+		for night := StartDate; !night.After(EndDate); night = night.AddDate(0, 0, 1) {
+			rates = append(rates, domain.Rate{RoomTypeUUID: roomType.UUID, Date: night, Rate: 100})
+		}
+		return rates, nil
+	}
+	get.MockGetWaitlist = func(ctx context.Context, RoomTypeUUID string, StartDate, EndDate time.Time) ([]domain.WaitlistEntry, error) {
+		if !StartDate.Equal(checkIn) || !EndDate.Equal(checkIn.AddDate(0, 0, 2)) {
+			t.Errorf("expected the waitlist of the freed stay but got the one of %v to %v", StartDate, EndDate)
+		}
+		return waitlist, nil
+	}
+	cancelled := &domain.Reservation{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:    roomType.HotelUUID,
+		RoomTypeUUID: roomType.UUID,
+		StartDate:    checkIn,
+		EndDate:      checkIn.AddDate(0, 0, 2),
+	}
+
+	event := func(eventType domain.EventType) *domain.OutboxEvent {
+		event, err := domain.NewReservationEvent(eventType, cancelled, time.Now())
+		if err != nil {
+			t.Fatalf("can't build event: %v", err)
+		}
+		return event
+	}
+	moved := *cancelled
+	moved.StartDate = checkIn.AddDate(0, 0, 10)
+	moved.EndDate = checkIn.AddDate(0, 0, 12)
+	modified, err := domain.NewReservationModifiedEvent(&moved, cancelled, time.Now())
+	if err != nil {
+		t.Fatalf("can't build event: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		event     *domain.OutboxEvent
+		wantGuest string
+	}{
+		{
+			name:      "Happy case: the first guest whose stay fits is offered the room",
+			event:     event(domain.RESERVATION_CANCELLED),
+			wantGuest: "second",
+		},
+		{
+			name:      "Happy case: the stay a modified reservation released is offered",
+			event:     modified,
+			wantGuest: "second",
+		},
+		{
+			name:  "Happy case: events that don't free rooms are ignored",
+			event: event(domain.RESERVATION_CHECKED_IN),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var offeredTo string
+			var hold *domain.Reservation
+			update := mock.NewMockUpdateRepository()
+			update.MockOfferWaitlistEntry = func(ctx context.Context, entry *domain.WaitlistEntry, reservation *domain.Reservation) (*domain.Reservation, error) {
+				// only the two freed nights are available
+				if len(domain.StayNights(reservation.StartDate, reservation.EndDate)) > 2 {
+					return nil, domain.ErrSoldOut
+				}
+				offeredTo = entry.GuestUUID
+				hold = reservation
+				return reservation, nil
+			}
+			u := newUseCase(t, mock.NewMockCreateRepository(), get, update, payment.NewFakeGateway())
+
+			if err := u.OfferWaitlistedRooms(ctx, tt.event); err != nil {
+				t.Fatalf("Usecase.OfferWaitlistedRooms() unexpected error = %v", err)
+			}
+			if offeredTo != tt.wantGuest {
+				t.Fatalf("expected the room to be offered to %q but it was offered to %q", tt.wantGuest, offeredTo)
+			}
+			if hold != nil && (hold.Status != string(domain.HELD) || hold.ExpiresAt == nil || hold.TotalPrice != 200) {
+				t.Errorf("expected a priced, expiring hold but got %+v", hold)
+			}
+		})
+	}
+}
+
+func TestUsecase_CreateBooking(t *testing.T) {
+	ctx := context.Background()
+	roomType := domain.RoomType{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:    gofakeit.UUID(),
+		Inventory:    10,
+	}
+	checkIn := domain.TruncateToDate(time.Now().AddDate(0, 0, 30))
+	get := mock.NewMockGetRepository()
+	get.MockGetRoomType = func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+		return &roomType, nil
+	}
+	get.MockGetRatesInRange = func(ctx context.Context, RoomTypeUUID string, StartDate, EndDate time.Time) ([]domain.Rate, error) {
+		rates := []domain.Rate{}
+		for night := StartDate; !night.After(EndDate); night = night.AddDate(0, 0, 1) {
+			rates = append(rates, domain.Rate{RoomTypeUUID: roomType.UUID, Date: night, Rate: 100})
+		}
+		return rates, nil
+	}
+	releaseDate := checkIn.AddDate(0, 0, -7)
+	pastReleaseDate := time.Now().AddDate(0, 0, -1)
+
+	tests := []struct {
+		name          string
+		releaseDate   *time.Time
+		paymentMethod string
+		unknownGuest  bool
+		soldOut       bool
+		wantStatus    domain.BookingStatus
+		wantPayment   bool
+		wantErr       error
+		wantAnyErr    bool
+	}{
+		{
+			name:        "Happy case: every room is booked and paid for",
+			wantStatus:  domain.BOOKING_CONFIRMED,
+			wantPayment: true,
+		},
+		{
+			name:        "Happy case: group block is paid for when confirmed",
+			releaseDate: &releaseDate,
+			wantStatus:  domain.BOOKING_BLOCKED,
+		},
+		{
+			name:        "Sad case: release date has passed",
+			releaseDate: &pastReleaseDate,
+			wantAnyErr:  true,
+		},
+		{
+			name:         "Sad case: guest doesn't exist",
+			unknownGuest: true,
+			wantAnyErr:   true,
+		},
+		{
+			name:          "Sad case: payment declined",
+			paymentMethod: payment.DeclinedPaymentMethod,
+			wantErr:       domain.ErrPaymentDeclined,
+		},
+		{
+			name:    "Sad case: one of the lines is sold out",
+			soldOut: true,
+			wantErr: domain.ErrSoldOut,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := payment.NewFakeGateway()
+			var authorized *domain.Payment
+			create := mock.NewMockCreateRepository()
+			create.MockCreateBooking = func(ctx context.Context, booking *domain.Booking, charge *domain.Payment) (*domain.Booking, error) {
+				authorized = charge
+				if tt.soldOut {
+					return nil, fmt.Errorf("infrastructure: can't create a new booking: %w", domain.ErrSoldOut)
+				}
+				return booking, nil
+			}
+			get.MockGetGuest = func(ctx context.Context, GuestUUID string) (*domain.Guest, error) {
+				if tt.unknownGuest {
+					return nil, nil
+				}
+				return &domain.Guest{AbstractBase: domain.AbstractBase{UUID: GuestUUID}}, nil
+			}
+			u := newUseCase(t, create, get, mock.NewMockUpdateRepository(), gateway)
+
+			booking, err := u.CreateBooking(ctx, &domain.Booking{
+				GuestUUID:     gofakeit.UUID(),
+				HotelUUID:     roomType.HotelUUID,
+				StartDate:     checkIn,
+				EndDate:       checkIn.AddDate(0, 0, 2),
+				ReleaseDate:   tt.releaseDate,
+				PaymentMethod: tt.paymentMethod,
+				Lines: []domain.BookingLine{
+					{RoomTypeUUID: roomType.UUID, Quantity: 2, Occupants: 2},
+					{RoomTypeUUID: roomType.UUID, Quantity: 1, Occupants: 1},
+				},
+			})
+			if tt.wantErr != nil || tt.wantAnyErr {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("Usecase.CreateBooking() error = %v, want %v", err, tt.wantErr)
+				}
+				if authorized != nil {
+					// the authorization of a booking that couldn't be made is given back
+					if _, _, voided := gateway.Balance(authorized.GatewayReference); !voided {
+						t.Errorf("expected the authorization of the failed booking to be voided")
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Usecase.CreateBooking() unexpected error = %v", err)
+			}
+			if booking.Status != tt.wantStatus {
+				t.Errorf("expected the booking to be %v but got %v", tt.wantStatus, booking.Status)
+			}
+			if booking.Lines[0].TotalPrice != 400 || booking.TotalPrice != 600 {
+				t.Errorf("expected line and booking prices of 400 and 600 but got %v and %v", booking.Lines[0].TotalPrice, booking.TotalPrice)
+			}
+			for _, line := range booking.Lines {
+				if line.Status != tt.wantStatus {
+					t.Errorf("expected every line to be %v but got %v", tt.wantStatus, line.Status)
+				}
+			}
+			if !tt.wantPayment {
+				if authorized != nil {
+					t.Errorf("expected no payment but got %+v", authorized)
+				}
+				return
+			}
+			if authorized == nil || authorized.Status != domain.AUTHORIZED || authorized.Amount != 600 {
+				t.Fatalf("expected 600 to be authorized but got %+v", authorized)
+			}
+			if _, _, voided := gateway.Balance(authorized.GatewayReference); voided {
+				t.Errorf("expected the authorization of the booking to be kept")
+			}
+		})
+	}
+}
+
+func TestUsecase_CancelBookingLine(t *testing.T) {
+	ctx := context.Background()
+	checkIn := domain.TruncateToDate(time.Now().AddDate(0, 0, 30))
+
+	tests := []struct {
+		name         string
+		paid         bool
+		wantCaptured int
+		wantRefunded int
+	}{
+		{
+			name:         "Happy case: the price of the cancelled line is refunded",
+			paid:         true,
+			wantCaptured: 600,
+			wantRefunded: 200,
+		},
+		{
+			name: "Happy case: an unconfirmed group block has nothing to refund",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := payment.NewFakeGateway()
+			booking := &domain.Booking{
+				AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+				StartDate:    checkIn,
+				EndDate:      checkIn.AddDate(0, 0, 2),
+				Status:       domain.BOOKING_CONFIRMED,
+				Lines: []domain.BookingLine{
+					{AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()}, TotalPrice: 400, Status: domain.BOOKING_CONFIRMED},
+					{AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()}, TotalPrice: 200, Status: domain.BOOKING_CANCELLED},
+				},
+			}
+			var charge *domain.Payment
+			var reference string
+			if tt.paid {
+				var err error
+				reference, err = gateway.Authorize(ctx, domain.AuthorizeRequest{Amount: 600, IdempotencyKey: booking.UUID})
+				if err != nil {
+					t.Fatalf("can't authorize payment: %v", err)
+				}
+				// the refund owed is recorded with the cancellation
+				charge = &domain.Payment{
+					BookingUUID:      &booking.UUID,
+					Amount:           600,
+					Status:           domain.AUTHORIZED,
+					GatewayReference: reference,
+				}
+				charge.OweRefund(domain.BookingLineRefund(booking, &booking.Lines[1], nil, time.Now()), time.Now())
+			}
+
+			get := mock.NewMockGetRepository()
+			get.MockGetBookingPayment = func(ctx context.Context, BookingUUID string) (*domain.Payment, error) {
+				return charge, nil
+			}
+			var updated *domain.Payment
+			update := mock.NewMockUpdateRepository()
+			update.MockCancelBookingLine = func(ctx context.Context, BookingUUID, LineUUID string) (*domain.Booking, error) {
+				return booking, nil
+			}
+			update.MockUpdatePayment = func(ctx context.Context, charge *domain.Payment) (*domain.Payment, error) {
+				updated = charge
+				return charge, nil
+			}
+			u := newUseCase(t, mock.NewMockCreateRepository(), get, update, gateway)
+
+			if _, err := u.CancelBookingLine(ctx, booking.UUID, booking.Lines[1].UUID); err != nil {
+				t.Fatalf("Usecase.CancelBookingLine() unexpected error = %v", err)
+			}
+			if !tt.paid {
+				if updated != nil {
+					t.Errorf("expected no payment to be settled but got %+v", updated)
+				}
+				return
+			}
+			if updated == nil || updated.Status != domain.PARTIALLY_REFUNDED || updated.SettleAt != nil {
+				t.Fatalf("expected the payment to be settled as %v but got %+v", domain.PARTIALLY_REFUNDED, updated)
+			}
+			captured, refunded, _ := gateway.Balance(reference)
+			if captured != tt.wantCaptured || refunded != tt.wantRefunded {
+				t.Errorf("expected %v captured and %v refunded but got %v and %v", tt.wantCaptured, tt.wantRefunded, captured, refunded)
+			}
+		})
+	}
+}
+
+func TestUsecase_ConfirmBookingBlock(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name              string
+		status            domain.BookingStatus
+		releasedMeanwhile bool
+		wantErr           error
+	}{
+		{
+			name:   "Happy case: the block is paid for and booked",
+			status: domain.BOOKING_BLOCKED,
+		},
+		{
+			name:    "Sad case: released block isn't charged",
+			status:  domain.BOOKING_RELEASED,
+			wantErr: domain.ErrBlockReleased,
+		},
+		{
+			name:              "Sad case: block released while it was confirmed gives its authorization back",
+			status:            domain.BOOKING_BLOCKED,
+			releasedMeanwhile: true,
+			wantErr:           domain.ErrBlockReleased,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := payment.NewFakeGateway()
+			bookingUUID := gofakeit.UUID()
+			get := mock.NewMockGetRepository()
+			get.MockGetBooking = func(ctx context.Context, BookingUUID string) (*domain.Booking, error) {
+				return &domain.Booking{
+					AbstractBase: domain.AbstractBase{UUID: BookingUUID},
+					Status:       tt.status,
+					TotalPrice:   600,
+				}, nil
+			}
+			var authorized *domain.Payment
+			update := mock.NewMockUpdateRepository()
+			update.MockConfirmBookingBlock = func(ctx context.Context, BookingUUID string, charge *domain.Payment, now time.Time) (*domain.Booking, error) {
+				authorized = charge
+				if tt.releasedMeanwhile {
+					return nil, fmt.Errorf("infrastructure: can't confirm booking block: %w", domain.ErrBlockReleased)
+				}
+				return &domain.Booking{AbstractBase: domain.AbstractBase{UUID: BookingUUID}, Status: domain.BOOKING_CONFIRMED}, nil
+			}
+			u := newUseCase(t, mock.NewMockCreateRepository(), get, update, gateway)
+
+			_, err := u.ConfirmBookingBlock(ctx, bookingUUID, gofakeit.UUID())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Usecase.ConfirmBookingBlock() error = %v, want %v", err, tt.wantErr)
+				}
+				if authorized != nil {
+					if _, _, voided := gateway.Balance(authorized.GatewayReference); !voided {
+						t.Errorf("expected the authorization of the block to be voided")
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Usecase.ConfirmBookingBlock() unexpected error = %v", err)
+			}
+			if authorized == nil || authorized.Status != domain.AUTHORIZED || authorized.Amount != 600 {
+				t.Errorf("expected 600 to be authorized but got %+v", authorized)
+			}
+		})
+	}
+}
+
+func TestUsecase_ModifyReservation(t *testing.T) {
+	ctx := context.Background()
+	hotelUUID := gofakeit.UUID()
+	standard, suite := gofakeit.UUID(), gofakeit.UUID()
+	nightlyRates := map[string]int{standard: 100, suite: 150}
+	checkIn := domain.TruncateToDate(time.Now().AddDate(0, 0, 30))
+	ratePlanUUID := gofakeit.UUID()
+	get := mock.NewMockGetRepository()
+	get.MockGetRoomType = func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+		return &domain.RoomType{
+			AbstractBase: domain.AbstractBase{UUID: RoomTypeUUID},
+			HotelUUID:    hotelUUID,
+			Inventory:    10,
+		}, nil
+	}
+	get.MockGetRatesInRange = func(ctx context.Context, RoomTypeUUID string, StartDate, EndDate time.Time) ([]domain.Rate, error) {
+		rates := []domain.Rate{}
+		for night := StartDate; !night.After(EndDate); night = night.AddDate(0, 0, 1) {
+			rates = append(rates, domain.Rate{RoomTypeUUID: RoomTypeUUID, Date: night, Rate: nightlyRates[RoomTypeUUID]})
+		}
+		return rates, nil
+	}
+
+	extended := checkIn.AddDate(0, 0, 3).Format(domain.DateLayout)
+
+	tests := []struct {
+		name         string
+		status       domain.ReservationStatus
+		payment      domain.PaymentStatus
+		payload      dto.ReservationModificationPayload
+		redeemed     int
+		soldOut      bool
+		wantRoomType string
+		wantPrice    int
+		wantRatePlan bool
+		wantRedeemed int
+		wantAmount   int
+		wantReplaced bool
+		wantRefunded int
+		wantErr      error
+		wantAnyErr   bool
+	}{
+		{
+			name:         "Happy case: extend the stay and authorize its new price",
+			status:       domain.RESERVED,
+			payment:      domain.AUTHORIZED,
+			payload:      dto.ReservationModificationPayload{EndDate: extended, PaymentMethod: gofakeit.UUID()},
+			wantRoomType: standard,
+			wantPrice:    300,
+			wantAmount:   300,
+			wantReplaced: true,
+		},
+		{
+			name:         "Happy case: shorten a paid stay and refund the difference",
+			status:       domain.RESERVED,
+			payment:      domain.CAPTURED,
+			payload:      dto.ReservationModificationPayload{EndDate: checkIn.AddDate(0, 0, 1).Format(domain.DateLayout)},
+			wantRoomType: standard,
+			wantPrice:    100,
+			wantAmount:   100,
+			wantRefunded: 100,
+		},
+		{
+			name:         "Happy case: upgrade the room type of a hold, it is paid for when confirmed",
+			status:       domain.HELD,
+			payload:      dto.ReservationModificationPayload{RoomTypeUUID: suite},
+			wantRoomType: suite,
+			wantPrice:    300,
+		},
+		{
+			name:         "Happy case: shorten a hold paid with points, the points its price isn't worth are given back",
+			status:       domain.HELD,
+			payload:      dto.ReservationModificationPayload{EndDate: checkIn.AddDate(0, 0, 1).Format(domain.DateLayout)},
+			redeemed:     150,
+			wantRoomType: standard,
+			wantPrice:    0,
+			wantRedeemed: 100,
+		},
+		{
+			name:       "Sad case: the higher price has no payment method",
+			status:     domain.RESERVED,
+			payment:    domain.AUTHORIZED,
+			payload:    dto.ReservationModificationPayload{EndDate: extended},
+			wantAnyErr: true,
+		},
+		{
+			name:    "Sad case: the higher price is declined",
+			status:  domain.RESERVED,
+			payment: domain.AUTHORIZED,
+			payload: dto.ReservationModificationPayload{EndDate: extended, PaymentMethod: payment.DeclinedPaymentMethod},
+			wantErr: domain.ErrPaymentDeclined,
+		},
+		{
+			name:       "Sad case: the price of a captured payment can't be raised",
+			status:     domain.RESERVED,
+			payment:    domain.CAPTURED,
+			payload:    dto.ReservationModificationPayload{EndDate: extended, PaymentMethod: gofakeit.UUID()},
+			wantAnyErr: true,
+		},
+		{
+			name:    "Sad case: the new nights are sold out",
+			status:  domain.RESERVED,
+			payment: domain.AUTHORIZED,
+			payload: dto.ReservationModificationPayload{StartDate: checkIn.AddDate(0, 0, 1).Format(domain.DateLayout), EndDate: checkIn.AddDate(0, 0, 4).Format(domain.DateLayout), PaymentMethod: gofakeit.UUID()},
+			soldOut: true,
+			wantErr: domain.ErrSoldOut,
+		},
+		{
+			name:       "Sad case: cancelled reservation",
+			status:     domain.CANCELLED,
+			payload:    dto.ReservationModificationPayload{RoomTypeUUID: suite},
+			wantAnyErr: true,
+		},
+		{
+			name:       "Sad case: nothing changes",
+			status:     domain.RESERVED,
+			payload:    dto.ReservationModificationPayload{RoomTypeUUID: standard},
+			wantAnyErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := payment.NewFakeGateway()
+			reservation := &domain.Reservation{
+				AbstractBase:   domain.AbstractBase{UUID: gofakeit.UUID()},
+				GuestUUID:      gofakeit.UUID(),
+				HotelUUID:      hotelUUID,
+				RoomTypeUUID:   standard,
+				StartDate:      checkIn,
+				EndDate:        checkIn.AddDate(0, 0, 2),
+				TotalPrice:     200,
+				Status:         string(tt.status),
+				RedeemedPoints: tt.redeemed,
+			}
+			if tt.payload.RoomTypeUUID == suite {
+				reservation.RatePlanUUID = &ratePlanUUID
+			}
+			var charge *domain.Payment
+			if tt.payment != "" {
+				reference, err := gateway.Authorize(ctx, domain.AuthorizeRequest{Amount: 200, IdempotencyKey: reservation.UUID})
+				if err != nil {
+					t.Fatalf("can't authorize payment: %v", err)
+				}
+				charge = &domain.Payment{
+					ReservationUUID:  &reservation.UUID,
+					Amount:           200,
+					Currency:         domain.DefaultCurrency,
+					Status:           domain.AUTHORIZED,
+					GatewayReference: reference,
+				}
+				if tt.payment == domain.CAPTURED {
+					if err := gateway.Capture(ctx, reference, 200); err != nil {
+						t.Fatalf("can't capture payment: %v", err)
+					}
+					charge.Status = domain.CAPTURED
+					charge.CapturedAmount = 200
+				}
+			}
+			original := charge
+			if charge != nil {
+				copied := *charge
+				original = &copied
+			}
+			get.MockGetReservation = func(ctx context.Context, ReservationUUID string) (*domain.Reservation, error) {
+				return reservation, nil
+			}
+			get.MockGetPayment = func(ctx context.Context, ReservationUUID string) (*domain.Payment, error) {
+				return charge, nil
+			}
+			var authorized string
+			update := mock.NewMockUpdateRepository()
+			update.MockModifyReservation = func(ctx context.Context, modified *domain.Reservation, authorization string) (*domain.Reservation, string, error) {
+				authorized = authorization
+				if tt.soldOut {
+					return nil, "", fmt.Errorf("infrastructure: can't modify reservation: %w", domain.ErrSoldOut)
+				}
+				if charge == nil {
+					return modified, "", nil
+				}
+				// the payment is repriced in the modification's transaction
+				repriced := *charge
+				replaced, err := repriced.Reprice(modified.TotalPrice, authorization, time.Now())
+				if err != nil {
+					return nil, "", fmt.Errorf("infrastructure: can't modify reservation: %w", err)
+				}
+				charge = &repriced
+				return modified, replaced, nil
+			}
+			update.MockUpdatePayment = func(ctx context.Context, updated *domain.Payment) (*domain.Payment, error) {
+				charge = updated
+				return updated, nil
+			}
+			u := newUseCase(t, mock.NewMockCreateRepository(), get, update, gateway)
+
+			modified, err := u.ModifyReservation(ctx, reservation.UUID, &tt.payload)
+			if tt.wantErr != nil || tt.wantAnyErr {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("Usecase.ModifyReservation() error = %v, want %v", err, tt.wantErr)
+				}
+				if reservation.TotalPrice != 200 || reservation.RoomTypeUUID != standard {
+					t.Errorf("expected a failed modification to leave the reservation untouched")
+				}
+				if authorized != "" {
+					if _, _, voided := gateway.Balance(authorized); !voided {
+						t.Errorf("expected the authorization of the new price to be voided")
+					}
+				}
+				if original != nil {
+					if _, _, voided := gateway.Balance(original.GatewayReference); voided || charge.GatewayReference != original.GatewayReference {
+						t.Errorf("expected a failed modification to keep the reservation's payment")
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Usecase.ModifyReservation() unexpected error = %v", err)
+			}
+			if modified.RoomTypeUUID != tt.wantRoomType || modified.TotalPrice != tt.wantPrice {
+				t.Errorf("expected room type %v at %v but got %v at %v", tt.wantRoomType, tt.wantPrice, modified.RoomTypeUUID, modified.TotalPrice)
+			}
+			if modified.RedeemedPoints != tt.wantRedeemed {
+				t.Errorf("expected %v redeemed points but got %v", tt.wantRedeemed, modified.RedeemedPoints)
+			}
+			if (modified.RatePlanUUID != nil) != tt.wantRatePlan {
+				t.Errorf("expected the rate plan to be kept: %v, got %v", tt.wantRatePlan, modified.RatePlanUUID)
+			}
+			if original == nil {
+				if authorized != "" {
+					t.Errorf("expected nothing to be authorized but got %v", authorized)
+				}
+				return
+			}
+			if charge.Amount != tt.wantAmount || charge.SettleAt != nil {
+				t.Errorf("expected a settled payment of %v but got %+v", tt.wantAmount, charge)
+			}
+			_, _, voided := gateway.Balance(original.GatewayReference)
+			if replaced := charge.GatewayReference != original.GatewayReference; replaced != tt.wantReplaced || voided != tt.wantReplaced {
+				t.Errorf("expected the authorization to be replaced and voided: %v, got replaced %v and voided %v", tt.wantReplaced, replaced, voided)
+			}
+			if _, refunded, _ := gateway.Balance(original.GatewayReference); refunded != tt.wantRefunded {
+				t.Errorf("expected %v refunded but got %v", tt.wantRefunded, refunded)
+			}
+		})
+	}
+}
+
+func TestUsecase_LookupReservation(t *testing.T) {
+	ctx := context.Background()
+	code, err := domain.NewConfirmationCode()
+	if err != nil {
+		t.Fatalf("NewConfirmationCode() unexpected error = %v", err)
+	}
+	reservation := &domain.Reservation{
+		AbstractBase:     domain.AbstractBase{UUID: gofakeit.UUID()},
+		Guest:            domain.Guest{FirstName: gofakeit.FirstName(), LastName: "Wanjiru"},
+		ConfirmationCode: code,
+	}
+	get := mock.NewMockGetRepository()
+	get.MockGetReservationByConfirmationCode = func(ctx context.Context, ConfirmationCode string) (*domain.Reservation, error) {
+		if ConfirmationCode == code {
+			return reservation, nil
+		}
+		return nil, nil
+	}
+	u := newUseCase(t, mock.NewMockCreateRepository(), get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+	other, err := domain.NewConfirmationCode()
+	if err != nil {
+		t.Fatalf("NewConfirmationCode() unexpected error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastName string
+		wantErr  error
+	}{
+		{name: "Happy case: code and name match", code: code, lastName: "Wanjiru"},
+		{name: "Happy case: code typed in lower case with a dash", code: strings.ToLower(code[:4] + "-" + code[4:]), lastName: " wanjiru"},
+		{name: "Sad case: wrong last name", code: code, lastName: "Otieno", wantErr: domain.ErrReservationNotFound},
+		{name: "Sad case: unknown code", code: other, lastName: "Wanjiru", wantErr: domain.ErrReservationNotFound},
+		{name: "Sad case: malformed code", code: "NOT-A-CODE", lastName: "Wanjiru", wantErr: domain.ErrReservationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := u.LookupReservation(ctx, tt.code, tt.lastName)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Usecase.LookupReservation() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Usecase.LookupReservation() unexpected error = %v", err)
+			}
+			if found.UUID != reservation.UUID {
+				t.Errorf("expected reservation %v but got %v", reservation.UUID, found.UUID)
+			}
+		})
+	}
+}
+
+func TestUsecase_RedeemLoyaltyPoints(t *testing.T) {
+	ctx := context.Background()
+	roomType := domain.RoomType{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		HotelUUID:    gofakeit.UUID(),
+		Inventory:    10,
+	}
+	checkIn := domain.TruncateToDate(time.Now().AddDate(0, 0, 30))
+	get := mock.NewMockGetRepository()
+	get.MockGetRoomType = func(ctx context.Context, RoomTypeUUID string) (*domain.RoomType, error) {
+		return &roomType, nil
+	}
+	get.MockGetRatesInRange = func(ctx context.Context, RoomTypeUUID string, StartDate, EndDate time.Time) ([]domain.Rate, error) {
+		return []domain.Rate{{RoomTypeUUID: roomType.UUID, Date: checkIn, Rate: 200}}, nil
+	}
+	get.MockGetLoyaltyBalance = func(ctx context.Context, GuestUUID string) (int, error) {
+		return 300, nil
+	}
+	create := mock.NewMockCreateRepository()
+	create.MockCreateReservation = func(ctx context.Context, reservation *domain.Reservation) (*domain.Reservation, error) {
+		return reservation, nil
+	}
+	u := newUseCase(t, create, get, mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+
+	tests := []struct {
+		name       string
+		redeem     int
+		wantPoints int
+		wantPrice  int
+		wantErr    error
+	}{
+		{name: "Happy case: points are taken off the price", redeem: 50, wantPoints: 50, wantPrice: 150},
+		{name: "Happy case: no more points than the price is worth", redeem: 250, wantPoints: 200, wantPrice: 0},
+		{name: "Sad case: not enough points", redeem: 301, wantErr: domain.ErrInsufficientPoints},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			held, err := u.HoldReservation(ctx, &domain.Reservation{
+				GuestUUID:      gofakeit.UUID(),
+				HotelUUID:      roomType.HotelUUID,
+				RoomTypeUUID:   roomType.UUID,
+				StartDate:      checkIn,
+				EndDate:        checkIn.AddDate(0, 0, 1),
+				RedeemedPoints: tt.redeem,
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Usecase.HoldReservation() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Usecase.HoldReservation() unexpected error = %v", err)
+			}
+			if held.RedeemedPoints != tt.wantPoints || held.TotalPrice != tt.wantPrice {
+				t.Errorf("expected %v points off a price of %v but got %v points off %v",
+					tt.wantPoints, tt.wantPrice, held.RedeemedPoints, held.TotalPrice)
+			}
+		})
+	}
+}
+
+func TestUsecase_UpdateLoyaltyLedger(t *testing.T) {
+	ctx := context.Background()
+	reservation := &domain.Reservation{
+		AbstractBase: domain.AbstractBase{UUID: gofakeit.UUID()},
+		GuestUUID:    gofakeit.UUID(),
+		TotalPrice:   1250,
+	}
+
+	tests := []struct {
+		name         string
+		eventType    domain.EventType
+		wantAccrued  int
+		wantReversed bool
+	}{
+		{name: "Happy case: checking out earns points", eventType: domain.RESERVATION_CHECKED_OUT, wantAccrued: 12},
+		{name: "Happy case: cancelling reverses points", eventType: domain.RESERVATION_CANCELLED, wantReversed: true},
+		{name: "Happy case: other events are ignored", eventType: domain.RESERVATION_CHECKED_IN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var accrued *domain.LoyaltyEntry
+			create := mock.NewMockCreateRepository()
+			create.MockCreateLoyaltyEntry = func(ctx context.Context, entry *domain.LoyaltyEntry) error {
+				accrued = entry
+				return nil
+			}
+			reversed := false
+			update := mock.NewMockUpdateRepository()
+			update.MockReverseLoyaltyEntries = func(ctx context.Context, ReservationUUID string) (*domain.LoyaltyEntry, error) {
+				reversed = ReservationUUID == reservation.UUID
+				return nil, nil
+			}
+			u := newUseCase(t, create, mock.NewMockGetRepository(), update, payment.NewFakeGateway())
+
+			event, err := domain.NewReservationEvent(tt.eventType, reservation, time.Now())
+			if err != nil {
+				t.Fatalf("NewReservationEvent() unexpected error = %v", err)
+			}
+			if err := u.UpdateLoyaltyLedger(ctx, event); err != nil {
+				t.Fatalf("Usecase.UpdateLoyaltyLedger() unexpected error = %v", err)
+			}
+			if tt.wantAccrued > 0 && (accrued == nil || accrued.Points != tt.wantAccrued || accrued.Type != domain.ACCRUAL) {
+				t.Errorf("expected an accrual of %v points but got %v", tt.wantAccrued, accrued)
+			}
+			if tt.wantAccrued == 0 && accrued != nil {
+				t.Errorf("expected no points to be awarded but got %v", accrued)
+			}
+			if reversed != tt.wantReversed {
+				t.Errorf("expected the reservation's points to be reversed: %v", tt.wantReversed)
+			}
+		})
+	}
+}
+
+func TestUsecase_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(trace.NewNoopTracerProvider()) })
+
+	create := mock.NewMockCreateRepository()
+	create.MockCreateGuest = func(ctx context.Context, guest *domain.Guest) (*domain.Guest, error) {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			t.Errorf("expected the repository to be called within the use case's span")
+		}
+		return nil, errors.New("connection refused")
+	}
+	u := newUseCase(t, create, mock.NewMockGetRepository(), mock.NewMockUpdateRepository(), payment.NewFakeGateway())
+
+	if _, err := u.CreateGuest(context.Background(), &domain.Guest{}); err == nil {
+		t.Fatalf("Usecase.CreateGuest() expected an error")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span but got %d", len(spans))
+	}
+	if spans[0].Name() != "usecase.CreateGuest" {
+		t.Errorf("span is named %q, want %q", spans[0].Name(), "usecase.CreateGuest")
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("expected the span to record the use case's error but got status %v", spans[0].Status())
+	}
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/common/dto"
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+// loyaltyReversingEvents are the events after which the points a reservation earned or spent are undone
+var loyaltyReversingEvents = map[domain.EventType]bool{
+	domain.RESERVATION_CANCELLED:      true,
+	domain.RESERVATION_HOLD_EXPIRED:   true,
+	domain.RESERVATION_PAYMENT_FAILED: true,
+}
+
+// UpdateLoyaltyLedger awards points for stays guests check out of and reverses the points of reservations
+// that are given up. It is an events.PublisherFunc so that the outbox relay drives the ledger
+func (u *Usecase) UpdateLoyaltyLedger(
+	ctx context.Context,
+	event *domain.OutboxEvent,
+) error {
+	if event.Type != domain.RESERVATION_CHECKED_OUT && !loyaltyReversingEvents[event.Type] {
+		return nil
+	}
+	reservation, err := event.ReservationEvent()
+	if err != nil {
+		return err
+	}
+	if loyaltyReversingEvents[event.Type] {
+		if _, err := u.Update.ReverseLoyaltyEntries(ctx, reservation.ReservationUUID); err != nil {
+			return fmt.Errorf("can't reverse loyalty points: %w", err)
+		}
+		return nil
+	}
+	points := domain.AccruedPoints(reservation.TotalPrice)
+	if points == 0 {
+		return nil
+	}
+	if err := u.Create.CreateLoyaltyEntry(ctx, &domain.LoyaltyEntry{
+		GuestUUID:       reservation.GuestUUID,
+		ReservationUUID: reservation.ReservationUUID,
+		Type:            domain.ACCRUAL,
+		Points:          points,
+	}); err != nil {
+		return fmt.Errorf("can't award loyalty points: %w", err)
+	}
+	return nil
+}
+
+// GetLoyaltyBalance gets how many loyalty points a guest has
+func (u *Usecase) GetLoyaltyBalance(
+	ctx context.Context,
+	GuestUUID string,
+) (_ *dto.LoyaltyBalance, err error) {
+	ctx, endSpan := startSpan(ctx, "GetLoyaltyBalance")
+	defer endSpan(&err)
+	points, err := u.Get.GetLoyaltyBalance(ctx, GuestUUID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.LoyaltyBalance{
+		GuestUUID: GuestUUID,
+		Points:    points,
+		Value:     domain.PointsValue(points),
+	}, nil
+}
+
+// GetLoyaltyHistory gets a guest's loyalty ledger, newest entries first
+func (u *Usecase) GetLoyaltyHistory(
+	ctx context.Context,
+	GuestUUID string,
+) (_ []domain.LoyaltyEntry, err error) {
+	ctx, endSpan := startSpan(ctx, "GetLoyaltyHistory")
+	defer endSpan(&err)
+	return u.Get.GetLoyaltyEntries(ctx, GuestUUID)
+}
+
+// applyLoyaltyPoints takes the value of the loyalty points redeemed on a priced reservation off its price.
+// No more points are spent than the price is worth. The balance is checked again when the points are spent
+func (u *Usecase) applyLoyaltyPoints(
+	ctx context.Context,
+	reservation *domain.Reservation,
+) error {
+	if reservation.RedeemedPoints < 0 {
+		return errors.New("redeemed points can't be negative")
+	}
+	if reservation.RedeemedPoints == 0 {
+		return nil
+	}
+	balance, err := u.Get.GetLoyaltyBalance(ctx, reservation.GuestUUID)
+	if err != nil {
+		return fmt.Errorf("can't get loyalty balance: %w", err)
+	}
+	if balance < reservation.RedeemedPoints {
+		return fmt.Errorf("%w: %d points available", domain.ErrInsufficientPoints, balance)
+	}
+	redeemLoyaltyPoints(reservation)
+	return nil
+}
+
+// redeemLoyaltyPoints takes the value of a reservation's redeemed points off its price. No more points
+// are redeemed than the price is worth
+func redeemLoyaltyPoints(reservation *domain.Reservation) {
+	if worth := reservation.TotalPrice / domain.LoyaltyPointValue; reservation.RedeemedPoints > worth {
+		reservation.RedeemedPoints = worth
+	}
+	reservation.TotalPrice -= domain.PointsValue(reservation.RedeemedPoints)
+}
@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/common/dto"
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+	"github.com/google/uuid"
+)
+
+// modifiableStatuses are the statuses of reservations whose stay can still be changed
+var modifiableStatuses = map[domain.ReservationStatus]bool{
+	domain.HELD:     true,
+	domain.RESERVED: true,
+}
+
+// ModifyReservation changes the dates and/or room type of a reservation, re-quotes it and reprices its
+// payment. A higher price is authorized on the payload's payment method before the old nights are swapped
+// for the new ones and the payment adjusted atomically, so the reservation is left untouched when the new
+// nights are sold out or the new price isn't paid for
+func (u *Usecase) ModifyReservation(
+	ctx context.Context,
+	ReservationUUID string,
+	payload *dto.ReservationModificationPayload,
+) (_ *domain.Reservation, err error) {
+	ctx, endSpan := startSpan(ctx, "ModifyReservation")
+	defer endSpan(&err)
+	current, err := u.Get.GetReservation(ctx, ReservationUUID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get reservation: %w", err)
+	}
+	if current == nil {
+		return nil, fmt.Errorf("reservation %s doesn't exist", ReservationUUID)
+	}
+	if !modifiableStatuses[domain.ReservationStatus(current.Status)] {
+		return nil, fmt.Errorf("a %s reservation can't be modified", current.Status)
+	}
+
+	modified := *current
+	if payload.StartDate != "" {
+		if modified.StartDate, err = domain.ParseDate(payload.StartDate); err != nil {
+			return nil, fmt.Errorf("invalid start date %q: %w", payload.StartDate, err)
+		}
+	}
+	if payload.EndDate != "" {
+		if modified.EndDate, err = domain.ParseDate(payload.EndDate); err != nil {
+			return nil, fmt.Errorf("invalid end date %q: %w", payload.EndDate, err)
+		}
+	}
+	if payload.RoomTypeUUID != "" && payload.RoomTypeUUID != current.RoomTypeUUID {
+		modified.RoomTypeUUID = payload.RoomTypeUUID
+		modified.RatePlanUUID = nil
+	}
+	if payload.RatePlanUUID != "" {
+		ratePlanUUID := payload.RatePlanUUID
+		modified.RatePlanUUID = &ratePlanUUID
+	}
+	if modified.StartDate.Equal(current.StartDate) && modified.EndDate.Equal(current.EndDate) &&
+		modified.RoomTypeUUID == current.RoomTypeUUID && equalUUIDs(modified.RatePlanUUID, current.RatePlanUUID) {
+		return nil, errors.New("the modification doesn't change the reservation")
+	}
+	if err := domain.CheckStayLength(modified.StartDate, modified.EndDate); err != nil {
+		return nil, err
+	}
+	if len(domain.StayNights(modified.StartDate, modified.EndDate)) == 0 {
+		return nil, errors.New("a reservation must be at least one night long")
+	}
+
+	price, err := u.quoteReservation(ctx, &modified, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	modified.TotalPrice = price
+	modified.Discount = 0
+	if err := u.rediscount(ctx, &modified); err != nil {
+		return nil, err
+	}
+	// the redeemed points are taken off the new price, the ones it isn't worth are given back with the modification
+	redeemLoyaltyPoints(&modified)
+
+	authorization, err := u.authorizeRaisedPrice(ctx, &modified, payload.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	reservation, replaced, err := u.Update.ModifyReservation(ctx, &modified, authorization)
+	if err != nil {
+		if authorization != "" {
+			if voidErr := u.Payments.Void(ctx, authorization); voidErr != nil {
+				return nil, fmt.Errorf("%w, and can't void the authorization of its new price: %v", err, voidErr)
+			}
+		}
+		return nil, err
+	}
+	if replaced != "" {
+		if voidErr := u.Payments.Void(ctx, replaced); voidErr != nil {
+			// the replaced authorization is never captured, it lapses at the gateway
+			logging.FromContext(ctx).Errorf("can't void the replaced authorization of reservation %s: %v", reservation.UUID, voidErr)
+		}
+	}
+	// a refund owed for a lower price was recorded with the modification, the payment settler retries it
+	// when it fails here
+	charge, getErr := u.Get.GetPayment(ctx, reservation.UUID)
+	if getErr != nil {
+		logging.FromContext(ctx).Errorf("can't get the payment of modified reservation %s: %v", reservation.UUID, getErr)
+		return reservation, nil
+	}
+	if charge != nil && charge.SettleAt != nil {
+		if settleErr := u.settlePayment(ctx, charge); settleErr != nil {
+			logging.FromContext(ctx).Errorf("can't settle the payment of modified reservation %s: %v", reservation.UUID, settleErr)
+		}
+	}
+	return reservation, nil
+}
+
+// authorizeRaisedPrice authorizes the new price of a paid reservation whose price went up, it returns
+// the authorization's reference or an empty one when nothing has to be authorized
+func (u *Usecase) authorizeRaisedPrice(
+	ctx context.Context,
+	modified *domain.Reservation,
+	PaymentMethod string,
+) (string, error) {
+	charge, err := u.Get.GetPayment(ctx, modified.UUID)
+	if err != nil {
+		return "", fmt.Errorf("can't get payment: %w", err)
+	}
+	if charge == nil || modified.TotalPrice <= charge.Amount {
+		return "", nil
+	}
+	if PaymentMethod == "" {
+		return "", errors.New("a payment method is required to pay the reservation's higher price")
+	}
+	// a modification may be retried after its authorization was voided, every attempt is authorized on its own
+	reference, err := u.Payments.Authorize(ctx, domain.AuthorizeRequest{
+		Amount:         modified.TotalPrice,
+		Currency:       charge.Currency,
+		PaymentMethod:  PaymentMethod,
+		IdempotencyKey: uuid.New().String(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("can't authorize payment: %w", err)
+	}
+	return reference, nil
+}
+
+// rediscount applies the promotion a reservation was booked with to its new price. The promotion has
+// already been redeemed so only whether it covers the new room type is checked
+func (u *Usecase) rediscount(
+	ctx context.Context,
+	reservation *domain.Reservation,
+) error {
+	if reservation.PromotionUUID == nil {
+		return nil
+	}
+	promotion, err := u.Get.GetPromotionByCode(ctx, reservation.PromoCode)
+	if err != nil {
+		return fmt.Errorf("can't get promotion: %w", err)
+	}
+	if promotion == nil || !promotion.Covers(reservation.HotelUUID, reservation.RoomTypeUUID) {
+		return fmt.Errorf("%w: the reservation's promo code doesn't apply to the new room type", domain.ErrPromotionNotApplicable)
+	}
+	reservation.Discount = promotion.Discount(reservation.TotalPrice)
+	reservation.TotalPrice -= reservation.Discount
+	return nil
+}
+
+// equalUUIDs reports whether two optional UUIDs are the same
+func equalUUIDs(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/events"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/notification"
+)
+
+const (
+	// ArrivalReminderDays is how many days before check-in guests are reminded of their stay
+	ArrivalReminderDays = 2
+	// arrivalReminderBatchSize caps how many reminders a single transaction records
+	arrivalReminderBatchSize = 100
+)
+
+// guestEmails are the emails sent to guests for each kind of reservation event
+var guestEmails = map[domain.EventType]notification.Kind{
+	domain.RESERVATION_CREATED:          notification.CONFIRMATION,
+	domain.RESERVATION_CANCELLED:        notification.CANCELLATION,
+	domain.RESERVATION_ARRIVAL_DUE:      notification.REMINDER,
+	domain.RESERVATION_WAITLIST_OFFERED: notification.WAITLIST_OFFER,
+}
+
+// GuestNotifier emails guests about their reservations' events. Events are delivered at-least-once,
+// so a guest can receive the same email twice when publishing an event is retried
+func (u *Usecase) GuestNotifier(
+	mailer notification.Mailer,
+	templates *notification.Templates,
+) events.PublisherFunc {
+	return func(ctx context.Context, event *domain.OutboxEvent) error {
+		kind, ok := guestEmails[event.Type]
+		if !ok {
+			return nil
+		}
+		reservation, err := event.ReservationEvent()
+		if err != nil {
+			return err
+		}
+		guest, err := u.Get.GetGuest(ctx, reservation.GuestUUID)
+		if err != nil {
+			return fmt.Errorf("can't get guest: %w", err)
+		}
+		if guest == nil || guest.Email == "" {
+			logging.FromContext(ctx).Warnf("can't email guest %s about %s event %s, they have no email", reservation.GuestUUID, event.Type, event.UUID)
+			return nil
+		}
+		hotel, err := u.Get.GetHotel(ctx, reservation.HotelUUID)
+		if err != nil {
+			return fmt.Errorf("can't get hotel: %w", err)
+		}
+		if hotel == nil {
+			return fmt.Errorf("hotel %s doesn't exist", reservation.HotelUUID)
+		}
+
+		subject, body, err := templates.Render(kind, notification.Data{
+			Hotel:       *hotel,
+			Guest:       *guest,
+			Reservation: *reservation,
+		})
+		if err != nil {
+			return err
+		}
+		return mailer.Send(ctx, notification.Message{
+			ReplyTo: hotel.Email,
+			To:      guest.Email,
+			Subject: subject,
+			HTML:    body,
+		})
+	}
+}
+
+// SendArrivalReminders records an arrival due event, which reminds the guest by email, for every stay
+// starting in ArrivalReminderDays days
+func (u *Usecase) SendArrivalReminders(ctx context.Context) (int, error) {
+	arrivalDate := domain.TruncateToDate(time.Now()).AddDate(0, 0, ArrivalReminderDays)
+	reminded := 0
+	for {
+		count, err := u.Update.RecordArrivalReminders(ctx, arrivalDate, arrivalReminderBatchSize)
+		reminded += count
+		if err != nil || count < arrivalReminderBatchSize {
+			return reminded, err
+		}
+	}
+}
+
+// RunArrivalReminders sends arrival reminders every interval until the context is cancelled
+func (u *Usecase) RunArrivalReminders(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := u.SendArrivalReminders(ctx); err != nil {
+				logging.FromContext(ctx).Errorf("can't send arrival reminders: %v", err)
+			}
+		}
+	}
+}
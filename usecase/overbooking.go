@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/common/dto"
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+// SetOverbookingLimits overrides how many rooms of a room type can be sold beyond its physical inventory
+// on every date covered by the payload
+func (u *Usecase) SetOverbookingLimits(
+	ctx context.Context,
+	payload *dto.OverbookingPayload,
+) (_ []domain.RoomTypeInventory, err error) {
+	ctx, endSpan := startSpan(ctx, "SetOverbookingLimits")
+	defer endSpan(&err)
+	kind := domain.OverbookingType(payload.Type)
+	if err := domain.ValidateOverbooking(kind, payload.Value); err != nil {
+		return nil, err
+	}
+	start, err := domain.ParseDate(payload.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q: %w", payload.StartDate, err)
+	}
+	end, err := domain.ParseDate(payload.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %q: %w", payload.EndDate, err)
+	}
+	if end.Before(start) {
+		return nil, errors.New("the end date is before the start date")
+	}
+	// the end date is included
+	if domain.CountNights(start, end)+1 > maxRateCalendarDays {
+		return nil, fmt.Errorf("overbooking limits can be set on at most %d nights at once", maxRateCalendarDays)
+	}
+	nights := domain.StayNights(start, end.AddDate(0, 0, 1))
+	if _, err := u.getHotelRoomType(ctx, payload.HotelUUID, payload.RoomTypeUUID); err != nil {
+		return nil, err
+	}
+	return u.Update.SetOverbookingLimits(ctx, payload.RoomTypeUUID, nights, kind, payload.Value)
+}
+
+// GetWalkOutReport lists the nights between two dates (both inclusive) on which a hotel has confirmed more
+// guests than it has rooms, and how many of them would have to be walked to another hotel
+func (u *Usecase) GetWalkOutReport(
+	ctx context.Context,
+	HotelUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+) (_ []domain.OversoldNight, err error) {
+	ctx, endSpan := startSpan(ctx, "GetWalkOutReport")
+	defer endSpan(&err)
+	if EndDate.Before(StartDate) {
+		return nil, errors.New("the end date is before the start date")
+	}
+	return u.Get.GetOversoldNights(ctx, HotelUUID, domain.TruncateToDate(StartDate), domain.TruncateToDate(EndDate))
+}
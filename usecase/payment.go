@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+	"github.com/google/uuid"
+)
+
+// paymentSettlerBatchSize caps how many payments the settler claims at a time
+const paymentSettlerBatchSize = 50
+
+// CapturePayment collects the authorized payment of a reservation
+func (u *Usecase) CapturePayment(
+	ctx context.Context,
+	ReservationUUID string,
+) (_ *domain.Payment, err error) {
+	ctx, endSpan := startSpan(ctx, "CapturePayment")
+	defer endSpan(&err)
+	charge, err := u.Get.GetPayment(ctx, ReservationUUID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get payment: %w", err)
+	}
+	if charge == nil {
+		return nil, fmt.Errorf("reservation %s has no payment", ReservationUUID)
+	}
+	if charge.Status != domain.AUTHORIZED {
+		return nil, fmt.Errorf("can't capture a %s payment", charge.Status)
+	}
+	if err := u.Payments.Capture(ctx, charge.GatewayReference, charge.Amount); err != nil {
+		return nil, fmt.Errorf("can't capture payment: %w", err)
+	}
+	charge.CapturedAmount = charge.Amount
+	charge.Status = domain.CAPTURED
+	return u.Update.UpdatePayment(ctx, charge)
+}
+
+// authorizePayment holds the price of a pending reservation on the guest's payment method.
+// The reservation is confirmed when the authorization succeeds, otherwise its inventory is released
+func (u *Usecase) authorizePayment(
+	ctx context.Context,
+	reservation *domain.Reservation,
+) (*domain.Reservation, error) {
+	charge := &domain.Payment{
+		ReservationUUID: &reservation.UUID,
+		Amount:          reservation.TotalPrice,
+		Currency:        domain.DefaultCurrency,
+	}
+	if charge.Amount <= 0 {
+		// there's nothing to authorize for a fully discounted stay
+		charge.Status = domain.CAPTURED
+		confirmed, err := u.Update.ConfirmReservationPayment(ctx, charge)
+		if err != nil {
+			return nil, err
+		}
+		u.Metrics.ReservationCreated(confirmed.HotelUUID)
+		return confirmed, nil
+	}
+
+	reference, err := u.Payments.Authorize(ctx, domain.AuthorizeRequest{
+		Amount:         charge.Amount,
+		Currency:       charge.Currency,
+		PaymentMethod:  reservation.PaymentMethod,
+		IdempotencyKey: reservation.UUID,
+	})
+	if err != nil {
+		charge.Status = domain.FAILED
+		charge.FailureReason = err.Error()
+		if _, failErr := u.Update.FailReservationPayment(ctx, charge); failErr != nil {
+			return nil, fmt.Errorf("can't release reservation after a failed payment: %w", failErr)
+		}
+		return nil, fmt.Errorf("can't authorize payment: %w", err)
+	}
+
+	charge.Status = domain.AUTHORIZED
+	charge.GatewayReference = reference
+	confirmed, err := u.Update.ConfirmReservationPayment(ctx, charge)
+	if err != nil {
+		if voidErr := u.Payments.Void(ctx, reference); voidErr != nil {
+			return nil, fmt.Errorf("can't confirm reservation: %v, and can't void its payment: %w", err, voidErr)
+		}
+		return nil, err
+	}
+	u.Metrics.ReservationCreated(confirmed.HotelUUID)
+	return confirmed, nil
+}
+
+// authorizeBookingPayment holds the price of a multi-room booking on the guest's payment method before
+// the booking is recorded with the payment. A booking that costs nothing is recorded as paid
+func (u *Usecase) authorizeBookingPayment(
+	ctx context.Context,
+	booking *domain.Booking,
+) (*domain.Payment, error) {
+	charge := &domain.Payment{
+		Amount:   booking.TotalPrice,
+		Currency: domain.DefaultCurrency,
+	}
+	if err := u.authorizeCharge(ctx, charge, booking.PaymentMethod); err != nil {
+		return nil, err
+	}
+	return charge, nil
+}
+
+// authorizeCharge holds the amount of a payment that isn't recorded yet on the guest's payment method,
+// a payment that costs nothing is paid
+func (u *Usecase) authorizeCharge(
+	ctx context.Context,
+	charge *domain.Payment,
+	PaymentMethod string,
+) error {
+	if charge.Amount <= 0 {
+		charge.Status = domain.CAPTURED
+		return nil
+	}
+	// a booking has no UUID until it is recorded and a declined hold may be confirmed again with another
+	// payment method, every attempt is authorized on its own
+	reference, err := u.Payments.Authorize(ctx, domain.AuthorizeRequest{
+		Amount:         charge.Amount,
+		Currency:       charge.Currency,
+		PaymentMethod:  PaymentMethod,
+		IdempotencyKey: uuid.New().String(),
+	})
+	if err != nil {
+		return fmt.Errorf("can't authorize payment: %w", err)
+	}
+	charge.Status = domain.AUTHORIZED
+	charge.GatewayReference = reference
+	return nil
+}
+
+// voidUnrecordedPayment voids an authorization whose booking or hold couldn't be recorded, and returns
+// the error that kept it from being recorded
+func (u *Usecase) voidUnrecordedPayment(
+	ctx context.Context,
+	charge *domain.Payment,
+	err error,
+) error {
+	if charge == nil || charge.Status != domain.AUTHORIZED {
+		return err
+	}
+	if voidErr := u.Payments.Void(ctx, charge.GatewayReference); voidErr != nil {
+		return fmt.Errorf("%w, and can't void its payment: %v", err, voidErr)
+	}
+	return err
+}
+
+// SettleDuePayments settles the payments whose settlement is due, the ones left owing a refund by a
+// cancelled reservation or booking line whose settlement failed or never completed. It returns how many
+// payments it attempted
+func (u *Usecase) SettleDuePayments(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		payments, err := u.Update.ClaimDuePayments(ctx, time.Now(), paymentSettlerBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+		for i := range payments {
+			if err := u.settlePayment(ctx, &payments[i]); err != nil {
+				logging.FromContext(ctx).Errorf("can't settle payment %s: %v", payments[i].UUID, err)
+			}
+		}
+		attempted += len(payments)
+		if len(payments) < paymentSettlerBatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// RunPaymentSettler settles due payments every interval until the context is cancelled
+func (u *Usecase) RunPaymentSettler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			attempted, err := u.SettleDuePayments(ctx)
+			if err != nil {
+				logging.FromContext(ctx).Errorf("can't settle due payments: %v", err)
+			}
+			if attempted > 0 {
+				logging.FromContext(ctx).Infof("attempted to settle %d payments", attempted)
+			}
+		}
+	}
+}
+
+// settlePayment gives back the refund owed on a payment and records the outcome. A failed settlement
+// is retried by the payment settler after a backoff
+func (u *Usecase) settlePayment(
+	ctx context.Context,
+	charge *domain.Payment,
+) error {
+	settleErr := u.settleRefundDue(ctx, charge)
+	charge.RecordSettlement(time.Now(), settleErr)
+	if _, err := u.Update.UpdatePayment(ctx, charge); err != nil {
+		if settleErr != nil {
+			return fmt.Errorf("%v, and can't record it: %w", settleErr, err)
+		}
+		return fmt.Errorf("can't record payment settlement: %w", err)
+	}
+	return settleErr
+}
+
+// settleRefundDue makes the refund owed on a payment with the gateway. An authorization owing its whole
+// amount is voided, otherwise it is captured and the refund due given back
+func (u *Usecase) settleRefundDue(
+	ctx context.Context,
+	charge *domain.Payment,
+) error {
+	if charge.Status == domain.AUTHORIZED {
+		if charge.RefundDue >= charge.Amount {
+			if err := u.Payments.Void(ctx, charge.GatewayReference); err != nil {
+				return fmt.Errorf("can't void payment: %w", err)
+			}
+			charge.Status = domain.VOIDED
+			charge.RefundDue = 0
+			return nil
+		}
+		if err := u.Payments.Capture(ctx, charge.GatewayReference, charge.Amount); err != nil {
+			return fmt.Errorf("can't capture payment: %w", err)
+		}
+		charge.CapturedAmount = charge.Amount
+		charge.Status = domain.CAPTURED
+		// the capture is saved before the refund is made, so that a retried refund doesn't capture again
+		if _, err := u.Update.UpdatePayment(ctx, charge); err != nil {
+			return fmt.Errorf("can't record payment capture: %w", err)
+		}
+	}
+	return u.refundPayment(ctx, charge)
+}
+
+// refundPayment gives back the refund due on a captured payment, up to what is left of it
+func (u *Usecase) refundPayment(
+	ctx context.Context,
+	charge *domain.Payment,
+) error {
+	amount := charge.RefundDue
+	if remaining := charge.CapturedAmount - charge.RefundedAmount; amount > remaining {
+		amount = remaining
+	}
+	if amount > 0 {
+		if err := u.Payments.Refund(ctx, charge.GatewayReference, amount); err != nil {
+			return fmt.Errorf("can't refund payment: %w", err)
+		}
+		charge.RefundedAmount += amount
+		charge.Status = domain.PARTIALLY_REFUNDED
+		if charge.RefundedAmount == charge.CapturedAmount {
+			charge.Status = domain.REFUNDED
+		}
+	}
+	charge.RefundDue = 0
+	return nil
+}
@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/common/dto"
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+// CreatePricingRule creates a new dynamic pricing rule for a hotel
+func (u *Usecase) CreatePricingRule(
+	ctx context.Context,
+	rule *domain.PricingRule,
+) (_ *domain.PricingRule, err error) {
+	ctx, endSpan := startSpan(ctx, "CreatePricingRule")
+	defer endSpan(&err)
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	if rule.RoomTypeUUID != "" {
+		if _, err := u.getHotelRoomType(ctx, rule.HotelUUID, rule.RoomTypeUUID); err != nil {
+			return nil, err
+		}
+	}
+	return u.Create.CreatePricingRule(ctx, rule)
+}
+
+// GetPricingRules gets the active pricing rules of a hotel
+func (u *Usecase) GetPricingRules(
+	ctx context.Context,
+	HotelUUID string,
+) (_ []domain.PricingRule, err error) {
+	ctx, endSpan := startSpan(ctx, "GetPricingRules")
+	defer endSpan(&err)
+	return u.Get.GetPricingRules(ctx, HotelUUID)
+}
+
+// PreviewPricing computes a room type's dynamic price calendar without booking or saving anything
+func (u *Usecase) PreviewPricing(
+	ctx context.Context,
+	HotelUUID string,
+	RoomTypeUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+	rules []domain.PricingRule,
+) (_ []dto.PricedNight, err error) {
+	ctx, endSpan := startSpan(ctx, "PreviewPricing")
+	defer endSpan(&err)
+	if EndDate.Before(StartDate) {
+		return nil, errors.New("the end date is before the start date")
+	}
+	// the end date is included
+	if domain.CountNights(StartDate, EndDate)+1 > maxRateCalendarDays {
+		return nil, fmt.Errorf("a preview can cover at most %d nights", maxRateCalendarDays)
+	}
+	nights := domain.StayNights(StartDate, EndDate.AddDate(0, 0, 1))
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	if rules == nil {
+		stored, err := u.Get.GetPricingRules(ctx, HotelUUID)
+		if err != nil {
+			return nil, fmt.Errorf("can't get pricing rules: %w", err)
+		}
+		rules = stored
+	}
+	roomType, err := u.getHotelRoomType(ctx, HotelUUID, RoomTypeUUID)
+	if err != nil {
+		return nil, err
+	}
+	return u.priceCalendar(ctx, roomType, nights, time.Now(), rules)
+}
+
+// priceCalendar prices every night from the room type's base rate, adjusted by the pricing rules that
+// apply to the room type given the night's occupancy, weekday and how far ahead it is booked
+func (u *Usecase) priceCalendar(
+	ctx context.Context,
+	roomType *domain.RoomType,
+	nights []time.Time,
+	bookedOn time.Time,
+	rules []domain.PricingRule,
+) ([]dto.PricedNight, error) {
+	calendar := []dto.PricedNight{}
+	if len(nights) == 0 {
+		return calendar, nil
+	}
+	first, last := nights[0], nights[len(nights)-1]
+
+	rates, err := u.Get.GetRatesInRange(ctx, roomType.UUID, first, last)
+	if err != nil {
+		return nil, fmt.Errorf("can't get rates: %w", err)
+	}
+	inventories, err := u.Get.GetRoomTypeInventories(ctx, roomType.HotelUUID, first, last)
+	if err != nil {
+		return nil, fmt.Errorf("can't get room type inventory: %w", err)
+	}
+
+	baseRates := map[time.Time]int{}
+	for _, rate := range rates {
+		baseRates[domain.TruncateToDate(rate.Date)] = rate.Rate
+	}
+	occupancy := map[time.Time]float64{}
+	for _, inventory := range inventories {
+		if inventory.RoomTypeUUID != roomType.UUID {
+			continue
+		}
+		night := domain.TruncateToDate(inventory.Date)
+		if inventory.TotalInventory <= 0 {
+			occupancy[night] = 100
+			continue
+		}
+		occupancy[night] = float64(inventory.TotalReserved) / float64(inventory.TotalInventory) * 100
+	}
+	applicable := []domain.PricingRule{}
+	for _, rule := range rules {
+		if rule.RoomTypeUUID == "" || rule.RoomTypeUUID == roomType.UUID {
+			applicable = append(applicable, rule)
+		}
+	}
+
+	for _, night := range nights {
+		priced := dto.PricedNight{
+			Date:         night.Format(domain.DateLayout),
+			Occupancy:    occupancy[night],
+			AppliedRules: []string{},
+		}
+		baseRate, ok := baseRates[night]
+		if ok {
+			demand := domain.NightDemand{Date: night, Occupancy: priced.Occupancy, BookedOn: bookedOn}
+			price, applied := domain.PriceNight(baseRate, demand, applicable)
+			priced.HasRate = true
+			priced.BaseRate = baseRate
+			priced.Price = price
+			for _, rule := range applied {
+				name := rule.Name
+				if name == "" {
+					name = string(rule.Type)
+				}
+				priced.AppliedRules = append(priced.AppliedRules, name)
+			}
+		}
+		calendar = append(calendar, priced)
+	}
+	return calendar, nil
+}
@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+// CreatePromotion creates a new promotion
+func (u *Usecase) CreatePromotion(
+	ctx context.Context,
+	promotion *domain.Promotion,
+) (_ *domain.Promotion, err error) {
+	ctx, endSpan := startSpan(ctx, "CreatePromotion")
+	defer endSpan(&err)
+	promotion.Code = domain.NormalizePromoCode(promotion.Code)
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+	return u.Create.CreatePromotion(ctx, promotion)
+}
+
+// applyPromotion discounts a quoted reservation with its promo code
+// The promotion's usage is only counted when the reservation is stored
+func (u *Usecase) applyPromotion(
+	ctx context.Context,
+	reservation *domain.Reservation,
+	bookedOn time.Time,
+) error {
+	code := domain.NormalizePromoCode(reservation.PromoCode)
+	if code == "" {
+		return nil
+	}
+	promotion, err := u.Get.GetPromotionByCode(ctx, code)
+	if err != nil {
+		return fmt.Errorf("can't get promotion: %w", err)
+	}
+	if promotion == nil {
+		return fmt.Errorf("%w: unknown promo code %s", domain.ErrPromotionNotApplicable, code)
+	}
+	if err := promotion.CheckApplicable(reservation.HotelUUID, reservation.RoomTypeUUID, bookedOn); err != nil {
+		return err
+	}
+
+	reservation.PromoCode = code
+	reservation.PromotionUUID = &promotion.UUID
+	reservation.Discount = promotion.Discount(reservation.TotalPrice)
+	reservation.TotalPrice -= reservation.Discount
+	return nil
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/common/dto"
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+// maxRateCalendarDays caps how many nights a single bulk upload may set
+const maxRateCalendarDays = 2 * 366
+
+// BulkUpsertRates sets the nightly rates of a room type for every date covered by the payload's periods
+func (u *Usecase) BulkUpsertRates(
+	ctx context.Context,
+	payload *dto.BulkRatePayload,
+) (_ *dto.BulkRateSummary, err error) {
+	ctx, endSpan := startSpan(ctx, "BulkUpsertRates")
+	defer endSpan(&err)
+	if len(payload.Periods) == 0 {
+		return nil, errors.New("at least one rate period is required")
+	}
+
+	rates := map[time.Time]int{}
+	var ranges [][2]time.Time
+	for i, period := range payload.Periods {
+		start, err := domain.ParseDate(period.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("period %d: invalid start date %q: %w", i, period.StartDate, err)
+		}
+		end, err := domain.ParseDate(period.EndDate)
+		if err != nil {
+			return nil, fmt.Errorf("period %d: invalid end date %q: %w", i, period.EndDate, err)
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("period %d: end date %s is before start date %s", i, period.EndDate, period.StartDate)
+		}
+		// the end date is included
+		if domain.CountNights(start, end)+1 > maxRateCalendarDays {
+			return nil, fmt.Errorf("period %d: a single upload can set at most %d nights", i, maxRateCalendarDays)
+		}
+		for j, other := range ranges {
+			if !start.After(other[1]) && !end.Before(other[0]) {
+				return nil, fmt.Errorf("period %d overlaps period %d", i, j)
+			}
+		}
+		ranges = append(ranges, [2]time.Time{start, end})
+
+		weekdayRates, err := parseWeekdayRates(period.WeekdayRates)
+		if err != nil {
+			return nil, fmt.Errorf("period %d: %w", i, err)
+		}
+		if period.DefaultRate < 0 {
+			return nil, fmt.Errorf("period %d: default rate can't be negative", i)
+		}
+		for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+			rate, ok := weekdayRates[date.Weekday()]
+			if !ok {
+				rate = period.DefaultRate
+			}
+			if rate == 0 {
+				continue
+			}
+			rates[date] = rate
+		}
+		if len(rates) > maxRateCalendarDays {
+			return nil, fmt.Errorf("a single upload can set at most %d nights", maxRateCalendarDays)
+		}
+	}
+
+	return u.upsertRateCalendar(ctx, payload.HotelUUID, payload.RoomTypeUUID, rates)
+}
+
+// UploadRateCalendar sets the nightly rates of a room type from a CSV document with a
+// `date,rate` header followed by one row per night
+func (u *Usecase) UploadRateCalendar(
+	ctx context.Context,
+	HotelUUID string,
+	RoomTypeUUID string,
+	document io.Reader,
+) (_ *dto.BulkRateSummary, err error) {
+	ctx, endSpan := startSpan(ctx, "UploadRateCalendar")
+	defer endSpan(&err)
+	reader := csv.NewReader(document)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("can't read csv header: %w", err)
+	}
+	if !strings.EqualFold(header[0], "date") || !strings.EqualFold(header[1], "rate") {
+		return nil, fmt.Errorf("expected csv header `date,rate` but got `%s`", strings.Join(header, ","))
+	}
+
+	rates := map[time.Time]int{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't read csv: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		date, err := domain.ParseDate(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q: %w", line, record[0], err)
+		}
+		rate, err := strconv.Atoi(record[1])
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("line %d: rate must be a positive whole number but got %q", line, record[1])
+		}
+		if _, ok := rates[date]; ok {
+			return nil, fmt.Errorf("line %d: date %s appears more than once", line, record[0])
+		}
+		rates[date] = rate
+		if len(rates) > maxRateCalendarDays {
+			return nil, fmt.Errorf("a single upload can set at most %d nights", maxRateCalendarDays)
+		}
+	}
+	if len(rates) == 0 {
+		return nil, errors.New("the csv document has no rates")
+	}
+
+	return u.upsertRateCalendar(ctx, HotelUUID, RoomTypeUUID, rates)
+}
+
+// upsertRateCalendar validates the room type and stores one rate per night
+func (u *Usecase) upsertRateCalendar(
+	ctx context.Context,
+	HotelUUID string,
+	RoomTypeUUID string,
+	calendar map[time.Time]int,
+) (*dto.BulkRateSummary, error) {
+	if len(calendar) == 0 {
+		return nil, errors.New("the rate periods don't set any night's rate")
+	}
+	if _, err := u.getHotelRoomType(ctx, HotelUUID, RoomTypeUUID); err != nil {
+		return nil, err
+	}
+
+	rates := make([]*domain.Rate, 0, len(calendar))
+	for date, rate := range calendar {
+		rates = append(rates, &domain.Rate{
+			HotelUUID:    HotelUUID,
+			RoomTypeUUID: RoomTypeUUID,
+			Rate:         rate,
+			Date:         date,
+		})
+	}
+	inserted, err := u.Create.UpsertRates(ctx, rates)
+	if err != nil {
+		return nil, err
+	}
+
+	total := int64(len(rates))
+	return &dto.BulkRateSummary{
+		Inserted: inserted,
+		Updated:  total - inserted,
+		Total:    total,
+	}, nil
+}
+
+// parseWeekdayRates maps weekday names e.g "monday" to their rate
+func parseWeekdayRates(weekdayRates map[string]int) (map[time.Weekday]int, error) {
+	parsed := map[time.Weekday]int{}
+	for name, rate := range weekdayRates {
+		weekday, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", name)
+		}
+		if rate < 0 {
+			return nil, fmt.Errorf("rate for %s can't be negative", name)
+		}
+		parsed[weekday] = rate
+	}
+	return parsed, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
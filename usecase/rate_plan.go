@@ -0,0 +1,306 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/application/common/dto"
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+)
+
+// CreateRatePlan creates a new rate plan for one of a hotel's room types
+func (u *Usecase) CreateRatePlan(
+	ctx context.Context,
+	ratePlan *domain.RatePlan,
+) (_ *domain.RatePlan, err error) {
+	ctx, endSpan := startSpan(ctx, "CreateRatePlan")
+	defer endSpan(&err)
+	if ratePlan.Name == "" {
+		return nil, errors.New("a rate plan must have a name")
+	}
+	restrictions := []int{
+		ratePlan.MinLengthOfStay,
+		ratePlan.MaxLengthOfStay,
+		ratePlan.MinAdvanceDays,
+		ratePlan.MaxAdvanceDays,
+	}
+	for _, restriction := range restrictions {
+		if restriction < 0 {
+			return nil, errors.New("rate plan restrictions can't be negative")
+		}
+	}
+	if ratePlan.MaxLengthOfStay > 0 && ratePlan.MaxLengthOfStay < ratePlan.MinLengthOfStay {
+		return nil, errors.New("the maximum length of stay can't be shorter than the minimum length of stay")
+	}
+	if ratePlan.MaxAdvanceDays > 0 && ratePlan.MaxAdvanceDays < ratePlan.MinAdvanceDays {
+		return nil, errors.New("the maximum advance purchase window can't be shorter than the minimum")
+	}
+	if _, err := u.getHotelRoomType(ctx, ratePlan.HotelUUID, ratePlan.RoomTypeUUID); err != nil {
+		return nil, err
+	}
+	return u.Create.CreateRatePlan(ctx, ratePlan)
+}
+
+// SetRatePlanRates sets a rate plan's nightly price and restrictions over a date range
+func (u *Usecase) SetRatePlanRates(
+	ctx context.Context,
+	payload *dto.RatePlanRatesPayload,
+) (_ []*domain.RatePlanRate, err error) {
+	ctx, endSpan := startSpan(ctx, "SetRatePlanRates")
+	defer endSpan(&err)
+	start, err := domain.ParseDate(payload.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date %q: %w", payload.StartDate, err)
+	}
+	end, err := domain.ParseDate(payload.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date %q: %w", payload.EndDate, err)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end date %s is before start date %s", payload.EndDate, payload.StartDate)
+	}
+	// the end date is included
+	if domain.CountNights(start, end)+1 > maxRateCalendarDays {
+		return nil, fmt.Errorf("a single upload can set at most %d nights", maxRateCalendarDays)
+	}
+	if payload.Rate <= 0 {
+		return nil, errors.New("the rate must be greater than zero")
+	}
+	if payload.MinLengthOfStay < 0 {
+		return nil, errors.New("the minimum length of stay can't be negative")
+	}
+	selected := map[time.Weekday]bool{}
+	for _, name := range payload.Weekdays {
+		weekday, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", name)
+		}
+		selected[weekday] = true
+	}
+	ratePlan, err := u.Get.GetRatePlan(ctx, payload.RatePlanUUID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get rate plan: %w", err)
+	}
+	if ratePlan == nil {
+		return nil, fmt.Errorf("rate plan %s doesn't exist", payload.RatePlanUUID)
+	}
+
+	rates := []*domain.RatePlanRate{}
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		if len(selected) > 0 && !selected[date.Weekday()] {
+			continue
+		}
+		rates = append(rates, &domain.RatePlanRate{
+			RatePlanUUID:      ratePlan.UUID,
+			Date:              date,
+			Rate:              payload.Rate,
+			MinLengthOfStay:   payload.MinLengthOfStay,
+			ClosedToArrival:   payload.ClosedToArrival,
+			ClosedToDeparture: payload.ClosedToDeparture,
+		})
+		if len(rates) > maxRateCalendarDays {
+			return nil, fmt.Errorf("a single upload can set at most %d nights", maxRateCalendarDays)
+		}
+	}
+	if len(rates) == 0 {
+		return nil, errors.New("none of the dates falls on the selected weekdays")
+	}
+	if err := u.Create.UpsertRatePlanRates(ctx, rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// SearchAvailability lists, for every room type of a hotel, how many rooms are free for the whole stay
+// and which of its rate plans can be booked
+func (u *Usecase) SearchAvailability(
+	ctx context.Context,
+	HotelUUID string,
+	StartDate time.Time,
+	EndDate time.Time,
+) (_ []dto.RoomTypeAvailability, err error) {
+	ctx, endSpan := startSpan(ctx, "SearchAvailability")
+	defer endSpan(&err)
+	if err := domain.CheckStayLength(StartDate, EndDate); err != nil {
+		return nil, err
+	}
+	nights := domain.StayNights(StartDate, EndDate)
+	if len(nights) == 0 {
+		return nil, errors.New("the stay must be at least one night long")
+	}
+	lastNight := nights[len(nights)-1]
+	departure := domain.TruncateToDate(EndDate)
+
+	roomTypes, err := u.Get.GetHotelRoomTypes(ctx, HotelUUID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get room types: %w", err)
+	}
+	inventories, err := u.Get.GetRoomTypeInventories(ctx, HotelUUID, nights[0], lastNight)
+	if err != nil {
+		return nil, fmt.Errorf("can't get room type inventory: %w", err)
+	}
+	ratePlans, err := u.Get.GetRatePlans(ctx, HotelUUID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get rate plans: %w", err)
+	}
+	ratePlanUUIDs := []string{}
+	for _, ratePlan := range ratePlans {
+		ratePlanUUIDs = append(ratePlanUUIDs, ratePlan.UUID)
+	}
+	// the departure date is fetched as well so that closed to departure restrictions are evaluated
+	ratePlanRates, err := u.Get.GetRatePlanRates(ctx, ratePlanUUIDs, nights[0], departure)
+	if err != nil {
+		return nil, fmt.Errorf("can't get rate plan rates: %w", err)
+	}
+	ratesByPlan := map[string]map[time.Time]domain.RatePlanRate{}
+	for _, rate := range ratePlanRates {
+		if ratesByPlan[rate.RatePlanUUID] == nil {
+			ratesByPlan[rate.RatePlanUUID] = map[time.Time]domain.RatePlanRate{}
+		}
+		ratesByPlan[rate.RatePlanUUID][domain.TruncateToDate(rate.Date)] = rate
+	}
+
+	now := time.Now()
+	results := []dto.RoomTypeAvailability{}
+	for i := range roomTypes {
+		roomType := &roomTypes[i]
+		availability := dto.RoomTypeAvailability{
+			RoomType:  *roomType,
+			Available: availableRooms(*roomType, inventories, nights),
+			Offers:    []dto.RatePlanOffer{},
+		}
+		basePrice, err := u.quoteBaseRates(ctx, roomType, StartDate, EndDate, now)
+		if err != nil && !errors.Is(err, domain.ErrMissingRate) {
+			return nil, err
+		}
+		availability.BasePrice = basePrice
+
+		for i := range ratePlans {
+			ratePlan := ratePlans[i]
+			if ratePlan.RoomTypeUUID != roomType.UUID {
+				continue
+			}
+			rates := ratesByPlan[ratePlan.UUID]
+			offer := dto.RatePlanOffer{
+				RatePlan:     ratePlan,
+				Restrictions: ratePlan.Violations(StartDate, EndDate, now, rates),
+			}
+			offer.Sellable = len(offer.Restrictions) == 0 && availability.Available > 0
+			if price, err := ratePlan.Quote(StartDate, EndDate, rates); err == nil {
+				offer.TotalPrice = price
+			}
+			availability.Offers = append(availability.Offers, offer)
+		}
+		results = append(results, availability)
+	}
+	return results, nil
+}
+
+// availableRooms is the number of rooms of a room type that are free on every night of a stay
+// Nights without an inventory row haven't been booked yet so the whole room type inventory is free
+func availableRooms(
+	roomType domain.RoomType,
+	inventories []domain.RoomTypeInventory,
+	nights []time.Time,
+) int64 {
+	byNight := map[time.Time]domain.RoomTypeInventory{}
+	for _, inventory := range inventories {
+		if inventory.RoomTypeUUID == roomType.UUID {
+			byNight[domain.TruncateToDate(inventory.Date)] = inventory
+		}
+	}
+	available := roomType.SellableRooms()
+	for _, night := range nights {
+		if inventory, ok := byNight[night]; ok && inventory.Available() < available {
+			available = inventory.Available()
+		}
+	}
+	if available < 0 {
+		return 0
+	}
+	return available
+}
+
+// quoteReservation prices a reservation from its rate plan, enforcing the plan's restrictions,
+// or dynamically from the room type's rate calendar when the reservation isn't booked against a plan
+func (u *Usecase) quoteReservation(
+	ctx context.Context,
+	reservation *domain.Reservation,
+	bookedOn time.Time,
+) (int, error) {
+	if reservation.RatePlanUUID == nil {
+		roomType, err := u.getHotelRoomType(ctx, reservation.HotelUUID, reservation.RoomTypeUUID)
+		if err != nil {
+			return 0, err
+		}
+		price, err := u.quoteBaseRates(ctx, roomType, reservation.StartDate, reservation.EndDate, bookedOn)
+		if errors.Is(err, domain.ErrMissingRate) {
+			// reservations without a plan were booked unpriced before rate calendars existed, and still are
+			// when the calendar doesn't price every night of the stay
+			return 0, nil
+		}
+		return price, err
+	}
+
+	ratePlan, err := u.Get.GetRatePlan(ctx, *reservation.RatePlanUUID)
+	if err != nil {
+		return 0, fmt.Errorf("can't get rate plan: %w", err)
+	}
+	if ratePlan == nil || !ratePlan.Active {
+		return 0, fmt.Errorf("rate plan %s doesn't exist", *reservation.RatePlanUUID)
+	}
+	if ratePlan.RoomTypeUUID != reservation.RoomTypeUUID || ratePlan.HotelUUID != reservation.HotelUUID {
+		return 0, fmt.Errorf("rate plan %s can't be used to book room type %s", ratePlan.UUID, reservation.RoomTypeUUID)
+	}
+	ratePlanRates, err := u.Get.GetRatePlanRates(
+		ctx,
+		[]string{ratePlan.UUID},
+		domain.TruncateToDate(reservation.StartDate),
+		domain.TruncateToDate(reservation.EndDate),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("can't get rate plan rates: %w", err)
+	}
+	rates := map[time.Time]domain.RatePlanRate{}
+	for _, rate := range ratePlanRates {
+		rates[domain.TruncateToDate(rate.Date)] = rate
+	}
+	violations := ratePlan.Violations(reservation.StartDate, reservation.EndDate, bookedOn, rates)
+	if len(violations) > 0 {
+		return 0, fmt.Errorf("%w: %s", domain.ErrStayRestricted, strings.Join(violations, "; "))
+	}
+	return ratePlan.Quote(reservation.StartDate, reservation.EndDate, rates)
+}
+
+// quoteBaseRates prices a stay from the room type's rate calendar, adjusted by the hotel's pricing rules
+func (u *Usecase) quoteBaseRates(
+	ctx context.Context,
+	roomType *domain.RoomType,
+	StartDate time.Time,
+	EndDate time.Time,
+	bookedOn time.Time,
+) (int, error) {
+	nights := domain.StayNights(StartDate, EndDate)
+	if len(nights) == 0 {
+		return 0, nil
+	}
+	rules, err := u.Get.GetPricingRules(ctx, roomType.HotelUUID)
+	if err != nil {
+		return 0, fmt.Errorf("can't get pricing rules: %w", err)
+	}
+	calendar, err := u.priceCalendar(ctx, roomType, nights, bookedOn, rules)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, night := range calendar {
+		if !night.HasRate {
+			return 0, fmt.Errorf("%w: %s", domain.ErrMissingRate, night.Date)
+		}
+		total += night.Price
+	}
+	return total, nil
+}
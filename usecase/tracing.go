@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// tracer starts the spans of the use cases
+var tracer = otel.Tracer("github.com/MelvinKim/Hotel-Reservation-System/usecase")
+
+// startSpan starts the span of the use case name, so that a slow request can be pinned on the use case or
+// the queries it made. The returned function ends the span, recording the error the use case returned:
+//
+//	ctx, endSpan := startSpan(ctx, "CreateGuest")
+//	defer endSpan(&err)
+func startSpan(ctx context.Context, name string) (context.Context, func(err *error)) {
+	ctx, span := tracer.Start(ctx, "usecase."+name)
+	return ctx, func(err *error) {
+		if *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+	}
+}
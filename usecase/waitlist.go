@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+)
+
+// WaitlistOfferDuration is how long a waitlisted guest has to confirm the room held for them
+const WaitlistOfferDuration = 2 * time.Hour
+
+// JoinWaitlist adds a guest to the waitlist of a room type for a stay
+func (u *Usecase) JoinWaitlist(
+	ctx context.Context,
+	entry *domain.WaitlistEntry,
+) (_ *domain.WaitlistEntry, err error) {
+	ctx, endSpan := startSpan(ctx, "JoinWaitlist")
+	defer endSpan(&err)
+	entry.StartDate = domain.TruncateToDate(entry.StartDate)
+	entry.EndDate = domain.TruncateToDate(entry.EndDate)
+	if err := domain.CheckStayLength(entry.StartDate, entry.EndDate); err != nil {
+		return nil, err
+	}
+	if len(domain.StayNights(entry.StartDate, entry.EndDate)) == 0 {
+		return nil, errors.New("a waitlisted stay must be at least one night long")
+	}
+	if _, err := u.getHotelRoomType(ctx, entry.HotelUUID, entry.RoomTypeUUID); err != nil {
+		return nil, err
+	}
+	entry.Status = domain.WAITING
+	return u.Create.CreateWaitlistEntry(ctx, entry)
+}
+
+// OfferWaitlistedRooms offers a time limited hold to the first waitlisted guest whose stay can be booked
+// in every stay an event gave back, see domain.OutboxEvent.FreedStays. It is an events.PublisherFunc so
+// that the outbox relay drives the waitlist
+func (u *Usecase) OfferWaitlistedRooms(
+	ctx context.Context,
+	event *domain.OutboxEvent,
+) error {
+	freed, err := event.FreedStays()
+	if err != nil {
+		return err
+	}
+	for _, stay := range freed {
+		if err := u.offerFreedStay(ctx, stay); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// offerFreedStay offers a hold to the first waitlisted guest whose stay fits in a freed stay
+func (u *Usecase) offerFreedStay(
+	ctx context.Context,
+	freed domain.FreedStay,
+) error {
+	start, err := domain.ParseDate(freed.StartDate)
+	if err != nil {
+		return err
+	}
+	end, err := domain.ParseDate(freed.EndDate)
+	if err != nil {
+		return err
+	}
+	entries, err := u.Get.GetWaitlist(ctx, freed.RoomTypeUUID, start, end)
+	if err != nil {
+		return fmt.Errorf("can't get waitlist: %w", err)
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		offered, err := u.offerWaitlistEntry(ctx, entry)
+		if errors.Is(err, domain.ErrSoldOut) || errors.Is(err, domain.ErrWaitlistEntryTaken) {
+			// the freed rooms don't cover this guest's stay, or another replica got to them first
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if offered {
+			return nil
+		}
+	}
+	return nil
+}
+
+// offerWaitlistEntry holds a room for a waitlisted guest. Entries whose stay can't be priced are skipped
+func (u *Usecase) offerWaitlistEntry(
+	ctx context.Context,
+	entry *domain.WaitlistEntry,
+) (bool, error) {
+	now := time.Now()
+	hold := &domain.Reservation{
+		GuestUUID:    entry.GuestUUID,
+		HotelUUID:    entry.HotelUUID,
+		RoomTypeUUID: entry.RoomTypeUUID,
+		StartDate:    entry.StartDate,
+		EndDate:      entry.EndDate,
+	}
+	if err := u.priceReservation(ctx, hold, now); err != nil {
+		logging.FromContext(ctx).Warnf("can't offer waitlist entry %s a room: %v", entry.UUID, err)
+		return false, nil
+	}
+	expiresAt := now.Add(WaitlistOfferDuration)
+	hold.Status = string(domain.HELD)
+	hold.ExpiresAt = &expiresAt
+	if _, err := u.Update.OfferWaitlistEntry(ctx, entry, hold); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MelvinKim/Hotel-Reservation-System/domain"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/logging"
+	"github.com/MelvinKim/Hotel-Reservation-System/infrastructure/service/webhook"
+)
+
+// webhookDispatchBatchSize caps how many deliveries a dispatcher claims at a time
+const webhookDispatchBatchSize = 50
+
+// CreateWebhookSubscription subscribes a partner's endpoint to a hotel's reservation events
+func (u *Usecase) CreateWebhookSubscription(
+	ctx context.Context,
+	subscription *domain.WebhookSubscription,
+) (_ *domain.WebhookSubscription, err error) {
+	ctx, endSpan := startSpan(ctx, "CreateWebhookSubscription")
+	defer endSpan(&err)
+	if err := subscription.Validate(); err != nil {
+		return nil, err
+	}
+	subscription.Active = true
+	return u.Create.CreateWebhookSubscription(ctx, subscription)
+}
+
+// GetWebhookDeliveries gets the delivery log of a webhook subscription
+func (u *Usecase) GetWebhookDeliveries(
+	ctx context.Context,
+	SubscriptionUUID string,
+) (_ []domain.WebhookDelivery, err error) {
+	ctx, endSpan := startSpan(ctx, "GetWebhookDeliveries")
+	defer endSpan(&err)
+	return u.Get.GetWebhookDeliveries(ctx, SubscriptionUUID)
+}
+
+// EnqueueWebhookDeliveries queues an event for delivery to every subscription it matches.
+// It is an events.PublisherFunc so that the outbox relay drives webhooks
+func (u *Usecase) EnqueueWebhookDeliveries(
+	ctx context.Context,
+	event *domain.OutboxEvent,
+) error {
+	subscriptions, err := u.Get.GetWebhookSubscriptions(ctx, event.HotelUUID)
+	if err != nil {
+		return fmt.Errorf("can't get webhook subscriptions: %w", err)
+	}
+	now := time.Now()
+	deliveries := []*domain.WebhookDelivery{}
+	for i := range subscriptions {
+		if !subscriptions[i].Matches(event) {
+			continue
+		}
+		deliveries = append(deliveries, &domain.WebhookDelivery{
+			SubscriptionUUID: subscriptions[i].UUID,
+			EventUUID:        event.UUID,
+			EventType:        event.Type,
+			Status:           domain.DELIVERY_PENDING,
+			NextAttemptAt:    now,
+		})
+	}
+	return u.Create.CreateWebhookDeliveries(ctx, deliveries)
+}
+
+// DispatchWebhooks attempts every webhook delivery that is due. Deliveries are claimed before they are
+// attempted and their outcome recorded after, no transaction is held while receivers are called
+func (u *Usecase) DispatchWebhooks(
+	ctx context.Context,
+	sender *webhook.Sender,
+) (int, error) {
+	attempted := 0
+	for {
+		deliveries, err := u.Update.ClaimWebhookDeliveries(ctx, time.Now(), webhookDispatchBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+		for i := range deliveries {
+			delivery := &deliveries[i]
+			statusCode, deliverErr := u.deliverWebhook(ctx, sender, delivery)
+			delivery.RecordAttempt(time.Now(), statusCode, deliverErr)
+			if err := u.Update.RecordWebhookDelivery(ctx, delivery); err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+		if len(deliveries) < webhookDispatchBatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// deliverWebhook POSTs a delivery's event to its subscription, returning the receiver's status code
+func (u *Usecase) deliverWebhook(
+	ctx context.Context,
+	sender *webhook.Sender,
+	delivery *domain.WebhookDelivery,
+) (int, error) {
+	if delivery.Subscription == nil || delivery.Event == nil {
+		return 0, errors.New("the delivery's subscription or event no longer exists")
+	}
+	return sender.Deliver(
+		ctx,
+		delivery.Subscription.URL,
+		delivery.Subscription.Secret,
+		delivery.EventUUID,
+		string(delivery.EventType),
+		delivery.Event.Payload,
+	)
+}
+
+// RunWebhookDispatcher attempts due webhook deliveries every interval until the context is cancelled
+func (u *Usecase) RunWebhookDispatcher(
+	ctx context.Context,
+	sender *webhook.Sender,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := u.DispatchWebhooks(ctx, sender); err != nil {
+				logging.FromContext(ctx).Errorf("can't dispatch webhooks: %v", err)
+			}
+		}
+	}
+}